@@ -0,0 +1,92 @@
+/*
+Copyright © 2023 weak_ptr <weak_ptr@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/dave/jennifer/jen"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/cli"
+	"github.com/nnnewb/jk/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// cliCmd represents the cli command
+var cliCmd = &cobra.Command{
+	Use:   "cli",
+	Short: "generate command line client of service",
+	Long: `generate a cobra based command line client <package>ctl with one subcommand per method.
+
+request fields of basic types become flags, file fields of multipart requests take a file path,
+others can be passed by --data as JSON file or stdin.
+generated code depends on go http client, run "jk generate transport -c -l go -f http" first.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var allErrors error
+		name, err := cmd.Flags().GetString("name")
+		allErrors = errors.Combine(allErrors, err)
+		output, err := cmd.Flags().GetString("output")
+		allErrors = errors.Combine(allErrors, err)
+		cobra.CheckErr(allErrors)
+
+		_, service, err := parse(cmd)
+		cobra.CheckErr(err)
+
+		if name == "" {
+			name = service.Interface.Obj().Pkg().Name() + "ctl"
+		}
+		if output == "" {
+			output = filepath.Join("cmd", name)
+		}
+		err = genCLI(service, name, filepath.Join(output, "main.go"))
+		cobra.CheckErr(err)
+	},
+}
+
+func init() {
+	generateCmd.AddCommand(cliCmd)
+
+	cliCmd.Flags().String("name", "", "command name, defaults to <package>ctl")
+	cliCmd.Flags().StringP("output", "o", "", "output directory, defaults to cmd/<name>")
+}
+
+// genCLI 生成命令行客户端代码。
+func genCLI(service *domain.Service, name, filename string) error {
+	f := jen.NewFile("main")
+	f.HeaderComment(fmt.Sprintf("Code generated by jk %s; DO NOT EDIT.", strings.Join(os.Args[1:], " ")))
+	utils.InitializeFileCommon(f)
+	err := cli.GenerateCLI(f, service, name)
+	if err != nil {
+		return errors.Wrap(err, "generate command line client failed")
+	}
+
+	err = os.MkdirAll(filepath.Dir(filename), 0o755)
+	if err != nil {
+		return errors.Wrap(err, "create output directory failed")
+	}
+
+	err = f.Save(filename)
+	if err != nil {
+		return errors.Wrap(err, "render generated command line client code failed")
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2023 weak_ptr <weak_ptr@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/nnnewb/jk/internal/model"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// describeCmd represents the describe command
+var describeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "print parsed service model as JSON or YAML",
+	Long: `print parsed service model as JSON or YAML, with default HTTP annotations populated.
+
+output is {"version": N, "service": {...}}, version is increased on incompatible changes.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := cmd.Flags().GetString("format")
+		cobra.CheckErr(err)
+
+		_, service, err := parse(cmd)
+		cobra.CheckErr(err)
+
+		m, err := model.FromService(service)
+		cobra.CheckErr(err)
+		err = describe(&model.Description{Version: model.Version, Service: m}, format)
+		cobra.CheckErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+
+	describeCmd.Flags().StringP("typename", "t", "", "name of service interface")
+	describeCmd.Flags().StringP("package", "p", "", "package name of service interface")
+	describeCmd.Flags().StringP("format", "o", "json", "output format, json or yaml")
+}
+
+// describe 按 format 输出服务模型。YAML 由 JSON 转换得到，字段名和顺序与 JSON 一致。
+func describe(desc *model.Description, format string) error {
+	data, err := json.MarshalIndent(desc, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal service model failed")
+	}
+
+	switch format {
+	case "json":
+		_, err = fmt.Fprintln(os.Stdout, string(data))
+		return err
+	case "yaml":
+		var doc yaml.MapSlice
+		err = yaml.Unmarshal(data, &doc)
+		if err != nil {
+			return errors.Wrap(err, "convert service model to yaml failed")
+		}
+		data, err = yaml.Marshal(doc)
+		if err != nil {
+			return errors.Wrap(err, "marshal service model as yaml failed")
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	default:
+		return errors.Errorf("unknown format %s, want json or yaml", format)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2023 weak_ptr <weak_ptr@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/nnnewb/jk/internal/diff"
+	"github.com/nnnewb/jk/internal/model"
+	"github.com/spf13/cobra"
+)
+
+// exitBreakingChanges 是发现不兼容变更时的退出码，与加载或解析失败的退出码 1 区分。
+const exitBreakingChanges = 2
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff OLD [NEW]",
+	Short: "detect breaking API changes between two versions of service",
+	Long: `compare two versions of service and print changelog.
+
+exit status is 0 when changes are compatible, 2 when breaking changes are found, and 1 when
+either version fails to load or parse.
+
+OLD and NEW are directories of service package or git revisions, NEW defaults to current directory.
+git revisions are checked out into temporary worktrees, service package is located at the same
+path relative to repository root as current directory.
+
+removed methods, changed http method or path, removed or renamed json fields, changed field types
+and fields losing omitempty are breaking, additions are compatible.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		var allErrors error
+		pkgPath, err := cmd.Flags().GetString("package")
+		allErrors = errors.Combine(allErrors, err)
+		typeName, err := cmd.Flags().GetString("typename")
+		allErrors = errors.Combine(allErrors, err)
+		reportPath, err := cmd.Flags().GetString("report")
+		allErrors = errors.Combine(allErrors, err)
+		cobra.CheckErr(allErrors)
+
+		if len(args) == 1 {
+			args = append(args, ".")
+		}
+
+		old, err := loadService(args[0], pkgPath, typeName)
+		cobra.CheckErr(err)
+		new, err := loadService(args[1], pkgPath, typeName)
+		cobra.CheckErr(err)
+
+		report := diff.Compare(old, new)
+		fmt.Print(report.Changelog())
+
+		if reportPath != "" {
+			err = writeFile(reportPath, func(wr io.Writer) error {
+				encoder := json.NewEncoder(wr)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(report)
+			})
+			cobra.CheckErr(err)
+		}
+
+		if report.Breaking {
+			os.Exit(exitBreakingChanges)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringP("typename", "t", "", "name of service interface")
+	diffCmd.Flags().StringP("package", "p", "", "package name of service interface")
+	diffCmd.Flags().String("report", "", "write JSON report to file")
+}
+
+// loadService 解析 version 对应的服务，version 是目录或者 git 版本。
+func loadService(version, pkgPath, typeName string) (*model.Service, error) {
+	if stat, err := os.Stat(version); err == nil && stat.IsDir() {
+		return loadServiceFromDir(version, pkgPath, typeName)
+	}
+
+	prefix, err := git("rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s is neither a directory nor a git revision", version)
+	}
+
+	worktree, err := os.MkdirTemp("", "jk-diff-")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer os.RemoveAll(worktree)
+
+	_, err = git("worktree", "add", "--detach", worktree, version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "checkout %s failed", version)
+	}
+	defer git("worktree", "remove", "--force", worktree)
+
+	return loadServiceFromDir(filepath.Join(worktree, prefix), pkgPath, typeName)
+}
+
+func loadServiceFromDir(dir, pkgPath, typeName string) (*model.Service, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	_, service, err := parsePackage(dir, pkgPath, typeName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse service in %s failed", dir)
+	}
+	return model.FromService(service)
+}
+
+// git 执行 git 命令，返回去掉首尾空白的标准输出。
+func git(args ...string) (string, error) {
+	var stderr strings.Builder
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
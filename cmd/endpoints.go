@@ -62,7 +62,7 @@ func genEndpoint(service *domain.Service, pkg, filename string) error {
 	f := jen.NewFilePath(pkg)
 	f.HeaderComment(fmt.Sprintf("Code generated by jk %s; DO NOT EDIT.", strings.Join(os.Args[1:], " ")))
 	utils.InitializeFileCommon(f)
-	err := endpoints.GenerateEndpoints(f, service.Interface)
+	err := endpoints.GenerateEndpoints(f, service)
 	if err != nil {
 		return errors.Wrap(err, "generate endpoints for service failed")
 	}
@@ -68,20 +68,26 @@ func parse(cmd *cobra.Command) (string, *domain.Service, error) {
 		return "", nil, errors.WithStack(err)
 	}
 
-	if pkgPath == "" {
-		pkgPath, err = utils.ResolveFullPackagePath(wd, wd)
-		if err != nil {
-			return "", nil, errors.Errorf("can not resolve full path of current working directory %s, error %+v", wd, err)
-		}
-	}
-
 	typeName, err := cmd.Flags().GetString("typename")
 	if err != nil {
 		return "", nil, errors.WithStack(err)
 	}
 
+	return parsePackage(wd, pkgPath, typeName)
+}
+
+// parsePackage 解析目录 dir 中的服务接口 typeName，pkgPath 为空时根据 go.mod 推断。
+func parsePackage(dir, pkgPath, typeName string) (string, *domain.Service, error) {
+	var err error
+	if pkgPath == "" {
+		pkgPath, err = utils.ResolveFullPackagePath(dir, dir)
+		if err != nil {
+			return "", nil, errors.Errorf("can not resolve full path of directory %s, error %+v", dir, err)
+		}
+	}
+
 	fileSet := token.NewFileSet()
-	parsedPackages, err := parser.ParseDir(fileSet, wd, nil, parser.ParseComments)
+	parsedPackages, err := parser.ParseDir(fileSet, dir, nil, parser.ParseComments)
 	if err != nil {
 		return "", nil, errors.WithStack(err)
 	}
@@ -95,14 +101,29 @@ func parse(cmd *cobra.Command) (string, *domain.Service, error) {
 	}
 
 	if astPkg == nil || astPkg.Files == nil {
-		return "", nil, errors.Errorf("no valid package found in path %s, test package skipped", wd)
+		return "", nil, errors.Errorf("no valid package found in path %s, test package skipped", dir)
+	}
+
+	// 服务接口修改后，之前生成的代码可能不再能通过类型检查，忽略生成的文件中的错误，否则无法重新生成
+	generated := make(map[string]bool)
+	for filename, file := range astPkg.Files {
+		generated[filename] = utils.IsGeneratedFile(file)
 	}
 
+	var typeErrors []error
 	info := &types.Info{}
-	typeCheckerConfig := types.Config{Importer: importer.ForCompiler(fileSet, "source", nil)}
-	pkg, err := typeCheckerConfig.Check(pkgPath, fileSet, maps.Values(astPkg.Files), info)
-	if err != nil {
-		return "", nil, errors.WithStack(err)
+	typeCheckerConfig := types.Config{
+		Importer: importer.ForCompiler(fileSet, "source", nil),
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); ok && generated[typeErr.Fset.Position(typeErr.Pos).Filename] {
+				return
+			}
+			typeErrors = append(typeErrors, err)
+		},
+	}
+	pkg, _ := typeCheckerConfig.Check(pkgPath, fileSet, maps.Values(astPkg.Files), info)
+	if len(typeErrors) > 0 {
+		return "", nil, errors.WithStack(typeErrors[0])
 	}
 
 	service, err := domain.ParseInterfaceData(pkg, astPkg, typeName)
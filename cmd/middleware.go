@@ -0,0 +1,73 @@
+/*
+Copyright © 2023 weak_ptr <weak_ptr@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/dave/jennifer/jen"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/middleware"
+	"github.com/nnnewb/jk/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// middlewareCmd represents the middleware command
+var middlewareCmd = &cobra.Command{
+	Use:   "middleware",
+	Short: "generate service middlewares",
+	Long: fmt.Sprintf(`generate logging, metrics and tracing middlewares wrapping the service interface.
+
+available kinds: %s`, strings.Join(middleware.Kinds(), ", ")),
+	Run: func(cmd *cobra.Command, args []string) {
+		kinds, err := cmd.Flags().GetStringSlice("kind")
+		cobra.CheckErr(err)
+
+		pkgPath, service, err := parse(cmd)
+		cobra.CheckErr(err)
+		err = genMiddleware(service, pkgPath, "middleware.go", kinds)
+		cobra.CheckErr(err)
+	},
+}
+
+func init() {
+	generateCmd.AddCommand(middlewareCmd)
+
+	middlewareCmd.Flags().StringSliceP("kind", "k", []string{"slog"}, "middleware kinds to generate, comma separated")
+}
+
+// genMiddleware 生成服务中间件代码。
+func genMiddleware(service *domain.Service, pkg, filename string, kinds []string) error {
+	f := jen.NewFilePath(pkg)
+	f.HeaderComment(fmt.Sprintf("Code generated by jk %s; DO NOT EDIT.", strings.Join(os.Args[1:], " ")))
+	utils.InitializeFileCommon(f)
+	err := middleware.GenerateMiddleware(f, service, kinds)
+	if err != nil {
+		return errors.Wrap(err, "generate middleware for service failed")
+	}
+
+	err = f.Save(filename)
+	if err != nil {
+		return errors.Wrap(err, "render generated middleware code failed")
+	}
+
+	return nil
+}
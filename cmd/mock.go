@@ -0,0 +1,68 @@
+/*
+Copyright © 2023 weak_ptr <weak_ptr@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/dave/jennifer/jen"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/mock"
+	"github.com/nnnewb/jk/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// mockCmd represents the mock command
+var mockCmd = &cobra.Command{
+	Use:   "mock",
+	Short: "generate mock and fake implementation of service",
+	Long: `generate an expectation based mock, an in-memory fake and NewMockEndpointSet for the service interface.
+
+generated code depends on EndpointSet, run "jk generate endpoints" first.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		pkgPath, service, err := parse(cmd)
+		cobra.CheckErr(err)
+		err = genMock(service, pkgPath, "mock.go")
+		cobra.CheckErr(err)
+	},
+}
+
+func init() {
+	generateCmd.AddCommand(mockCmd)
+}
+
+// genMock 生成服务 mock 代码。
+func genMock(service *domain.Service, pkg, filename string) error {
+	f := jen.NewFilePath(pkg)
+	f.HeaderComment(fmt.Sprintf("Code generated by jk %s; DO NOT EDIT.", strings.Join(os.Args[1:], " ")))
+	utils.InitializeFileCommon(f)
+	err := mock.GenerateMock(f, service)
+	if err != nil {
+		return errors.Wrap(err, "generate mock for service failed")
+	}
+
+	err = f.Save(filename)
+	if err != nil {
+		return errors.Wrap(err, "render generated mock code failed")
+	}
+
+	return nil
+}
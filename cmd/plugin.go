@@ -0,0 +1,84 @@
+/*
+Copyright © 2023 weak_ptr <weak_ptr@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/nnnewb/jk/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+// pluginCmd represents the plugin command
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "run built-in generator or external generator plugin by name",
+	Long: `run built-in generator or external generator plugin by name.
+
+generator named foo is looked up in built-in generators first, then jk-gen-foo in PATH.
+plugin reads {"version", "parameter", "service"} as JSON from stdin and writes
+{"files": [{"name", "content", "keep_existing"}], "error"} as JSON to stdout.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		list, err := cmd.Flags().GetBool("list")
+		cobra.CheckErr(err)
+		if list {
+			fmt.Println(strings.Join(generator.Names(), "\n"))
+			return
+		}
+
+		var allErrors error
+		name, err := cmd.Flags().GetString("name")
+		allErrors = errors.Combine(allErrors, err)
+		params, err := cmd.Flags().GetStringToString("param")
+		allErrors = errors.Combine(allErrors, err)
+		cobra.CheckErr(allErrors)
+
+		if name == "" {
+			cobra.CheckErr(errors.New("generator name is required"))
+		}
+
+		pkgPath, service, err := parse(cmd)
+		cobra.CheckErr(err)
+		err = runGenerator(name, &generator.Request{Package: pkgPath, Service: service, Parameter: params})
+		cobra.CheckErr(err)
+	},
+}
+
+func init() {
+	generateCmd.AddCommand(pluginCmd)
+
+	pluginCmd.Flags().String("name", "", "generator name")
+	pluginCmd.Flags().StringToString("param", nil, "generator parameters, key=value")
+	pluginCmd.Flags().Bool("list", false, "list built-in generators")
+}
+
+// runGenerator 查找并运行生成器，把生成的文件写入工作目录。
+func runGenerator(name string, req *generator.Request) error {
+	g, err := generator.Lookup(name)
+	if err != nil {
+		return err
+	}
+
+	files, err := g.Generate(req)
+	if err != nil {
+		return errors.Wrapf(err, "generator %s failed", name)
+	}
+
+	return generator.WriteFiles(files)
+}
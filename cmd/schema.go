@@ -0,0 +1,143 @@
+/*
+Copyright © 2023 weak_ptr <weak_ptr@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"go/types"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"emperror.dev/errors"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/gen/http/doc"
+	"github.com/spf13/cobra"
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "generate JSON Schema of request and response types",
+	Long: `generate JSON Schema (draft 2020-12) of request and response types.
+
+By default one <Type>.schema.json is written per request/response type into the
+output directory. With --bundle all types are written into a single file under $defs.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var allErrors error
+		bundle, err := cmd.Flags().GetBool("bundle")
+		allErrors = errors.Combine(allErrors, err)
+		output, err := cmd.Flags().GetString("output")
+		allErrors = errors.Combine(allErrors, err)
+		cobra.CheckErr(allErrors)
+
+		_, service, err := parse(cmd)
+		cobra.CheckErr(err)
+
+		if bundle {
+			if output == "" {
+				output = "schema.json"
+			}
+			err = genJSONSchemaBundle(service, output)
+			cobra.CheckErr(err)
+		} else {
+			if output == "" {
+				output = "."
+			}
+			err = genJSONSchemas(service, output)
+			cobra.CheckErr(err)
+		}
+	},
+}
+
+func init() {
+	generateCmd.AddCommand(schemaCmd)
+
+	schemaCmd.Flags().Bool("bundle", false, "write all types into one schema file under $defs")
+	schemaCmd.Flags().StringP("output", "o", "", "output file with --bundle (default schema.json), otherwise output directory (default .)")
+}
+
+// writeFile 创建或覆盖文件 filename，调用 write 写入内容。
+func writeFile(filename string, write func(wr io.Writer) error) error {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "open file failed")
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			log.Printf("close file failed, error %+v", err)
+		}
+	}(file)
+
+	return write(file)
+}
+
+// genJSONSchemaBundle 生成包含全部类型的 JSON Schema 文件。
+func genJSONSchemaBundle(service *domain.Service, filename string) error {
+	err := writeFile(filename, func(wr io.Writer) error {
+		return doc.GenerateJSONSchemaBundle(wr, service)
+	})
+	if err != nil {
+		return errors.Wrap(err, "generate json schema bundle failed")
+	}
+	return nil
+}
+
+// genJSONSchemas 为每个请求、响应类型生成单独的 JSON Schema 文件。
+func genJSONSchemas(service *domain.Service, dir string) error {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	written := make(map[string]bool)
+	for _, method := range service.Methods {
+		if !method.Func.Exported() {
+			continue
+		}
+
+		typs := []types.Type{method.RequestType()}
+		switch {
+		case method.IsEventStream():
+			// SSE 方法导出事件类型
+			typs = append(typs, method.ResponseType().(*types.Chan).Elem())
+		case !common.IsStream(method):
+			// 流式响应不是 JSON，没有 Schema
+			typs = append(typs, method.ResponseType())
+		}
+		for _, typ := range typs {
+			named := typ.(*types.Pointer).Elem().(*types.Named)
+			if written[named.Obj().Name()] {
+				continue
+			}
+			written[named.Obj().Name()] = true
+
+			filename := filepath.Join(dir, named.Obj().Name()+".schema.json")
+			err = writeFile(filename, func(wr io.Writer) error {
+				return doc.GenerateJSONSchema(wr, service, named)
+			})
+			if err != nil {
+				return errors.Wrapf(err, "generate json schema of %s failed", named.Obj().Name())
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2023 weak_ptr <weak_ptr@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/nnnewb/jk/internal/gen/tmpl"
+	"github.com/nnnewb/jk/internal/model"
+	"github.com/spf13/cobra"
+)
+
+// templateCmd represents the template command
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "render user defined text/template over service model",
+	Long: `render user defined text/template over service model, the same model printed by "jk describe".
+
+template data is the service, e.g. {{range .Methods}}{{.HTTPMethod}} {{.HTTPPath}}{{end}}.
+helper funcs:
+  camel, lowerCamel, snake, screamingSnake, kebab  case conversions
+  lower, upper, join, replace                      string helpers
+  json                                             marshal value as JSON
+  lookup TYPE                                      named type definition of type reference
+  fields TYPE                                      fields of struct type reference
+  jsonName TYPE FIELD                              json name of go field
+  goType TYPE, tsType TYPE                         render type reference as Go/TypeScript`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var allErrors error
+		templatePath, err := cmd.Flags().GetString("template")
+		allErrors = errors.Combine(allErrors, err)
+		out, err := cmd.Flags().GetString("out")
+		allErrors = errors.Combine(allErrors, err)
+		cobra.CheckErr(allErrors)
+
+		if templatePath == "" {
+			cobra.CheckErr(errors.New("template is required"))
+		}
+
+		_, service, err := parse(cmd)
+		cobra.CheckErr(err)
+
+		// 先渲染到内存，模板执行失败时不覆盖已有的输出文件
+		m, err := model.FromService(service)
+		cobra.CheckErr(err)
+		var buf bytes.Buffer
+		err = tmpl.Execute(&buf, templatePath, m)
+		cobra.CheckErr(err)
+
+		if out == "" {
+			_, err = buf.WriteTo(os.Stdout)
+			cobra.CheckErr(err)
+			return
+		}
+		err = writeFile(out, func(wr io.Writer) error {
+			_, err := buf.WriteTo(wr)
+			return err
+		})
+		cobra.CheckErr(err)
+	},
+}
+
+func init() {
+	generateCmd.AddCommand(templateCmd)
+
+	templateCmd.Flags().String("template", "", "path of text/template file")
+	templateCmd.Flags().String("out", "", "output file, defaults to stdout")
+}
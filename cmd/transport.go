@@ -19,19 +19,10 @@ package cmd
 
 import (
 	"fmt"
-	"log"
-	"os"
-	"strings"
+	"strconv"
 
 	"emperror.dev/errors"
-	"github.com/dave/jennifer/jen"
-	"github.com/nnnewb/jk/internal/domain"
-	stdcli "github.com/nnnewb/jk/internal/gen/http/client/go/std"
-	"github.com/nnnewb/jk/internal/gen/http/client/typescript/fetch"
-	"github.com/nnnewb/jk/internal/gen/http/doc"
-	"github.com/nnnewb/jk/internal/gen/http/server/go/gin"
-	stdsvr "github.com/nnnewb/jk/internal/gen/http/server/go/std"
-	"github.com/nnnewb/jk/internal/utils"
+	"github.com/nnnewb/jk/internal/generator"
 	"github.com/spf13/cobra"
 )
 
@@ -56,55 +47,43 @@ var transportCmd = &cobra.Command{
 		allErrors = errors.Combine(allErrors, err)
 		embedSwagger, err := cmd.Flags().GetBool("embed-swagger")
 		allErrors = errors.Combine(allErrors, err)
+		zod, err := cmd.Flags().GetBool("zod")
+		allErrors = errors.Combine(allErrors, err)
+		withTests, err := cmd.Flags().GetBool("with-tests")
+		allErrors = errors.Combine(allErrors, err)
 		cobra.CheckErr(allErrors)
 
+		if proto != "http" {
+			cobra.CheckErr(fmt.Errorf("protocol %s is not supported", proto))
+		}
+
 		pkgPath, service, err := parse(cmd)
 		cobra.CheckErr(err)
 
-		switch proto {
-		case "http":
-			if server {
-				switch lang {
-				case "go":
-					switch framework {
-					case "http":
-						err = genHTTPServer(service, pkgPath, "transport_http_server.go", embedSwagger)
-						cobra.CheckErr(err)
-					case "gin":
-						err = genGinServer(service, pkgPath, "transport_gin_server.go", embedSwagger)
-						cobra.CheckErr(err)
-					default:
-						cobra.CheckErr(fmt.Errorf("protocol %s server code generation does not support framework %s (%s)", proto, framework, lang))
-					}
-				default:
-					cobra.CheckErr(fmt.Errorf("protocol %s server code generation does not support language %s", proto, lang))
-				}
-				if swagger {
-					err = genSwagger(service, "swagger.json")
-					cobra.CheckErr(err)
-				}
-			} else if client {
-				switch lang {
-				case "go":
-					switch framework {
-					case "http":
-						err = genHTTPClient(service, pkgPath, "transport_http_client.go")
-						cobra.CheckErr(err)
-					default:
-						cobra.CheckErr(fmt.Errorf("protocol %s server code generation does not support framework %s (%s)", proto, framework, lang))
-					}
-				case "ts":
-					switch framework {
-					case "fetch":
-						err = genTypeScriptClient(service, "client.ts")
-						cobra.CheckErr(err)
-					default:
-						cobra.CheckErr(fmt.Errorf("protocol %s server code generation does not support framework %s (%s)", proto, framework, lang))
-					}
-				default:
-					cobra.CheckErr(fmt.Errorf("protocol %s client code generation does not support language %s", proto, lang))
-				}
+		// 生成器名称为 <language>-<framework>-<server|client>，找不到内置生成器时查找 jk-gen-<name> 插件
+		var names []string
+		if server {
+			names = append(names, fmt.Sprintf("%s-%s-server", lang, framework))
+			if swagger {
+				names = append(names, "swagger")
 			}
+		} else if client {
+			names = append(names, fmt.Sprintf("%s-%s-client", lang, framework))
+		}
+
+		req := &generator.Request{
+			Package: pkgPath,
+			Service: service,
+			Parameter: map[string]string{
+				"protocol":      proto,
+				"embed-swagger": strconv.FormatBool(embedSwagger),
+				"zod":           strconv.FormatBool(zod),
+				"with-tests":    strconv.FormatBool(withTests),
+			},
+		}
+		for _, name := range names {
+			err = runGenerator(name, req)
+			cobra.CheckErr(err)
 		}
 	},
 }
@@ -124,136 +103,10 @@ func init() {
 	generateCmd.PersistentFlags().Bool("embed-swagger", false, "embed swagger ui into server code")
 	generateCmd.PersistentFlags().BoolP("server", "s", false, "generate server code")
 	generateCmd.PersistentFlags().BoolP("client", "c", false, "generate client code")
+	generateCmd.PersistentFlags().Bool("zod", false, "generate zod schemas and response validation, works with typescript client")
+	generateCmd.PersistentFlags().Bool("with-tests", false, "generate round trip tests for go server, requires endpoints and go http client code")
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// transportCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
-
-// genHTTPClient 生成HTTP客户端代码。
-func genHTTPClient(service *domain.Service, pkg, filename string) error {
-	f := jen.NewFilePath(pkg)
-	f.HeaderComment(fmt.Sprintf("Code generated by jk %s; DO NOT EDIT.", strings.Join(os.Args[1:], " ")))
-	utils.InitializeFileCommon(f)
-	stdcli.GenerateHTTPTransportClient(f, service)
-
-	err := f.Save(filename)
-	if err != nil {
-		return errors.Wrap(err, "render generated transport layer code failed")
-	}
-
-	return nil
-}
-
-// genHTTPServer 生成HTTP服务器代码。
-func genHTTPServer(service *domain.Service, pkg, filename string, embedSwaggerUI bool) error {
-	f := jen.NewFilePath(pkg)
-	f.HeaderComment(fmt.Sprintf("Code generated by jk %s; DO NOT EDIT.", strings.Join(os.Args[1:], " ")))
-	utils.InitializeFileCommon(f)
-	stdsvr.GenerateHTTPTransportServer(f, service)
-
-	if embedSwaggerUI {
-		stdsvr.GenerateEmbedSwaggerJSON(f, service)
-	}
-
-	err := f.Save(filename)
-	if err != nil {
-		return errors.Wrap(err, "render generated transport layer code failed")
-	}
-
-	return nil
-}
-
-// genGinServer 生成 gin 服务器代码。
-func genGinServer(service *domain.Service, pkg, filename string, embedSwaggerUI bool) error {
-	f := jen.NewFilePath(pkg)
-	f.HeaderComment(fmt.Sprintf("Code generated by jk %s; DO NOT EDIT.", strings.Join(os.Args[1:], " ")))
-	utils.InitializeFileCommon(f)
-	err := gin.GenerateGin(f, service)
-	if err != nil {
-		return errors.Wrap(err, "generate gin server code failed")
-	}
-
-	if embedSwaggerUI {
-		gin.GenerateGinEmbedSwaggerUI(f, service)
-	}
-
-	err = f.Save(filename)
-	if err != nil {
-		return errors.Wrap(err, "render generated transport layer (gin) code failed")
-	}
-
-	return nil
-}
-
-// genSwagger 生成Swagger文档。
-func genSwagger(service *domain.Service, filename string) error {
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
-	if err != nil {
-		return errors.Wrap(err, "open file failed")
-	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			log.Printf("close file failed, error %+v", err)
-		}
-	}(file)
-
-	err = doc.GenerateSwagger(file, service)
-	if err != nil {
-		return errors.Wrap(err, "generate swagger failed")
-	}
-	return nil
-}
-
-// genTypeScriptClient 生成 typescript 客户端代码。
-func genTypeScriptClient(service *domain.Service, filename string) error {
-	_, err := os.Stat("tsconfig.json")
-	if os.IsNotExist(err) {
-		file, err := os.OpenFile("tsconfig.json", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
-		if err != nil {
-			return err
-		}
-		defer func(file *os.File) {
-			_ = file.Close()
-		}(file)
-
-		_, err = fmt.Fprintf(file, `{
-  "compilerOptions": {
-    "target": "ES2015",
-    "module": "ES6",
-    "sourceMap": true,
-    "declaration": true,
-    "declarationMap": true,
-    "preserveConstEnums": true,
-    "lib": [
-      "DOM",
-      "ES2015"
-    ]
-  },
-  "files": [
-    "%s"
-  ]
-}`, filename)
-		if err != nil {
-			return err
-		}
-	}
-
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
-	if err != nil {
-		return errors.Wrap(err, "open file failed")
-	}
-
-	_, err = fmt.Fprintf(file, "// Code generated by jk %s; DO NOT EDIT.\n", strings.Join(os.Args[1:], " "))
-	if err != nil {
-		return errors.Wrap(err, "write typescript api client failed")
-	}
-
-	err = fetch.GenerateTypeScriptClient(file, service)
-	if err != nil {
-		return errors.Wrap(err, "generate typescript api client failed")
-	}
-
-	return nil
-}
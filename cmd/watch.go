@@ -0,0 +1,298 @@
+/*
+Copyright © 2023 weak_ptr <weak_ptr@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/nnnewb/jk/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "regenerate code when service definitions change",
+	Long: `poll go files of service package in current directory, validate service and re-run
+every "//go:generate jk ..." directive of the package on change.
+
+generated files and test files are not watched. bursts of changes are debounced,
+errors are printed and watching continues.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var allErrors error
+		interval, err := cmd.Flags().GetDuration("interval")
+		allErrors = errors.Combine(allErrors, err)
+		debounce, err := cmd.Flags().GetDuration("debounce")
+		allErrors = errors.Combine(allErrors, err)
+		cobra.CheckErr(allErrors)
+
+		w := &watcher{cmd: cmd, generated: make(map[string]watchedFile)}
+		err = w.watch(interval, debounce)
+		cobra.CheckErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringP("typename", "t", "", "name of service interface")
+	watchCmd.Flags().StringP("package", "p", "", "package name of service interface")
+	watchCmd.Flags().Duration("interval", 500*time.Millisecond, "polling interval")
+	watchCmd.Flags().Duration("debounce", 300*time.Millisecond, "wait until no change happens for this duration before regenerating")
+}
+
+// watchedFile 是一次扫描时文件的状态。
+type watchedFile struct {
+	modTime   time.Time
+	size      int64
+	generated bool
+}
+
+type watcher struct {
+	cmd       *cobra.Command
+	generated map[string]watchedFile // 按文件名缓存是否为生成的代码，文件修改后重新判断
+}
+
+// scan 返回当前目录下需要监视的文件状态，跳过测试文件和生成的代码。
+func (w *watcher) scan() (map[string]watchedFile, error) {
+	filenames, err := filepath.Glob("*.go")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ret := make(map[string]watchedFile)
+	for _, filename := range filenames {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+
+		stat, err := os.Stat(filename)
+		if err != nil {
+			// 扫描期间被删除
+			continue
+		}
+
+		state := watchedFile{modTime: stat.ModTime(), size: stat.Size()}
+		cached, ok := w.generated[filename]
+		if ok && cached.modTime.Equal(state.modTime) && cached.size == state.size {
+			state.generated = cached.generated
+		} else {
+			file, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.PackageClauseOnly|parser.ParseComments)
+			state.generated = err == nil && utils.IsGeneratedFile(file)
+			w.generated[filename] = state
+		}
+
+		if !state.generated {
+			ret[filename] = state
+		}
+	}
+	return ret, nil
+}
+
+// targets 返回监视的文件中全部 //go:generate jk 指令的参数，同 go generate 按文件名和文件内的顺序排列。
+func (w *watcher) targets(files map[string]watchedFile) ([][]string, error) {
+	filenames := make([]string, 0, len(files))
+	for filename := range files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	var ret [][]string
+	for _, filename := range filenames {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), filename, content, parser.PackageClauseOnly)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		// 同 go generate 展开 $GOFILE、$GOPACKAGE 和环境变量
+		expand := func(name string) string {
+			switch name {
+			case "GOFILE":
+				return filename
+			case "GOPACKAGE":
+				return file.Name.Name
+			case "DOLLAR":
+				return "$"
+			default:
+				return os.Getenv(name)
+			}
+		}
+
+		for _, line := range strings.Split(string(content), "\n") {
+			if !strings.HasPrefix(line, "//go:generate ") {
+				continue
+			}
+			words, err := splitDirective(strings.TrimPrefix(line, "//go:generate "))
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse %s", strings.TrimSpace(line))
+			}
+			if len(words) > 1 && filepath.Base(words[0]) == "jk" {
+				args := make([]string, 0, len(words)-1)
+				for _, word := range words[1:] {
+					args = append(args, os.Expand(word, expand))
+				}
+				ret = append(ret, args)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// splitDirective 按 go generate 的规则切分指令，空白分隔，双引号字符串按 Go 语法解析。
+func splitDirective(line string) ([]string, error) {
+	var words []string
+	line = strings.TrimSpace(line)
+	for line != "" {
+		if line[0] == '"' {
+			end := 1
+			for ; end < len(line) && line[end] != '"'; end++ {
+				if line[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(line) {
+				return nil, errors.New("unterminated quoted string")
+			}
+			word, err := strconv.Unquote(line[:end+1])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			words = append(words, word)
+			line = line[end+1:]
+		} else {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			words = append(words, line[:end])
+			line = line[end:]
+		}
+		line = strings.TrimLeft(line, " \t")
+	}
+	return words, nil
+}
+
+// validate 解析服务并检查全部方法签名，返回全部错误。
+func (w *watcher) validate() []error {
+	_, service, err := parse(w.cmd)
+	if err != nil {
+		return []error{err}
+	}
+
+	var ret []error
+	for _, method := range service.Methods {
+		signature := method.Func.Type().(*types.Signature)
+		if err := utils.CheckParams(signature.Params()); err != nil {
+			ret = append(ret, errors.Errorf("%s: %v", method.Func.Name(), err))
+		}
+		if err := utils.CheckResults(signature.Results()); err != nil {
+			ret = append(ret, errors.Errorf("%s: %v", method.Func.Name(), err))
+		}
+	}
+	return ret
+}
+
+// regenerate 校验服务后依次执行全部 jk 指令，错误只打印不退出。
+func (w *watcher) regenerate(files map[string]watchedFile) {
+	logf := func(format string, args ...any) {
+		fmt.Printf("%s %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
+	}
+
+	if errs := w.validate(); len(errs) > 0 {
+		for _, err := range errs {
+			logf("invalid service: %v", err)
+		}
+		return
+	}
+
+	targets, err := w.targets(files)
+	if err != nil {
+		logf("read go:generate directives failed: %v", err)
+		return
+	}
+	if len(targets) == 0 {
+		logf("no //go:generate jk directive found")
+		return
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		logf("find jk executable failed: %v", err)
+		return
+	}
+
+	for _, args := range targets {
+		var output bytes.Buffer
+		c := exec.Command(executable, args...)
+		c.Stdout = &output
+		c.Stderr = &output
+		if err := c.Run(); err != nil {
+			logf("jk %s: %s", strings.Join(args, " "), strings.TrimSpace(output.String()))
+			continue
+		}
+		logf("jk %s: ok", strings.Join(args, " "))
+	}
+}
+
+func (w *watcher) watch(interval, debounce time.Duration) error {
+	files, err := w.scan()
+	if err != nil {
+		return err
+	}
+	w.regenerate(files)
+
+	var (
+		pending    bool
+		lastChange time.Time
+	)
+	for {
+		time.Sleep(interval)
+
+		current, err := w.scan()
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(current, files) {
+			files = current
+			pending = true
+			lastChange = time.Now()
+			continue
+		}
+
+		if pending && time.Since(lastChange) >= debounce {
+			pending = false
+			w.regenerate(files)
+		}
+	}
+}
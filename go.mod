@@ -10,6 +10,7 @@ require (
 	github.com/nnnewb/battery v0.2.1
 	github.com/spf13/cobra v1.7.0
 	golang.org/x/mod v0.6.0-dev.0.20220419223038-86c51ed26bb4
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
@@ -26,5 +27,4 @@ require (
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.8.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
@@ -0,0 +1,316 @@
+// Package diff 比较同一个服务的两个版本，找出会破坏已有客户端的改动。
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nnnewb/jk/internal/model"
+)
+
+// Severity 是改动的兼容性。
+type Severity string
+
+const (
+	Breaking   Severity = "breaking"
+	Compatible Severity = "compatible"
+)
+
+// Kind 是改动的类型。
+type Kind string
+
+const (
+	MethodRemoved     Kind = "method-removed"
+	MethodAdded       Kind = "method-added"
+	HTTPMethodChanged Kind = "http-method-changed"
+	HTTPPathChanged   Kind = "http-path-changed"
+	FieldRemoved      Kind = "field-removed"
+	FieldRenamed      Kind = "field-renamed"
+	FieldAdded        Kind = "field-added"
+	FieldTypeChanged  Kind = "field-type-changed"
+	FieldRequired     Kind = "field-required"
+)
+
+// severities 是每种改动的兼容性，除新增以外都会破坏已有客户端。
+var severities = map[Kind]Severity{
+	MethodRemoved:     Breaking,
+	MethodAdded:       Compatible,
+	HTTPMethodChanged: Breaking,
+	HTTPPathChanged:   Breaking,
+	FieldRemoved:      Breaking,
+	FieldRenamed:      Breaking,
+	FieldAdded:        Compatible,
+	FieldTypeChanged:  Breaking,
+	FieldRequired:     Breaking,
+}
+
+// Change 是一处改动。
+type Change struct {
+	Severity Severity `json:"severity"`
+	Kind     Kind     `json:"kind"`
+	Method   string   `json:"method"`
+	Path     string   `json:"path,omitempty"` // 字段路径，如 request.order_info[].item_id
+	Old      string   `json:"old,omitempty"`
+	New      string   `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	subject := c.Method
+	if c.Path != "" {
+		subject += " " + c.Path
+	}
+
+	switch {
+	case c.Old != "" && c.New != "":
+		return fmt.Sprintf("[%s] %s: %s -> %s", c.Kind, subject, c.Old, c.New)
+	case c.Old != "":
+		return fmt.Sprintf("[%s] %s: %s", c.Kind, subject, c.Old)
+	case c.New != "":
+		return fmt.Sprintf("[%s] %s: %s", c.Kind, subject, c.New)
+	default:
+		return fmt.Sprintf("[%s] %s", c.Kind, subject)
+	}
+}
+
+// Report 是比较的结果。
+type Report struct {
+	Breaking bool     `json:"breaking"`
+	Changes  []Change `json:"changes"`
+}
+
+// Changelog 返回可读的改动列表，破坏性改动在前。
+func (r *Report) Changelog() string {
+	var sb strings.Builder
+	titles := map[Severity]string{Breaking: "Breaking", Compatible: "Compatible"}
+	for _, severity := range []Severity{Breaking, Compatible} {
+		var lines []string
+		for _, change := range r.Changes {
+			if change.Severity == severity {
+				lines = append(lines, "  - "+change.String())
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s changes:\n%s\n", titles[severity], strings.Join(lines, "\n"))
+	}
+	if sb.Len() == 0 {
+		return "No changes.\n"
+	}
+	return sb.String()
+}
+
+type comparer struct {
+	old, new *model.Service
+	method   string
+	visited  map[[2]string]bool // 已经比较过的命名类型，避免递归类型无限展开
+	report   *Report
+}
+
+func (c *comparer) add(kind Kind, path, old, new string) {
+	severity := severities[kind]
+	c.report.Changes = append(c.report.Changes, Change{
+		Severity: severity,
+		Kind:     kind,
+		Method:   c.method,
+		Path:     path,
+		Old:      old,
+		New:      new,
+	})
+	if severity == Breaking {
+		c.report.Breaking = true
+	}
+}
+
+// Compare 比较服务的两个版本。只比较 JSON 的结构，Go 类型改名和指针的增减不算改动。
+func Compare(old, new *model.Service) *Report {
+	c := &comparer{old: old, new: new, report: &Report{Changes: []Change{}}}
+
+	newMethods := make(map[string]*model.Method)
+	for i := range new.Methods {
+		newMethods[new.Methods[i].Name] = &new.Methods[i]
+	}
+
+	oldMethods := make(map[string]bool)
+	for i := range old.Methods {
+		oldMethod := &old.Methods[i]
+		oldMethods[oldMethod.Name] = true
+		c.method = oldMethod.Name
+
+		newMethod, ok := newMethods[oldMethod.Name]
+		if !ok {
+			c.add(MethodRemoved, "", oldMethod.HTTPMethod+" "+oldMethod.HTTPPath, "")
+			continue
+		}
+
+		if oldMethod.HTTPMethod != newMethod.HTTPMethod {
+			c.add(HTTPMethodChanged, "", oldMethod.HTTPMethod, newMethod.HTTPMethod)
+		}
+		if oldMethod.HTTPPath != newMethod.HTTPPath {
+			c.add(HTTPPathChanged, "", oldMethod.HTTPPath, newMethod.HTTPPath)
+		}
+
+		c.visited = make(map[[2]string]bool)
+		c.compareType("request", oldMethod.Request, newMethod.Request)
+		c.compareType("response", oldMethod.Response, newMethod.Response)
+	}
+
+	for _, method := range new.Methods {
+		if !oldMethods[method.Name] {
+			c.method = method.Name
+			c.add(MethodAdded, "", "", method.HTTPMethod+" "+method.HTTPPath)
+		}
+	}
+
+	return c.report
+}
+
+// lookup 返回命名类型的定义。
+func lookup(service *model.Service, t *model.Type) *model.Named {
+	for i := range service.Types {
+		if service.Types[i].Name == t.Name && service.Types[i].Package == t.Package {
+			return &service.Types[i]
+		}
+	}
+	return nil
+}
+
+// resolve 去掉指针，把命名类型展开为底层类型，返回展开前的最后一个命名类型名。
+func resolve(service *model.Service, t *model.Type) (*model.Type, string) {
+	var name string
+	for t != nil {
+		switch t.Kind {
+		case model.KindPointer:
+			t = t.Elem
+		case model.KindNamed:
+			named := lookup(service, t)
+			if named == nil {
+				return t, name
+			}
+			name = t.Package + "." + t.Name
+			t = named.Underlying
+		default:
+			return t, name
+		}
+	}
+	return t, name
+}
+
+// shape 返回类型在 JSON 中的形状，用于报告类型改动。
+func shape(service *model.Service, t *model.Type) string {
+	t, _ = resolve(service, t)
+	switch t.Kind {
+	case model.KindBasic:
+		return t.Name
+	case model.KindNamed:
+		return t.Package + "." + t.Name
+	case model.KindSlice, model.KindArray:
+		return "[]" + shape(service, t.Elem)
+	case model.KindChan:
+		return "<-chan " + shape(service, t.Elem)
+	case model.KindMap:
+		return "map[" + shape(service, t.Key) + "]" + shape(service, t.Elem)
+	case model.KindStruct:
+		return "object"
+	default:
+		return string(t.Kind)
+	}
+}
+
+func (c *comparer) compareType(path string, oldType, newType *model.Type) {
+	oldResolved, oldName := resolve(c.old, oldType)
+	newResolved, newName := resolve(c.new, newType)
+
+	if oldName != "" && newName != "" {
+		key := [2]string{oldName, newName}
+		if c.visited[key] {
+			return
+		}
+		c.visited[key] = true
+	}
+
+	// 切片和数组在 JSON 中都是数组
+	oldKind, newKind := oldResolved.Kind, newResolved.Kind
+	if oldKind == model.KindArray {
+		oldKind = model.KindSlice
+	}
+	if newKind == model.KindArray {
+		newKind = model.KindSlice
+	}
+	if oldKind != newKind {
+		c.add(FieldTypeChanged, path, shape(c.old, oldType), shape(c.new, newType))
+		return
+	}
+
+	switch oldKind {
+	case model.KindBasic, model.KindNamed:
+		if oldResolved.Name != newResolved.Name || oldResolved.Package != newResolved.Package {
+			c.add(FieldTypeChanged, path, shape(c.old, oldType), shape(c.new, newType))
+		}
+	case model.KindSlice:
+		c.compareType(path+"[]", oldResolved.Elem, newResolved.Elem)
+	case model.KindChan:
+		c.compareType(path, oldResolved.Elem, newResolved.Elem)
+	case model.KindMap:
+		if shape(c.old, oldResolved.Key) != shape(c.new, newResolved.Key) {
+			c.add(FieldTypeChanged, path, shape(c.old, oldType), shape(c.new, newType))
+			return
+		}
+		c.compareType(path+"{}", oldResolved.Elem, newResolved.Elem)
+	case model.KindStruct:
+		c.compareFields(path, c.flatten(c.old, oldResolved.Fields), c.flatten(c.new, newResolved.Fields))
+	}
+}
+
+// flatten 展开没有 json 名称的嵌入结构体，和 encoding/json 一样把它们的字段提升到外层。
+func (c *comparer) flatten(service *model.Service, fields []model.Field) []model.Field {
+	ret := make([]model.Field, 0, len(fields))
+	for _, field := range fields {
+		name := strings.Split(reflect.StructTag(field.Tag).Get("json"), ",")[0]
+		if field.Embedded && name == "" {
+			if t, _ := resolve(service, field.Type); t.Kind == model.KindStruct {
+				ret = append(ret, c.flatten(service, t.Fields)...)
+				continue
+			}
+		}
+		ret = append(ret, field)
+	}
+	return ret
+}
+
+func (c *comparer) compareFields(path string, oldFields, newFields []model.Field) {
+	newByJSON := make(map[string]*model.Field)
+	newByName := make(map[string]*model.Field)
+	for i := range newFields {
+		newByJSON[newFields[i].JSONName] = &newFields[i]
+		newByName[newFields[i].Name] = &newFields[i]
+	}
+
+	matched := make(map[string]bool)
+	for _, oldField := range oldFields {
+		fieldPath := path + "." + oldField.JSONName
+		newField, ok := newByJSON[oldField.JSONName]
+		if !ok {
+			if renamed, ok := newByName[oldField.Name]; ok && newByJSON[renamed.JSONName] == renamed {
+				matched[renamed.JSONName] = true
+				c.add(FieldRenamed, fieldPath, oldField.JSONName, renamed.JSONName)
+			} else {
+				c.add(FieldRemoved, fieldPath, "", "")
+			}
+			continue
+		}
+
+		matched[newField.JSONName] = true
+		c.compareType(fieldPath, oldField.Type, newField.Type)
+		if oldField.OmitEmpty && !newField.OmitEmpty {
+			c.add(FieldRequired, fieldPath, "", "")
+		}
+	}
+
+	for _, newField := range newFields {
+		if !matched[newField.JSONName] {
+			c.add(FieldAdded, path+"."+newField.JSONName, "", shape(c.new, newField.Type))
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/nnnewb/jk/internal/model"
+)
+
+const pkg = "example/api/order"
+
+func basic(name string) *model.Type {
+	return &model.Type{Kind: model.KindBasic, Name: name}
+}
+
+func named(name string) *model.Type {
+	return &model.Type{Kind: model.KindNamed, Name: name, Package: pkg}
+}
+
+func ptr(elem *model.Type) *model.Type {
+	return &model.Type{Kind: model.KindPointer, Elem: elem}
+}
+
+func structOf(name string, fields ...model.Field) model.Named {
+	return model.Named{Name: name, Package: pkg, Underlying: &model.Type{Kind: model.KindStruct, Fields: fields}}
+}
+
+func field(name, jsonName string, typ *model.Type) model.Field {
+	return model.Field{Name: name, JSONName: jsonName, Type: typ}
+}
+
+// service 返回只有一个方法 Get 的服务，请求和响应的字段由参数给出。
+func service(request, response []model.Field, types ...model.Named) *model.Service {
+	return &model.Service{
+		Package: pkg,
+		Name:    "Service",
+		Methods: []model.Method{{
+			Name:       "Get",
+			HTTPMethod: "GET",
+			HTTPPath:   "/get",
+			Request:    ptr(named("GetRequest")),
+			Response:   ptr(named("GetResponse")),
+		}},
+		Types: append([]model.Named{
+			structOf("GetRequest", request...),
+			structOf("GetResponse", response...),
+		}, types...),
+	}
+}
+
+func kinds(report *Report) []Kind {
+	ret := make([]Kind, 0, len(report.Changes))
+	for _, change := range report.Changes {
+		ret = append(ret, change.Kind)
+	}
+	return ret
+}
+
+func TestCompare(t *testing.T) {
+	id := field("ID", "id", basic("string"))
+	optional := model.Field{Name: "Page", JSONName: "page", OmitEmpty: true, Type: ptr(basic("int"))}
+
+	cases := []struct {
+		name     string
+		old, new *model.Service
+		want     []Kind
+		breaking bool
+	}{
+		{
+			name: "no changes",
+			old:  service([]model.Field{id}, nil),
+			new:  service([]model.Field{id}, nil),
+			want: []Kind{},
+		},
+		{
+			name: "field added",
+			old:  service([]model.Field{id}, nil),
+			new:  service([]model.Field{id, optional}, nil),
+			want: []Kind{FieldAdded},
+		},
+		{
+			name:     "field removed",
+			old:      service([]model.Field{id, optional}, nil),
+			new:      service([]model.Field{id}, nil),
+			want:     []Kind{FieldRemoved},
+			breaking: true,
+		},
+		{
+			name:     "json name changed",
+			old:      service([]model.Field{id}, nil),
+			new:      service([]model.Field{field("ID", "order_id", basic("string"))}, nil),
+			want:     []Kind{FieldRenamed},
+			breaking: true,
+		},
+		{
+			name:     "field type changed",
+			old:      service(nil, []model.Field{id}),
+			new:      service(nil, []model.Field{field("ID", "id", basic("int64"))}),
+			want:     []Kind{FieldTypeChanged},
+			breaking: true,
+		},
+		{
+			name:     "field became required",
+			old:      service([]model.Field{optional}, nil),
+			new:      service([]model.Field{field("Page", "page", ptr(basic("int")))}, nil),
+			want:     []Kind{FieldRequired},
+			breaking: true,
+		},
+		{
+			name: "go type renamed",
+			old: service([]model.Field{field("Item", "item", named("Item"))}, nil,
+				structOf("Item", id)),
+			new: service([]model.Field{field("Item", "item", ptr(named("OrderItem")))}, nil,
+				structOf("OrderItem", id)),
+			want: []Kind{},
+		},
+		{
+			name: "nested field removed",
+			old: service(nil, []model.Field{field("Items", "items", &model.Type{Kind: model.KindSlice, Elem: named("Item")})},
+				structOf("Item", id, optional)),
+			new: service(nil, []model.Field{field("Items", "items", &model.Type{Kind: model.KindSlice, Elem: named("Item")})},
+				structOf("Item", id)),
+			want:     []Kind{FieldRemoved},
+			breaking: true,
+		},
+		{
+			name: "recursive type",
+			old: service([]model.Field{field("Node", "node", ptr(named("Node")))}, nil,
+				structOf("Node", field("Children", "children", &model.Type{Kind: model.KindSlice, Elem: named("Node")}))),
+			new: service([]model.Field{field("Node", "node", ptr(named("Node")))}, nil,
+				structOf("Node", field("Children", "children", &model.Type{Kind: model.KindSlice, Elem: named("Node")}), optional)),
+			want: []Kind{FieldAdded},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			report := Compare(c.old, c.new)
+			got := kinds(report)
+			if len(got) != len(c.want) {
+				t.Fatalf("changes = %v, want %v", got, c.want)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Fatalf("changes = %v, want %v", got, c.want)
+				}
+			}
+			if report.Breaking != c.breaking {
+				t.Errorf("breaking = %v, want %v", report.Breaking, c.breaking)
+			}
+		})
+	}
+}
+
+func TestCompareMethods(t *testing.T) {
+	old := service(nil, nil)
+	new := service(nil, nil)
+	new.Methods[0].HTTPMethod = "POST"
+	new.Methods[0].HTTPPath = "/v2/get"
+	new.Methods = append(new.Methods, model.Method{Name: "List", HTTPMethod: "GET", HTTPPath: "/list",
+		Request: ptr(named("GetRequest")), Response: ptr(named("GetResponse"))})
+
+	report := Compare(old, new)
+	want := []Kind{HTTPMethodChanged, HTTPPathChanged, MethodAdded}
+	got := kinds(report)
+	if len(got) != len(want) {
+		t.Fatalf("changes = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("changes = %v, want %v", got, want)
+		}
+	}
+
+	report = Compare(new, old)
+	if !report.Breaking || report.Changes[len(report.Changes)-1].Kind != MethodRemoved {
+		t.Errorf("removing method should be breaking, got %v", kinds(report))
+	}
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// authSchemes 是 @http-auth 注解支持的认证方式，none 表示方法不需要认证。
+var authSchemes = []string{"bearer", "basic", "apikey", "none"}
+
+// AuthScheme 返回方法的认证方式 bearer、basic 或 apikey，方法的 @http-auth 覆盖服务的 @http-auth，
+// 不需要认证时返回空字符串。
+func (m *Method) AuthScheme() string {
+	scheme := m.Annotations.HTTPAuth
+	if scheme == "" && m.parent != nil {
+		scheme = m.parent.Annotations.HTTPAuth
+	}
+	if scheme == "none" {
+		return ""
+	}
+	return scheme
+}
+
+// normalizeAuthScheme 检查并规范化 @http-auth 注解的值，where 用于错误信息。
+func normalizeAuthScheme(value *string, where string) error {
+	*value = strings.ToLower(strings.TrimSpace(*value))
+	if *value == "" {
+		return nil
+	}
+	for _, scheme := range authSchemes {
+		if *value == scheme {
+			return nil
+		}
+	}
+	return errors.Errorf("invalid @http-auth value %q of %s, expect one of %s", *value, where, strings.Join(authSchemes, ", "))
+}
@@ -0,0 +1,78 @@
+// Package domaintest 为生成器的测试从源代码构造 domain.Service。
+package domaintest
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/nnnewb/jk/internal/domain"
+)
+
+// PkgPath 是测试源代码的包路径。
+const PkgPath = "example/api/order"
+
+// Package 类型检查 src，src 只能导入标准库。
+func Package(t testing.TB, src string) (*types.Package, *ast.Package) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "service.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("parse source failed: %+v", err)
+	}
+
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := config.Check(PkgPath, fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("type check source failed: %+v", err)
+	}
+	return pkg, &ast.Package{Name: file.Name.Name, Files: map[string]*ast.File{"service.go": file}}
+}
+
+// Service 类型检查 src，返回其中名为 name 的服务接口。
+func Service(t testing.TB, src, name string) *domain.Service {
+	t.Helper()
+	pkg, astPkg := Package(t, src)
+	service, err := domain.ParseInterfaceData(pkg, astPkg, name)
+	if err != nil {
+		t.Fatalf("parse service %s failed: %+v", name, err)
+	}
+	return service
+}
+
+// Struct 类型检查 src，返回其中名为 name 的结构体。
+func Struct(t testing.TB, src, name string) *types.Struct {
+	t.Helper()
+	pkg, _ := Package(t, src)
+	obj := pkg.Scope().Lookup(name)
+	if obj == nil {
+		t.Fatalf("type %s not found", name)
+	}
+	structType, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		t.Fatalf("%s is not a struct type", name)
+	}
+	return structType
+}
+
+// Contains 检查生成的代码 code 包含 want 中的每一段，缺少时报告错误。
+func Contains(t testing.TB, code string, want ...string) {
+	t.Helper()
+	for _, s := range want {
+		if !strings.Contains(code, s) {
+			t.Errorf("generated code does not contain %q:\n%s", s, code)
+		}
+	}
+}
+
+// ErrorContains 检查 err 不为 nil 并且错误信息包含 want。
+func ErrorContains(t testing.TB, err error, want string) {
+	t.Helper()
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %v, want error containing %q", err, want)
+	}
+}
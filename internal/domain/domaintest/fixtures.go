@@ -0,0 +1,60 @@
+package domaintest
+
+// EmbeddedSource 是包含 time.Time、嵌入结构体和 Go 字段名冲突的服务，服务接口名为 Service。
+//
+// CreateRequest 嵌入 Base 和 *Meta，UserID 和 UserId 转换为 snake_case 或 kebab-case 后同名；
+// CreateResponse 嵌入 Base，Code 和 Message 直接声明。
+const EmbeddedSource = `package order
+
+import (
+	"context"
+	"time"
+)
+
+type Base struct {
+	Tenant    string    ` + "`json:\"tenant\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\"`" + `
+}
+
+type Meta struct {
+	Source string ` + "`json:\"source\"`" + `
+}
+
+type CreateRequest struct {
+	Base
+	*Meta
+	UserID   string     ` + "`json:\"userID\"`" + `
+	UserId   string     ` + "`json:\"user_id\"`" + `
+	Deadline *time.Time ` + "`json:\"deadline,omitempty\"`" + `
+}
+
+type CreateResponse struct {
+	Code    int    ` + "`json:\"code\"`" + `
+	Message string ` + "`json:\"message\"`" + `
+	Base
+}
+
+type Service interface {
+	Create(ctx context.Context, req *CreateRequest) (*CreateResponse, error)
+}
+`
+
+// CollisionSource 是使用两个同名类型 FileHeader 的服务，一个来自 mime/multipart，一个在服务所在的包中声明，
+// 服务接口名为 Service。
+const CollisionSource = `package order
+
+import (
+	"context"
+	"mime/multipart"
+)
+
+type FileHeader struct {
+	Code    int    ` + "`json:\"code\"`" + `
+	Message string ` + "`json:\"message\"`" + `
+	Name    string ` + "`json:\"name\"`" + `
+}
+
+type Service interface {
+	Open(ctx context.Context, req *multipart.FileHeader) (*FileHeader, error)
+}
+`
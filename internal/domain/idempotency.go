@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"emperror.dev/errors"
+)
+
+// IdempotencyKeyRequired 判断 @idempotent 方法的请求是否必须携带幂等键，即注解值为 required。
+func (m *Method) IdempotencyKeyRequired() bool {
+	return m.Annotations.Idempotent && m.RawAnnotations["idempotent"] == "required"
+}
+
+// checkIdempotent 检查 @idempotent 注解。
+//
+// 服务端保存并重放完整的响应，所以只支持返回响应结构体的方法。
+func checkIdempotent(m *Method) error {
+	if value := m.RawAnnotations["idempotent"]; value != "" && value != "required" {
+		return errors.Errorf("invalid @idempotent value %q of method %s, expect empty or required", value, m.Func.Name())
+	}
+	return checkResponseStruct(m, "idempotent")
+}
@@ -4,14 +4,21 @@ import (
 	"go/ast"
 	"go/types"
 	"net/http"
+	"strings"
 
 	"emperror.dev/errors"
 	"github.com/dave/jennifer/jen"
 )
 
 type MethodAnnotations struct {
-	HTTPMethod string `jk:"http-method"`
-	HTTPPath   string `jk:"http-path"`
+	HTTPMethod      string `jk:"http-method"`
+	HTTPPath        string `jk:"http-path"`
+	RateLimit       string `jk:"rate-limit"`        // 限流，如 100/s
+	CircuitBreaker  bool   `jk:"circuit-breaker"`   // 熔断
+	Timeout         string `jk:"timeout"`           // 超时，如 3s
+	HTTPContentType string `jk:"http-content-type"` // 请求体格式，如 multipart/form-data，默认为 JSON
+	Idempotent      bool   `jk:"idempotent"`        // 幂等键，值为 required 时请求必须携带幂等键
+	HTTPAuth        string `jk:"http-auth"`         // 认证方式 bearer、basic、apikey，none 表示不需要认证，默认使用服务的认证方式
 }
 
 type Method struct {
@@ -19,6 +26,14 @@ type Method struct {
 	Func        *types.Func
 	Field       *ast.Field
 	Annotations *MethodAnnotations
+
+	RawAnnotations map[string]string // 全部注解，包括 MethodAnnotations 未定义的
+	Pagination     *Pagination       // @paginated 注解配置的分页字段，不分页的方法为 nil
+}
+
+// Doc 返回方法的文档注释，不含注解行。
+func (m *Method) Doc() string {
+	return commentText(m.Field.Doc)
 }
 
 func (m *Method) HTTPMethodJen() *jen.Statement {
@@ -68,15 +83,24 @@ func (m *Method) ResponseType() types.Type {
 	return signature.Results().At(0).Type()
 }
 
+// ResponseTypeName 返回响应类型名，SSE 方法返回事件类型名。
 func (m *Method) ResponseTypeName() string {
-	signature := m.Func.Type().(*types.Signature)
-	if signature.Params().Len() != 2 {
-		panic(errors.Errorf("unexpected function signature: params type mismatch, got %s", signature))
-	}
+	return m.ResponseNamed().Obj().Name()
+}
 
-	ptrType := signature.Results().At(0).Type().(*types.Pointer)
-	named := ptrType.Elem().(*types.Named)
-	return named.Obj().Name()
+// IsEventStream 判断方法是否以 SSE 推送事件，即返回值是 <-chan *T。
+func (m *Method) IsEventStream() bool {
+	_, ok := m.ResponseType().(*types.Chan)
+	return ok
+}
+
+// ResponseNamed 返回 *T 或 <-chan *T 中的 T。
+func (m *Method) ResponseNamed() *types.Named {
+	typ := m.ResponseType()
+	if ch, ok := typ.(*types.Chan); ok {
+		typ = ch.Elem()
+	}
+	return typ.(*types.Pointer).Elem().(*types.Named)
 }
 
 func (m *Method) RequestTypeCodeJen() *jen.Statement {
@@ -86,9 +110,34 @@ func (m *Method) RequestTypeCodeJen() *jen.Statement {
 	return jen.Qual(named.Obj().Pkg().Path(), named.Obj().Name())
 }
 
+// ResponseTypeCodeJen 返回响应类型 T，SSE 方法返回事件类型。
 func (m *Method) ResponseTypeCodeJen() *jen.Statement {
 	// 一般来说这个类型是 *T，也就是 ptr->named->struct
-	ptr := m.ResponseType().(*types.Pointer)
-	named := ptr.Elem().(*types.Named)
+	named := m.ResponseNamed()
 	return jen.Qual(named.Obj().Pkg().Path(), named.Obj().Name())
 }
+
+// ResultTypeCodeJen 返回方法第一个返回值的类型，即 *T，SSE 方法是 <-chan *T。
+func (m *Method) ResultTypeCodeJen() *jen.Statement {
+	if m.IsEventStream() {
+		return jen.Op("<-").Chan().Op("*").Add(m.ResponseTypeCodeJen())
+	}
+	return jen.Op("*").Add(m.ResponseTypeCodeJen())
+}
+
+// commentText 返回注释文本，去掉以 @ 开头的注解行和首尾空行。
+func commentText(cg *ast.CommentGroup) string {
+	if cg == nil {
+		return ""
+	}
+
+	lines := strings.Split(cg.Text(), "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "@") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
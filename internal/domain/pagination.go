@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"go/types"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"emperror.dev/errors"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+// Pagination 是 @paginated 方法的分页字段。
+//
+// 请求的 PageToken 为空时查询第一页，响应的 NextPageToken 为空表示已经是最后一页。
+type Pagination struct {
+	PageToken     *types.Var // 请求中的页令牌，string
+	PageSize      *types.Var // 请求中的每页数量，整数
+	NextPageToken *types.Var // 响应中的下一页令牌，string
+	Items         *types.Var // 响应中当前页的数据，切片
+}
+
+// ItemType 返回每页数据切片的元素类型。
+func (p *Pagination) ItemType() types.Type {
+	return p.Items.Type().Underlying().(*types.Slice).Elem()
+}
+
+// paginationKeys 是 @paginated 注解可以配置的字段及默认的 Go 字段名。
+var paginationKeys = []struct {
+	key          string
+	defaultField string
+}{
+	{"page-token", "PageToken"},
+	{"page-size", "PageSize"},
+	{"next-page-token", "NextPageToken"},
+	{"items", "Items"},
+}
+
+// parsePagination 解析 @paginated 注解，value 是以逗号或空格分隔的 key=字段名，
+// 如 page-token=Cursor, items=Orders，没有配置的字段使用默认字段名。
+func parsePagination(m *Method, value string) (*Pagination, error) {
+	fields := make(map[string]string, len(paginationKeys))
+	for _, k := range paginationKeys {
+		fields[k.key] = k.defaultField
+	}
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		key, field, ok := strings.Cut(item, "=")
+		if _, known := fields[key]; !ok || !known || field == "" {
+			return nil, errors.Errorf("invalid @paginated option %q of method %s, expect one of page-token, page-size, next-page-token, items=<field name>", item, m.Func.Name())
+		}
+		fields[key] = field
+	}
+
+	if err := checkResponseStruct(m, "paginated"); err != nil {
+		return nil, err
+	}
+
+	reqType := m.RequestType().(*types.Pointer).Elem().Underlying().(*types.Struct)
+	respType := m.ResponseNamed().Underlying().(*types.Struct)
+
+	var (
+		ret = &Pagination{}
+		err error
+	)
+	ret.PageToken, err = paginationField(m, "request", reqType, fields["page-token"], isString)
+	if err != nil {
+		return nil, err
+	}
+	ret.PageSize, err = paginationField(m, "request", reqType, fields["page-size"], isInteger)
+	if err != nil {
+		return nil, err
+	}
+	ret.NextPageToken, err = paginationField(m, "response", respType, fields["next-page-token"], isString)
+	if err != nil {
+		return nil, err
+	}
+	ret.Items, err = paginationField(m, "response", respType, fields["items"], isItemSlice)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// checkResponseStruct 检查方法签名，注解 annotation 只支持返回响应结构体的方法，不支持文件流和 SSE。
+func checkResponseStruct(m *Method, annotation string) error {
+	signature := m.Func.Type().(*types.Signature)
+	if err := utils.CheckParams(signature.Params()); err != nil {
+		return errors.Wrapf(err, "check method signature: %s", m.Func.FullName())
+	}
+	if err := utils.CheckResults(signature.Results()); err != nil {
+		return errors.Wrapf(err, "check method signature: %s", m.Func.FullName())
+	}
+	if ptr, ok := m.ResponseType().(*types.Pointer); !ok || utils.IsStreamType(ptr.Elem()) {
+		return errors.Errorf("@%s method %s must return response struct, not stream or events", annotation, m.Func.Name())
+	}
+	return nil
+}
+
+// paginationField 查找请求或响应结构体中名为 name 的字段并检查类型，where 用于错误信息。
+func paginationField(m *Method, where string, structType *types.Struct, name string, check func(types.Type) (string, bool)) (*types.Var, error) {
+	for i := 0; i < structType.NumFields(); i++ {
+		field := structType.Field(i)
+		if field.Name() != name {
+			continue
+		}
+		if want, ok := check(field.Type()); !ok {
+			return nil, errors.Errorf("pagination field %s in %s of method %s must be %s, got %s", name, where, m.Func.Name(), want, field.Type())
+		}
+		if !field.Exported() || reflect.StructTag(structType.Tag(i)).Get("json") == "-" {
+			return nil, errors.Errorf("pagination field %s in %s of method %s must be exported and serialized", name, where, m.Func.Name())
+		}
+		return field, nil
+	}
+	return nil, errors.Errorf("pagination field %s not found in %s of method %s", name, where, m.Func.Name())
+}
+
+func isString(t types.Type) (string, bool) {
+	basic, ok := t.Underlying().(*types.Basic)
+	return "string", ok && basic.Kind() == types.String
+}
+
+func isInteger(t types.Type) (string, bool) {
+	basic, ok := t.Underlying().(*types.Basic)
+	return "integer", ok && basic.Info()&types.IsInteger != 0
+}
+
+func isItemSlice(t types.Type) (string, bool) {
+	slice, ok := t.Underlying().(*types.Slice)
+	if !ok {
+		return "slice", false
+	}
+	// []byte 编码为 base64 字符串，不是列表
+	basic, isBasic := slice.Elem().Underlying().(*types.Basic)
+	return "slice", !isBasic || basic.Kind() != types.Uint8
+}
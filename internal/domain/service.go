@@ -14,6 +14,8 @@ type ServiceAnnotations struct {
 	SwaggerInfoAPIVersion string `jk:"swagger-info-api-version"`
 	SwaggerInfoAPITitle   string `jk:"swagger-info-api-title"`
 	HTTPBasePath          string `jk:"http-base-path"`
+	HTTPCodecs            string `jk:"http-codecs"` // JSON 以外的请求体和响应格式，如 msgpack, cbor
+	HTTPAuth              string `jk:"http-auth"`   // 所有方法默认的认证方式 bearer、basic 或 apikey
 }
 
 type Service struct {
@@ -22,15 +24,63 @@ type Service struct {
 	TypeSpec    *ast.TypeSpec       // 如果是 /* document here */ type xxx interface
 	Annotations *ServiceAnnotations // 以@开头写在注释里的注解
 
+	RawAnnotations map[string]string // 全部注解，包括 ServiceAnnotations 未定义的
+
 	Methods []*Method // 预先解析好的 method 列表
+
+	docs map[token.Pos]*ast.CommentGroup // 包内类型声明和结构体字段的文档注释，按标识符位置索引
 }
 
 func (s *Service) Name() string {
 	return s.Interface.Obj().Name()
 }
 
+// Doc 返回服务接口的文档注释，不含注解行。
+func (s *Service) Doc() string {
+	return s.ObjectDoc(s.Interface.Obj())
+}
+
+// ObjectDoc 返回服务所在包内声明的类型或结构体字段的文档注释，不含注解行。
+// 其他包中声明的对象没有语法树可用，总是返回空字符串。
+func (s *Service) ObjectDoc(obj types.Object) string {
+	return commentText(s.docs[obj.Pos()])
+}
+
+// indexDocs 收集包内所有类型声明和结构体字段的文档注释。
+func indexDocs(astPkg *ast.Package) map[token.Pos]*ast.CommentGroup {
+	ret := make(map[token.Pos]*ast.CommentGroup)
+	ast.Inspect(astPkg, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.GenDecl:
+			if n.Tok != token.TYPE {
+				return true
+			}
+			for _, spec := range n.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if typeSpec.Doc == nil && len(n.Specs) == 1 {
+					ret[typeSpec.Name.Pos()] = n.Doc
+				} else {
+					ret[typeSpec.Name.Pos()] = typeSpec.Doc
+				}
+			}
+		case *ast.StructType:
+			for _, field := range n.Fields.List {
+				doc := field.Doc
+				if doc == nil {
+					doc = field.Comment
+				}
+				for _, name := range field.Names {
+					ret[name.Pos()] = doc
+				}
+			}
+		}
+		return true
+	})
+	return ret
+}
+
 func ParseInterfaceData(pkg *types.Package, astPkg *ast.Package, name string) (*Service, error) {
-	ret := &Service{}
+	ret := &Service{docs: indexDocs(astPkg)}
 
 	ast.Inspect(astPkg, func(node ast.Node) bool {
 		switch n := node.(type) {
@@ -79,10 +129,15 @@ func ParseInterfaceData(pkg *types.Package, astPkg *ast.Package, name string) (*
 	}
 
 	ret.Annotations = &ServiceAnnotations{}
+	ret.RawAnnotations = utils.ParseAnnotations(cg)
 	err := utils.UnmarshalAnnotations(cg, ret.Annotations)
 	if err != nil {
 		return nil, err
 	}
+	err = normalizeAuthScheme(&ret.Annotations.HTTPAuth, "service "+name)
+	if err != nil {
+		return nil, err
+	}
 
 	// 预先解析所有方法
 	interfaceType := ret.Interface.Underlying().(*types.Interface)
@@ -93,14 +148,31 @@ func ParseInterfaceData(pkg *types.Package, astPkg *ast.Package, name string) (*
 		for _, field := range astInterfaceType.Methods.List {
 			if field.Names[0].Name == m.Name() {
 				method := &Method{
-					Func:        m,
-					Field:       field,
-					Annotations: &MethodAnnotations{},
+					parent:         ret,
+					Func:           m,
+					Field:          field,
+					Annotations:    &MethodAnnotations{},
+					RawAnnotations: utils.ParseAnnotations(field.Doc),
 				}
 				err := utils.UnmarshalAnnotations(field.Doc, method.Annotations)
 				if err != nil {
 					return nil, err
 				}
+				err = normalizeAuthScheme(&method.Annotations.HTTPAuth, "method "+m.Name())
+				if err != nil {
+					return nil, err
+				}
+				if value, ok := method.RawAnnotations["paginated"]; ok {
+					method.Pagination, err = parsePagination(method, value)
+					if err != nil {
+						return nil, err
+					}
+				}
+				if method.Annotations.Idempotent {
+					if err = checkIdempotent(method); err != nil {
+						return nil, err
+					}
+				}
 				ret.Methods = append(ret.Methods, method)
 			}
 		}
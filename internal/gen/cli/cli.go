@@ -0,0 +1,634 @@
+package cli
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/dave/jennifer/jen"
+	"github.com/iancoleman/strcase"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+const (
+	cobraPkg  = "github.com/spf13/cobra"
+	khttpPkg  = "github.com/go-kit/kit/transport/http"
+	ioPkg     = "io"
+	jsonPkg   = "encoding/json"
+	stringPkg = "strings"
+)
+
+// flagKinds 是 pflag 支持的基本类型，值为 FlagSet 上对应的方法名。
+var flagKinds = map[types.BasicKind]string{
+	types.Bool:    "Bool",
+	types.String:  "String",
+	types.Int:     "Int",
+	types.Int8:    "Int8",
+	types.Int16:   "Int16",
+	types.Int32:   "Int32",
+	types.Int64:   "Int64",
+	types.Uint:    "Uint",
+	types.Uint8:   "Uint8",
+	types.Uint16:  "Uint16",
+	types.Uint32:  "Uint32",
+	types.Uint64:  "Uint64",
+	types.Float32: "Float32",
+	types.Float64: "Float64",
+}
+
+// sliceFlagKinds 是 pflag 支持的切片类型，元素必须是未命名的基本类型。
+var sliceFlagKinds = map[types.BasicKind]string{
+	types.Bool:    "BoolSlice",
+	types.String:  "StringSlice",
+	types.Int:     "IntSlice",
+	types.Int32:   "Int32Slice",
+	types.Int64:   "Int64Slice",
+	types.Uint:    "UintSlice",
+	types.Float32: "Float32Slice",
+	types.Float64: "Float64Slice",
+}
+
+// mapFlagKinds 是 pflag 支持的 map[string]T 类型。
+var mapFlagKinds = map[types.BasicKind]string{
+	types.String: "StringToString",
+	types.Int:    "StringToInt",
+	types.Int64:  "StringToInt64",
+}
+
+// reservedFlags 是生成的命令已经使用的参数名，同名的请求字段只能通过 --data 传入。
+var reservedFlags = map[string]bool{
+	"data":     true,
+	"help":     true,
+	"base-url": true,
+	"header":   true,
+	"output":   true,
+}
+
+// requestFlag 描述由请求字段生成的命令行参数。
+type requestFlag struct {
+	name     string         // 参数名，kebab-case 的 json 字段名
+	variable string         // 保存参数值的局部变量名
+	field    *types.Var     // 请求结构体字段
+	selector *jen.Statement // req 中字段的选择器
+	allocs   []jen.Code     // 字段所在的嵌入指针为 nil 时分配内存的语句
+	method   string         // FlagSet 上定义参数的方法
+	zero     *jen.Statement // 参数默认值
+	pointer  bool           // 字段是指针，赋值时取地址
+	convert  *jen.Statement // 字段是命名类型时需要的类型转换，否则为 nil
+	file     bool           // 字段是 multipart/form-data 请求上传的文件，参数值是文件路径
+	multiple bool           // 文件字段可以有多个文件，参数可以重复
+}
+
+func typeCode(typ types.Type) *jen.Statement {
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return jen.Id(typ.String())
+	}
+	return jen.Qual(named.Obj().Pkg().Path(), named.Obj().Name())
+}
+
+// embeddedPointer 是字段被提升时经过的嵌入指针。
+type embeddedPointer struct {
+	selector *jen.Statement // 嵌入指针的选择器
+	elem     types.Type     // 指向的结构体类型
+}
+
+// fieldSelector 返回 recv 中 encoding/json 提升的字段的选择器，以及字段经过的嵌入指针。
+// 未导出的嵌入字段在其他包中不能直接访问，使用提升的字段名；路径上有未导出的嵌入指针时返回 false。
+func fieldSelector(recv string, field common.JSONField) (*jen.Statement, []embeddedPointer, bool) {
+	selector := jen.Id(recv)
+	var pointers []embeddedPointer
+	for _, embedded := range field.Path {
+		ptr, isPointer := embedded.Type().(*types.Pointer)
+		if !embedded.Exported() {
+			if isPointer {
+				return nil, nil, false
+			}
+			continue
+		}
+		selector = selector.Clone().Dot(embedded.Name())
+		if isPointer {
+			pointers = append(pointers, embeddedPointer{selector: selector.Clone(), elem: ptr.Elem()})
+		}
+	}
+	return selector.Clone().Dot(field.Var.Name()), pointers, true
+}
+
+// newRequestFlag 尝试为字段生成命令行参数，不支持的类型返回 false，只能通过 --data 传入。
+func newRequestFlag(field common.JSONField) (requestFlag, bool) {
+	ret := requestFlag{name: strcase.ToKebab(field.Name), field: field.Var}
+	ret.variable = strcase.ToLowerCamel(ret.name) + "Flag"
+	if reservedFlags[ret.name] {
+		return ret, false
+	}
+	selector, pointers, ok := fieldSelector("req", field)
+	if !ok {
+		return ret, false
+	}
+	ret.selector = selector
+	for _, ptr := range pointers {
+		// if req.Base == nil { req.Base = new(Base) }
+		ret.allocs = append(ret.allocs, jen.If(ptr.selector.Clone().Op("==").Nil()).Block(
+			ptr.selector.Clone().Op("=").New(typeCode(ptr.elem)),
+		))
+	}
+	if isFile, multiple := common.FilePart(field); isFile {
+		ret.file, ret.multiple = true, multiple
+		ret.method, ret.zero = "String", jen.Lit("")
+		if multiple {
+			ret.method, ret.zero = "StringArray", jen.Nil()
+		}
+		if _, named := field.Var.Type().(*types.Named); named {
+			// 带有 jk:"file" 标签的命名 []byte 类型
+			ret.convert = typeCode(field.Var.Type())
+		}
+		return ret, true
+	}
+	typ := field.Var.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		ret.pointer = true
+		typ = ptr.Elem()
+	}
+
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		method, ok := flagKinds[t.Kind()]
+		if !ok {
+			return ret, false
+		}
+		ret.method = method
+		if _, named := typ.(*types.Named); named {
+			ret.convert = typeCode(typ)
+		}
+		switch {
+		case t.Info()&types.IsBoolean != 0:
+			ret.zero = jen.False()
+		case t.Info()&types.IsString != 0:
+			ret.zero = jen.Lit("")
+		default:
+			ret.zero = jen.Lit(0)
+		}
+		return ret, true
+	case *types.Slice:
+		elem, ok := t.Elem().(*types.Basic)
+		if ret.pointer || !ok {
+			return ret, false
+		}
+		method, ok := sliceFlagKinds[elem.Kind()]
+		if !ok {
+			return ret, false
+		}
+		ret.method = method
+		ret.zero = jen.Nil()
+		if _, named := typ.(*types.Named); named {
+			ret.convert = typeCode(typ)
+		}
+		return ret, true
+	case *types.Map:
+		key, ok := t.Key().(*types.Basic)
+		if ret.pointer || !ok || key.Kind() != types.String {
+			return ret, false
+		}
+		elem, ok := t.Elem().(*types.Basic)
+		if !ok {
+			return ret, false
+		}
+		method, ok := mapFlagKinds[elem.Kind()]
+		if !ok {
+			return ret, false
+		}
+		ret.method = method
+		ret.zero = jen.Nil()
+		if _, named := typ.(*types.Named); named {
+			ret.convert = typeCode(typ)
+		}
+		return ret, true
+	default:
+		return ret, false
+	}
+}
+
+// requestFlags 返回请求字段的命令行参数，kebab-case 后同名的字段（如 userID 和 user_id）无法区分，只能通过 --data 传入。
+func requestFlags(reqType *types.Named) []requestFlag {
+	var flags []requestFlag
+	count := make(map[string]int)
+	for _, field := range common.JSONFields(reqType.Underlying().(*types.Struct)) {
+		if flag, ok := newRequestFlag(field); ok {
+			flags = append(flags, flag)
+			count[flag.name]++
+		}
+	}
+
+	ret := flags[:0]
+	for _, flag := range flags {
+		if count[flag.name] == 1 {
+			ret = append(ret, flag)
+		}
+	}
+	return ret
+}
+
+// hasFileFlags 判断是否有方法的请求包含 *jkhttp.File 或 []*jkhttp.File 字段，生成的代码只在这时包含 openFile。
+func hasFileFlags(service *domain.Service) bool {
+	for _, method := range service.Methods {
+		reqType := method.RequestType().(*types.Pointer).Elem().(*types.Named)
+		for _, flag := range requestFlags(reqType) {
+			if flag.file && !isBytes(flag.field.Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isBytes(typ types.Type) bool {
+	slice, ok := typ.Underlying().(*types.Slice)
+	if !ok {
+		return false
+	}
+	elem, ok := slice.Elem().(*types.Basic)
+	return ok && elem.Kind() == types.Uint8
+}
+
+func shortDoc(doc, name string) string {
+	doc = strings.TrimSpace(doc)
+	if i := strings.IndexByte(doc, '\n'); i >= 0 {
+		doc = doc[:i]
+	}
+	return strings.TrimSpace(strings.TrimPrefix(doc, name))
+}
+
+// generateRuntime 生成所有子命令共用的客户端创建、请求读取和响应输出函数。
+func generateRuntime(f *jen.File, service *domain.Service, name string) {
+	env := strings.ToUpper(strcase.ToSnake(name))
+
+	// var (
+	//   baseURL string
+	//   headers []string
+	//   output  string
+	// )
+	f.Var().Defs(
+		jen.Id("baseURL").String(),
+		jen.Id("headers").Index().String(),
+		jen.Id("output").String(),
+	).Line()
+
+	// func newClient() (order.Service, error) {
+	f.Comment("newClient 使用全局参数创建服务客户端，每个 Key: Value 形式的请求头都会附加到请求上。")
+	f.Func().Id("newClient").Params().
+		Params(jen.Qual(service.Interface.Obj().Pkg().Path(), service.Name()), jen.Error()).
+		Block(
+			jen.If(jen.Id("baseURL").Op("==").Lit("")).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit(fmt.Sprintf("base url is required, use --base-url or %s_BASE_URL", env)))),
+			),
+			jen.Line(),
+			jen.Var().Id("options").Index().Qual(khttpPkg, "ClientOption"),
+			jen.For(jen.List(jen.Id("_"), jen.Id("header")).Op(":=").Range().Id("headers")).Block(
+				jen.List(jen.Id("key"), jen.Id("value"), jen.Id("ok")).Op(":=").Qual(stringPkg, "Cut").Call(jen.Id("header"), jen.Lit(":")),
+				jen.If(jen.Op("!").Id("ok")).Block(
+					jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("invalid header %q, want Key: Value"), jen.Id("header"))),
+				),
+				jen.Id("options").Op("=").Append(jen.Id("options"), jen.Qual(khttpPkg, "ClientBefore").Call(
+					jen.Qual(khttpPkg, "SetRequestHeader").Call(
+						jen.Qual(stringPkg, "TrimSpace").Call(jen.Id("key")),
+						jen.Qual(stringPkg, "TrimSpace").Call(jen.Id("value")),
+					),
+				)),
+			),
+			jen.Return(jen.Qual(service.Interface.Obj().Pkg().Path(), "NewHTTPClient").Call(jen.Id("baseURL"), jen.Id("options").Op("..."))),
+		).Line()
+
+	// func readRequest(cmd *cobra.Command, data string, req any) error {
+	f.Comment("readRequest 从 --data 指定的 JSON 文件读取请求，- 表示从标准输入读取。")
+	f.Func().Id("readRequest").
+		Params(jen.Id("cmd").Op("*").Qual(cobraPkg, "Command"), jen.Id("data").String(), jen.Id("req").Any()).
+		Error().
+		Block(
+			jen.If(jen.Id("data").Op("==").Lit("")).Block(jen.Return(jen.Nil())),
+			jen.Line(),
+			jen.Var().Id("r").Qual(ioPkg, "Reader").Op("=").Id("cmd").Dot("InOrStdin").Call(),
+			jen.If(jen.Id("data").Op("!=").Lit("-")).Block(
+				jen.List(jen.Id("file"), jen.Err()).Op(":=").Qual("os", "Open").Call(jen.Id("data")),
+				jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err())),
+				jen.Defer().Id("file").Dot("Close").Call(),
+				jen.Id("r").Op("=").Id("file"),
+			),
+			jen.Line(),
+			jen.If(jen.Err().Op(":=").Qual(jsonPkg, "NewDecoder").Call(jen.Id("r")).Dot("Decode").Call(jen.Id("req")), jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Qual("fmt", "Errorf").Call(jen.Lit("decode request data: %w"), jen.Err())),
+			),
+			jen.Return(jen.Nil()),
+		).Line()
+
+	if hasFileFlags(service) {
+		// func openFile(path string) (*jkhttp.File, error) {
+		f.Comment("openFile 打开要上传的文件，调用方负责关闭。")
+		f.Func().Id("openFile").Params(jen.Id("path").String()).
+			Params(jen.Op("*").Qual(utils.FilePackage, "File"), jen.Error()).
+			Block(
+				jen.List(jen.Id("file"), jen.Err()).Op(":=").Qual("os", "Open").Call(jen.Id("path")),
+				jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err())),
+				jen.Return(jen.Op("&").Qual(utils.FilePackage, "File").Values(jen.Dict{
+					jen.Id("Name"):        jen.Qual("path/filepath", "Base").Call(jen.Id("path")),
+					jen.Id("ContentType"): jen.Qual("mime", "TypeByExtension").Call(jen.Qual("path/filepath", "Ext").Call(jen.Id("path"))),
+					jen.Id("Reader"):      jen.Id("file"),
+				}), jen.Nil()),
+			).Line()
+	}
+
+	// type tableRow struct {
+	f.Type().Id("tableRow").Struct(
+		jen.Id("field").String(),
+		jen.Id("value").Any(),
+	).Line()
+
+	// func printResponse(cmd *cobra.Command, resp any, rows []tableRow) error {
+	f.Comment("printResponse 按 --output 输出响应，table 格式每行一个字段，复合类型的字段值输出为 JSON。")
+	f.Func().Id("printResponse").
+		Params(jen.Id("cmd").Op("*").Qual(cobraPkg, "Command"), jen.Id("resp").Any(), jen.Id("rows").Index().Id("tableRow")).
+		Error().
+		Block(
+			jen.Switch(jen.Id("output")).Block(
+				jen.Case(jen.Lit("json")).Block(
+					jen.Id("encoder").Op(":=").Qual(jsonPkg, "NewEncoder").Call(jen.Id("cmd").Dot("OutOrStdout").Call()),
+					jen.Id("encoder").Dot("SetIndent").Call(jen.Lit(""), jen.Lit("  ")),
+					jen.Return(jen.Id("encoder").Dot("Encode").Call(jen.Id("resp"))),
+				),
+				jen.Case(jen.Lit("table")).Block(
+					jen.Id("w").Op(":=").Qual("text/tabwriter", "NewWriter").Call(jen.Id("cmd").Dot("OutOrStdout").Call(), jen.Lit(0), jen.Lit(4), jen.Lit(2), jen.LitRune(' '), jen.Lit(0)),
+					jen.Qual("fmt", "Fprintln").Call(jen.Id("w"), jen.Lit("FIELD\tVALUE")),
+					jen.For(jen.List(jen.Id("_"), jen.Id("row")).Op(":=").Range().Id("rows")).Block(
+						jen.List(jen.Id("value"), jen.Err()).Op(":=").Id("formatValue").Call(jen.Id("row").Dot("value")),
+						jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err())),
+						jen.Qual("fmt", "Fprintf").Call(jen.Id("w"), jen.Lit("%s\t%s\n"), jen.Id("row").Dot("field"), jen.Id("value")),
+					),
+					jen.Return(jen.Id("w").Dot("Flush").Call()),
+				),
+				jen.Default().Block(
+					jen.Return(jen.Qual("fmt", "Errorf").Call(jen.Lit("unknown output format %q, want json or table"), jen.Id("output"))),
+				),
+			),
+		).Line()
+
+	// func formatValue(v any) (string, error) {
+	f.Func().Id("formatValue").Params(jen.Id("v").Any()).Params(jen.String(), jen.Error()).Block(
+		jen.Id("rv").Op(":=").Qual("reflect", "ValueOf").Call(jen.Id("v")),
+		jen.For(jen.Id("rv").Dot("Kind").Call().Op("==").Qual("reflect", "Pointer")).Block(
+			jen.If(jen.Id("rv").Dot("IsNil").Call()).Block(jen.Return(jen.Lit(""), jen.Nil())),
+			jen.Id("rv").Op("=").Id("rv").Dot("Elem").Call(),
+		),
+		jen.Switch(jen.Id("rv").Dot("Kind").Call()).Block(
+			jen.Case(jen.Qual("reflect", "Struct"), jen.Qual("reflect", "Map"), jen.Qual("reflect", "Slice"), jen.Qual("reflect", "Array"), jen.Qual("reflect", "Interface")).Block(
+				jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual(jsonPkg, "Marshal").Call(jen.Id("rv").Dot("Interface").Call()),
+				jen.Return(jen.String().Call(jen.Id("data")), jen.Err()),
+			),
+			jen.Default().Block(
+				jen.Return(jen.Qual("fmt", "Sprint").Call(jen.Id("rv").Dot("Interface").Call()), jen.Nil()),
+			),
+		),
+	).Line()
+
+	// func newRootCommand() *cobra.Command {
+	f.Func().Id("newRootCommand").Params().Op("*").Qual(cobraPkg, "Command").BlockFunc(func(g *jen.Group) {
+		short := shortDoc(service.Doc(), service.Name())
+		if short == "" {
+			short = fmt.Sprintf("command line client of %s", service.Name())
+		}
+		g.Id("cmd").Op(":=").Op("&").Qual(cobraPkg, "Command").Values(jen.Dict{
+			jen.Id("Use"):          jen.Lit(name),
+			jen.Id("Short"):        jen.Lit(short),
+			jen.Id("SilenceUsage"): jen.True(),
+		})
+		g.Id("cmd").Dot("PersistentFlags").Call().Dot("StringVar").Call(
+			jen.Op("&").Id("baseURL"), jen.Lit("base-url"),
+			jen.Qual("os", "Getenv").Call(jen.Lit(env+"_BASE_URL")),
+			jen.Lit(fmt.Sprintf("service base url, defaults to $%s_BASE_URL", env)),
+		)
+		g.Id("cmd").Dot("PersistentFlags").Call().Dot("StringArrayVarP").Call(
+			jen.Op("&").Id("headers"), jen.Lit("header"), jen.Lit("H"),
+			jen.Id("envHeaders").Call(),
+			jen.Lit(fmt.Sprintf("request header in Key: Value form, defaults to $%s_HEADERS separated by newlines", env)),
+		)
+		g.Id("cmd").Dot("PersistentFlags").Call().Dot("StringVarP").Call(
+			jen.Op("&").Id("output"), jen.Lit("output"), jen.Lit("o"),
+			jen.Lit("json"),
+			jen.Lit("output format, json or table"),
+		)
+		for _, method := range service.Methods {
+			g.Id("cmd").Dot("AddCommand").Call(jen.Id(commandFuncName(method)).Call())
+		}
+		g.Return(jen.Id("cmd"))
+	}).Line()
+
+	// func envHeaders() []string {
+	f.Func().Id("envHeaders").Params().Index().String().Block(
+		jen.Id("value").Op(":=").Qual(stringPkg, "TrimSpace").Call(jen.Qual("os", "Getenv").Call(jen.Lit(env+"_HEADERS"))),
+		jen.If(jen.Id("value").Op("==").Lit("")).Block(jen.Return(jen.Nil())),
+		jen.Return(jen.Qual(stringPkg, "Split").Call(jen.Id("value"), jen.Lit("\n"))),
+	).Line()
+
+	// func main() {
+	f.Func().Id("main").Params().Block(
+		jen.If(jen.Err().Op(":=").Id("newRootCommand").Call().Dot("Execute").Call(), jen.Err().Op("!=").Nil()).Block(
+			jen.Qual("os", "Exit").Call(jen.Lit(1)),
+		),
+	).Line()
+}
+
+func commandFuncName(method *domain.Method) string {
+	return "new" + method.Func.Name() + "Command"
+}
+
+// generateMethodCommand 为方法生成子命令，请求先从 --data 读取，再用显式设置的参数覆盖。
+func generateMethodCommand(f *jen.File, service *domain.Service, method *domain.Method) {
+	reqType := method.RequestType().(*types.Pointer).Elem().(*types.Named)
+	respType := method.ResponseNamed()
+
+	flags := requestFlags(reqType)
+
+	short := shortDoc(method.Doc(), method.Func.Name())
+	f.Func().Id(commandFuncName(method)).Params().Op("*").Qual(cobraPkg, "Command").BlockFunc(func(g *jen.Group) {
+		g.Id("cmd").Op(":=").Op("&").Qual(cobraPkg, "Command").Values(jen.Dict{
+			jen.Id("Use"):   jen.Lit(strcase.ToKebab(method.Func.Name())),
+			jen.Id("Short"): jen.Lit(short),
+			jen.Id("Args"):  jen.Qual(cobraPkg, "NoArgs"),
+		})
+		g.Id("data").Op(":=").Id("cmd").Dot("Flags").Call().Dot("String").Call(
+			jen.Lit("data"), jen.Lit(""), jen.Lit("JSON file of request, - to read from stdin"),
+		)
+		for _, flag := range flags {
+			usage := shortDoc(service.ObjectDoc(flag.field), flag.field.Name())
+			if usage == "" {
+				usage = flag.field.Name()
+			}
+			if flag.file {
+				usage += ", path of file to upload"
+			}
+			g.Id(flag.variable).Op(":=").Id("cmd").Dot("Flags").Call().Dot(flag.method).Call(
+				jen.Lit(flag.name), flag.zero, jen.Lit(usage),
+			)
+		}
+		g.Line()
+
+		g.Id("cmd").Dot("RunE").Op("=").Func().
+			Params(jen.Id("cmd").Op("*").Qual(cobraPkg, "Command"), jen.Id("args").Index().String()).
+			Error().
+			BlockFunc(func(g *jen.Group) {
+				g.Id("req").Op(":=").Op("&").Add(method.RequestTypeCodeJen()).Values()
+				g.If(jen.Err().Op(":=").Id("readRequest").Call(jen.Id("cmd"), jen.Op("*").Id("data"), jen.Id("req")), jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Err()),
+				)
+				for _, flag := range flags {
+					value := jen.Op("*").Id(flag.variable)
+					if flag.convert != nil {
+						value = flag.convert.Clone().Call(value)
+					}
+					g.If(jen.Id("cmd").Dot("Flags").Call().Dot("Changed").Call(jen.Lit(flag.name))).BlockFunc(func(g *jen.Group) {
+						g.Add(flag.allocs...)
+						if flag.file {
+							generateFileFlag(g, flag)
+							return
+						}
+						if flag.pointer {
+							g.Id("value").Op(":=").Add(value)
+							g.Add(flag.selector.Clone()).Op("=").Op("&").Id("value")
+						} else {
+							g.Add(flag.selector.Clone()).Op("=").Add(value)
+						}
+					})
+				}
+				g.Line()
+				g.List(jen.Id("client"), jen.Err()).Op(":=").Id("newClient").Call()
+				g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err()))
+				g.List(jen.Id("resp"), jen.Err()).Op(":=").Id("client").Dot(method.Func.Name()).Call(
+					jen.Id("cmd").Dot("Context").Call(), jen.Id("req"),
+				)
+				g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err()))
+				if common.IsStream(method) {
+					// 流式响应原样写入标准输出
+					g.Defer().Id("resp").Dot("Body").Dot("Close").Call()
+					g.List(jen.Id("_"), jen.Err()).Op("=").Qual("io", "Copy").Call(jen.Id("cmd").Dot("OutOrStdout").Call(), jen.Id("resp").Dot("Body"))
+					g.Return(jen.Err())
+					return
+				}
+				if method.IsEventStream() {
+					// SSE 的每个事件输出为一行 JSON，直到服务端关闭连接
+					g.Id("enc").Op(":=").Qual("encoding/json", "NewEncoder").Call(jen.Id("cmd").Dot("OutOrStdout").Call())
+					g.For(jen.Id("event").Op(":=").Range().Id("resp")).Block(
+						jen.If(jen.Err().Op(":=").Id("enc").Dot("Encode").Call(jen.Id("event")), jen.Err().Op("!=").Nil()).Block(
+							jen.Return(jen.Err()),
+						),
+					)
+					g.Return(jen.Nil())
+					return
+				}
+				generatePrintResponse(g, respType)
+			})
+		g.Return(jen.Id("cmd"))
+	}).Line()
+}
+
+// generatePrintResponse 生成按 --output 输出响应 resp 的代码，table 格式按字段顺序每行一个字段。
+func generatePrintResponse(g *jen.Group, respType *types.Named) {
+	type row struct {
+		name     string
+		selector *jen.Statement
+		pointers []embeddedPointer
+	}
+	var (
+		rows     []row
+		embedded bool
+	)
+	for _, field := range common.JSONFields(respType.Underlying().(*types.Struct)) {
+		selector, pointers, ok := fieldSelector("resp", field)
+		if !ok {
+			continue
+		}
+		rows = append(rows, row{name: field.Name, selector: selector, pointers: pointers})
+		embedded = embedded || len(pointers) > 0
+	}
+
+	if !embedded {
+		// return printResponse(cmd, resp, []tableRow{{"id", resp.ID}, ...})
+		g.Return(jen.Id("printResponse").Call(jen.Id("cmd"), jen.Id("resp"), jen.Index().Id("tableRow").ValuesFunc(func(g *jen.Group) {
+			for _, row := range rows {
+				g.Values(jen.Lit(row.name), row.selector)
+			}
+		})))
+		return
+	}
+
+	// 嵌入指针为 nil 时跳过其中的字段
+	// rows := make([]tableRow, 0, n)
+	// if resp.Base != nil {
+	//   rows = append(rows, tableRow{"tenant", resp.Base.Tenant})
+	// }
+	g.Id("rows").Op(":=").Make(jen.Index().Id("tableRow"), jen.Lit(0), jen.Lit(len(rows)))
+	for _, row := range rows {
+		appendRow := jen.Id("rows").Op("=").Append(jen.Id("rows"), jen.Id("tableRow").Values(jen.Lit(row.name), row.selector))
+		if len(row.pointers) == 0 {
+			g.Add(appendRow)
+			continue
+		}
+		condition := row.pointers[0].selector.Clone().Op("!=").Nil()
+		for _, ptr := range row.pointers[1:] {
+			condition = condition.Op("&&").Add(ptr.selector.Clone()).Op("!=").Nil()
+		}
+		g.If(condition).Block(appendRow)
+	}
+	g.Return(jen.Id("printResponse").Call(jen.Id("cmd"), jen.Id("resp"), jen.Id("rows")))
+}
+
+// generateFileFlag 生成读取文件参数的代码，上传的文件在命令结束时关闭。
+func generateFileFlag(g *jen.Group, flag requestFlag) {
+	field := flag.selector.Clone()
+	switch {
+	case flag.multiple:
+		// req.Files = nil
+		// for _, path := range *filesFlag {
+		g.Add(field.Clone()).Op("=").Nil()
+		g.For(jen.List(jen.Id("_"), jen.Id("path")).Op(":=").Range().Op("*").Id(flag.variable)).Block(
+			jen.List(jen.Id("file"), jen.Err()).Op(":=").Id("openFile").Call(jen.Id("path")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err())),
+			jen.Defer().Id("file").Dot("Close").Call(),
+			field.Clone().Op("=").Append(field.Clone(), jen.Id("file")),
+		)
+	case isBytes(flag.field.Type()):
+		// 带有 jk:"file" 标签的 []byte 字段读取整个文件
+		value := jen.Id("content")
+		if flag.convert != nil {
+			value = flag.convert.Clone().Call(value)
+		}
+		g.List(jen.Id("content"), jen.Err()).Op(":=").Qual("os", "ReadFile").Call(jen.Op("*").Id(flag.variable))
+		g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err()))
+		g.Add(field.Clone()).Op("=").Add(value)
+	default:
+		g.List(jen.Id("file"), jen.Err()).Op(":=").Id("openFile").Call(jen.Op("*").Id(flag.variable))
+		g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err()))
+		g.Defer().Id("file").Dot("Close").Call()
+		g.Add(field.Clone()).Op("=").Id("file")
+	}
+}
+
+// GenerateCLI 生成名为 name 的命令行客户端 main 包。
+// 每个方法对应一个子命令，调用通过生成的 Go HTTP 客户端 NewHTTPClient 完成。
+func GenerateCLI(f *jen.File, service *domain.Service, name string) error {
+	for _, method := range service.Methods {
+		signature := method.Func.Type().(*types.Signature)
+		if err := utils.CheckParams(signature.Params()); err != nil {
+			return errors.Wrapf(err, "check method signature: %s", method.Func.FullName())
+		}
+		if err := utils.CheckResults(signature.Results()); err != nil {
+			return errors.Wrapf(err, "check method signature: %s", method.Func.FullName())
+		}
+	}
+
+	generateRuntime(f, service, name)
+	for _, method := range service.Methods {
+		generateMethodCommand(f, service, method)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/nnnewb/jk/internal/domain/domaintest"
+)
+
+func TestGenerateCLI(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		src     string
+		want    []string
+		notWant []string
+	}{
+		// 嵌入结构体的字段生成参数，嵌入指针在赋值前分配；time.Time 和 kebab-case 后同名的字段只能通过 --data 传入
+		{"Embedded", domaintest.EmbeddedSource, []string{
+			`tenantFlag := cmd.Flags().String("tenant", "", "Tenant")`,
+			"req.Base.Tenant = *tenantFlag",
+			"if req.Meta == nil {",
+			"req.Meta = new(order.Meta)",
+			"req.Meta.Source = *sourceFlag",
+			`{"created_at", resp.Base.CreatedAt}`,
+		}, []string{`"created-at"`, `"user-id"`, `"deadline"`}},
+		// 同名类型按包区分
+		{"Collision", domaintest.CollisionSource, []string{
+			"req := &multipart.FileHeader{}",
+			"req.Filename = *filenameFlag",
+			`{"name", resp.Name}`,
+		}, nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := jen.NewFile("main")
+			err := GenerateCLI(f, domaintest.Service(t, c.src, "Service"), "order")
+			if err != nil {
+				t.Fatalf("generate failed: %+v", err)
+			}
+			var buf bytes.Buffer
+			if err = f.Render(&buf); err != nil {
+				t.Fatalf("render failed: %+v", err)
+			}
+			domaintest.Contains(t, buf.String(), c.want...)
+			for _, s := range c.notWant {
+				if strings.Contains(buf.String(), s) {
+					t.Errorf("generated code contains %q", s)
+				}
+			}
+		})
+	}
+}
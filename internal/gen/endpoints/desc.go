@@ -0,0 +1,154 @@
+package endpoints
+
+import (
+	"sort"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/iancoleman/strcase"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/http/common"
+)
+
+// methodDescName 返回方法描述变量名。
+func methodDescName(methodName string) string {
+	return strcase.ToLowerCamel(methodName) + "MethodDesc"
+}
+
+// withMethodDesc 返回 withMethodDesc(xxxMethodDesc)(inner)，方法描述在最外层注入，所有中间件都能从上下文中读取。
+func withMethodDesc(methodName string, inner jen.Code) *jen.Statement {
+	return jen.Id("withMethodDesc").Call(jen.Id(methodDescName(methodName))).Call(inner)
+}
+
+func generateMethodDesc(f *jen.File, service *domain.Service) {
+	common.HTTPPopulateDefaultAnnotations(service)
+
+	// type MethodDesc struct {
+	f.Comment("MethodDesc 描述 EndpointSet 中的一个端点，中间件可以据此选择性地生效。")
+	f.Type().Id("MethodDesc").Struct(
+		jen.Id("Service").String(),
+		jen.Id("Method").String(),
+		jen.Id("HTTPMethod").String(),
+		jen.Id("HTTPPath").String(),
+		jen.Id("Annotations").Map(jen.String()).String().Comment("方法注释中的全部注解，没有参数的注解值为空字符串"),
+	).Line()
+
+	// var (
+	//   xxxMethodDesc = MethodDesc{...}
+	// )
+	f.Var().DefsFunc(func(g *jen.Group) {
+		for _, method := range service.Methods {
+			if !method.Func.Exported() {
+				continue
+			}
+
+			keys := make([]string, 0, len(method.RawAnnotations))
+			for key := range method.RawAnnotations {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			g.Id(methodDescName(method.Func.Name())).Op("=").Id("MethodDesc").Values(jen.Dict{
+				jen.Id("Service"):    jen.Lit(service.Name()),
+				jen.Id("Method"):     jen.Lit(method.Func.Name()),
+				jen.Id("HTTPMethod"): jen.Lit(method.Annotations.HTTPMethod),
+				jen.Id("HTTPPath"):   jen.Lit(method.Annotations.HTTPPath),
+				jen.Id("Annotations"): jen.Map(jen.String()).String().ValuesFunc(func(g *jen.Group) {
+					for _, key := range keys {
+						g.Line().Lit(key).Op(":").Lit(method.RawAnnotations[key])
+					}
+					if len(keys) > 0 {
+						g.Line()
+					}
+				}),
+			})
+		}
+	}).Line()
+
+	// func MethodDescs() []MethodDesc {
+	f.Comment("MethodDescs 返回服务全部端点的描述，返回值中的 Annotations 不应修改。")
+	f.Func().
+		Id("MethodDescs").
+		Params().
+		Index().Id("MethodDesc").
+		Block(
+			jen.Return(jen.Index().Id("MethodDesc").ValuesFunc(func(g *jen.Group) {
+				for _, method := range service.Methods {
+					if !method.Func.Exported() {
+						continue
+					}
+					g.Line().Id(methodDescName(method.Func.Name()))
+				}
+				g.Line()
+			})),
+		).Line()
+
+	// type methodDescContextKey struct{}
+	f.Type().Id("methodDescContextKey").Struct().Line()
+
+	// func MethodDescFromContext(ctx context.Context) (MethodDesc, bool) {
+	f.Comment("MethodDescFromContext 返回当前调用的端点描述，只在 EndpointSet 的端点及其中间件中可用。")
+	f.Func().
+		Id("MethodDescFromContext").
+		Params(jen.Id("ctx").Qual("context", "Context")).
+		Params(jen.Id("MethodDesc"), jen.Bool()).
+		Block(
+			jen.List(jen.Id("desc"), jen.Id("ok")).Op(":=").
+				Id("ctx").Dot("Value").Call(jen.Id("methodDescContextKey").Values()).Assert(jen.Id("MethodDesc")),
+			jen.Return(jen.Id("desc"), jen.Id("ok")),
+		).Line()
+
+	// func withMethodDesc(desc MethodDesc) endpoint.Middleware {
+	f.Func().
+		Id("withMethodDesc").
+		Params(jen.Id("desc").Id("MethodDesc")).
+		Qual("github.com/go-kit/kit/endpoint", "Middleware").
+		Block(
+			jen.Return(jen.Func().
+				Params(jen.Id("next").Qual("github.com/go-kit/kit/endpoint", "Endpoint")).
+				Qual("github.com/go-kit/kit/endpoint", "Endpoint").
+				Block(
+					jen.Return(jen.Func().
+						Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("request").Interface()).
+						Params(jen.Interface(), jen.Error()).
+						Block(
+							jen.Return(jen.Id("next").Call(
+								jen.Qual("context", "WithValue").Call(jen.Id("ctx"), jen.Id("methodDescContextKey").Values(), jen.Id("desc")),
+								jen.Id("request"))),
+						)),
+				)),
+		).Line()
+}
+
+// generateWithFunc 生成 EndpointSet.WithFunc，按端点描述选择中间件。
+func generateWithFunc(f *jen.File, service *domain.Service) {
+	// func (s EndpointSet) WithFunc(f func(desc MethodDesc) []endpoint.Middleware) EndpointSet {
+	f.Comment("WithFunc 对每个端点调用 f 选择要应用的中间件，f 返回空时端点保持不变。")
+	f.Func().
+		Params(jen.Id("s").Id("EndpointSet")).
+		Id("WithFunc").
+		Params(jen.Id("f").Func().Params(jen.Id("desc").Id("MethodDesc")).Index().Qual("github.com/go-kit/kit/endpoint", "Middleware")).
+		Id("EndpointSet").
+		BlockFunc(func(g *jen.Group) {
+			for _, method := range service.Methods {
+				if !method.Func.Exported() {
+					continue
+				}
+
+				// if middlewares := f(xxxMethodDesc); len(middlewares) > 0 {
+				//   s.XXXEndpoint = withMethodDesc(xxxMethodDesc)(endpoint.Chain(middlewares[0], middlewares[1:]...)(s.XXXEndpoint))
+				// }
+				g.If(
+					jen.Id("middlewares").Op(":=").Id("f").Call(jen.Id(methodDescName(method.Func.Name()))),
+					jen.Len(jen.Id("middlewares")).Op(">").Lit(0),
+				).Block(
+					jen.Id("s").Dot(method.Func.Name() + "Endpoint").Op("=").Add(withMethodDesc(
+						method.Func.Name(),
+						jen.Qual("github.com/go-kit/kit/endpoint", "Chain").
+							Call(jen.Id("middlewares").Index(jen.Lit(0)), jen.Id("middlewares").Index(jen.Lit(1), jen.Empty()).Op("...")).
+							Call(jen.Id("s").Dot(method.Func.Name()+"Endpoint")),
+					)),
+				)
+			}
+			g.Return(jen.Id("s"))
+		}).Line()
+}
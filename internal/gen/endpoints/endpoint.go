@@ -6,6 +6,7 @@ import (
 
 	"emperror.dev/errors"
 	"github.com/dave/jennifer/jen"
+	"github.com/nnnewb/jk/internal/domain"
 	"github.com/nnnewb/jk/internal/utils"
 )
 
@@ -52,9 +53,18 @@ func generateEndpointSet(file *jen.File, svc *types.Named) {
 		receiver := strings.ToLower(svc.Obj().Name()[:1])
 		endpointFunc := method.Name() + "Endpoint"
 		reqPtrType := params.At(1).Type().(*types.Pointer)
-		respPtrType := results.At(0).Type().(*types.Pointer)
 		reqType := reqPtrType.Elem().(*types.Named)
-		respType := respPtrType.Elem().(*types.Named)
+
+		// 响应类型 *RESP 出错时返回 &RESP{}，SSE 方法的 <-chan *EVENT 出错时返回 nil
+		var respCode, respZero *jen.Statement
+		if eventType, ok := utils.EventType(results.At(0).Type()); ok {
+			respCode = jen.Op("<-").Chan().Op("*").Qual(eventType.Obj().Pkg().Path(), eventType.Obj().Name())
+			respZero = jen.Nil()
+		} else {
+			respType := results.At(0).Type().(*types.Pointer).Elem().(*types.Named)
+			respCode = jen.Op("*").Qual(respType.Obj().Pkg().Path(), respType.Obj().Name())
+			respZero = jen.Op("&").Qual(respType.Obj().Pkg().Path(), respType.Obj().Name()).Values()
+		}
 
 		file.Func().
 			Params(jen.Id(receiver).Id(receiverTyp)).
@@ -63,7 +73,7 @@ func generateEndpointSet(file *jen.File, svc *types.Named) {
 				jen.Id("ctx").Qual("context", "Context"),
 				jen.Id("req").Op("*").Qual(reqType.Obj().Pkg().Path(), reqType.Obj().Name())).
 			Params(
-				jen.Op("*").Qual(respType.Obj().Pkg().Path(), respType.Obj().Name()),
+				respCode,
 				jen.Error()).
 			BlockFunc(func(g *jen.Group) {
 				g.List(jen.Id("resp"), jen.Err()).
@@ -73,11 +83,11 @@ func generateEndpointSet(file *jen.File, svc *types.Named) {
 				// if err != nil { return RESP{}, err }
 				g.If(jen.Err().Op("!=").Nil()).Block(
 					jen.Return(
-						jen.Op("&").Qual(respType.Obj().Pkg().Path(), respType.Obj().Name()).Values(),
+						respZero,
 						jen.Err()))
 				// return resp.(RESP), nil
 				g.Return(
-					jen.Id("resp").Assert(jen.Op("*").Qual(respType.Obj().Pkg().Path(), respType.Obj().Name())),
+					jen.Id("resp").Assert(respCode),
 					jen.Nil())
 			}).Line()
 	}
@@ -95,7 +105,7 @@ func generateEndpointSet(file *jen.File, svc *types.Named) {
 					}
 
 					endpointName := method.Name() + "Endpoint"
-					d[jen.Id(endpointName)] = jen.Id("makeEndpointFromFunc").Call(jen.Id("svc").Dot(method.Name()))
+					d[jen.Id(endpointName)] = withMethodDesc(method.Name(), jen.Id("makeEndpointFromFunc").Call(jen.Id("svc").Dot(method.Name())))
 				}
 			})))
 		}).Line()
@@ -118,10 +128,10 @@ func generateEndpointSet(file *jen.File, svc *types.Named) {
 						}
 
 						endpointName := method.Name() + "Endpoint"
-						d[jen.Id(endpointName)] = jen.Qual("github.com/go-kit/kit/endpoint", "Chain").Call(
+						d[jen.Id(endpointName)] = withMethodDesc(method.Name(), jen.Qual("github.com/go-kit/kit/endpoint", "Chain").Call(
 							jen.Id("outer"),
 							jen.Id("others").Op("..."),
-						).Call(jen.Id("s").Dot(endpointName))
+						).Call(jen.Id("s").Dot(endpointName)))
 					}
 				}))
 			})
@@ -129,10 +139,11 @@ func generateEndpointSet(file *jen.File, svc *types.Named) {
 }
 
 // GenerateEndpoints generates endpoint factory for a given service
-func GenerateEndpoints(f *jen.File, svc *types.Named) error {
+func GenerateEndpoints(f *jen.File, service *domain.Service) error {
 	var (
 		interfaceType *types.Interface
 		ok            bool
+		svc           = service.Interface
 	)
 
 	// Get the underlying interface of the named type
@@ -161,6 +172,8 @@ func GenerateEndpoints(f *jen.File, svc *types.Named) error {
 	}
 
 	generateEndpointFactory(f)
+	generateMethodDesc(f, service)
 	generateEndpointSet(f, svc)
-	return nil
+	generateWithFunc(f, service)
+	return generateWithResilience(f, service)
 }
@@ -0,0 +1,163 @@
+package endpoints
+
+import (
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/dave/jennifer/jen"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+// durationCode 返回时长 d 的代码，尽量使用 time.Second 这样的单位常量。
+func durationCode(d time.Duration) *jen.Statement {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "Hour"},
+		{time.Minute, "Minute"},
+		{time.Second, "Second"},
+		{time.Millisecond, "Millisecond"},
+		{time.Microsecond, "Microsecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			if d == u.unit {
+				return jen.Qual("time", u.name)
+			}
+			return jen.Lit(int(d/u.unit)).Op("*").Qual("time", u.name)
+		}
+	}
+	return jen.Qual("time", "Duration").Call(jen.Lit(int64(d)))
+}
+
+// resilienceMiddlewares 返回方法注解声明的端点中间件，从外到内依次是限流、熔断、超时。
+func resilienceMiddlewares(service *domain.Service, method *domain.Method) ([]jen.Code, error) {
+	var ret []jen.Code
+	annotations := method.Annotations
+
+	if annotations.RateLimit != "" {
+		n, per, err := utils.ParseRate(annotations.RateLimit)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse @rate-limit of method %s", method.Func.Name())
+		}
+
+		// ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Every(per/n), n))
+		ret = append(ret, jen.Qual("github.com/go-kit/kit/ratelimit", "NewErroringLimiter").Call(
+			jen.Qual("golang.org/x/time/rate", "NewLimiter").Call(
+				jen.Qual("golang.org/x/time/rate", "Every").Call(durationCode(per).Op("/").Lit(n)),
+				jen.Lit(n),
+			),
+		))
+	}
+
+	if annotations.CircuitBreaker {
+		// circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "Service.Method"}))
+		ret = append(ret, jen.Qual("github.com/go-kit/kit/circuitbreaker", "Gobreaker").Call(
+			jen.Qual("github.com/sony/gobreaker", "NewCircuitBreaker").Call(
+				jen.Qual("github.com/sony/gobreaker", "Settings").Values(jen.Dict{
+					jen.Id("Name"): jen.Lit(service.Name() + "." + method.Func.Name()),
+				}),
+			),
+		))
+	}
+
+	if annotations.Timeout != "" {
+		if method.IsEventStream() {
+			// 超时会在方法返回通道后取消上下文，事件推送随之结束
+			return nil, errors.Errorf("@timeout is not supported by server-sent events method %s", method.Func.Name())
+		}
+		if common.IsStream(method) {
+			// 超时会在方法返回流之后、写完响应之前取消上下文，下载随之中断
+			return nil, errors.Errorf("@timeout is not supported by stream method %s", method.Func.Name())
+		}
+
+		timeout, err := time.ParseDuration(annotations.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, errors.Errorf("invalid @timeout %q of method %s", annotations.Timeout, method.Func.Name())
+		}
+
+		// endpointTimeout(3 * time.Second)
+		ret = append(ret, jen.Id("endpointTimeout").Call(durationCode(timeout)))
+	}
+
+	return ret, nil
+}
+
+func generateEndpointTimeout(f *jen.File) {
+	// func endpointTimeout(timeout time.Duration) endpoint.Middleware {
+	f.Comment("endpointTimeout 为请求上下文设置超时，超时后下游调用应当尽快返回 context.DeadlineExceeded。")
+	f.Func().
+		Id("endpointTimeout").
+		Params(jen.Id("timeout").Qual("time", "Duration")).
+		Qual("github.com/go-kit/kit/endpoint", "Middleware").
+		Block(
+			jen.Return(jen.Func().
+				Params(jen.Id("next").Qual("github.com/go-kit/kit/endpoint", "Endpoint")).
+				Qual("github.com/go-kit/kit/endpoint", "Endpoint").
+				Block(
+					jen.Return(jen.Func().
+						Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("request").Interface()).
+						Params(jen.Interface(), jen.Error()).
+						Block(
+							jen.List(jen.Id("ctx"), jen.Id("cancel")).Op(":=").
+								Qual("context", "WithTimeout").Call(jen.Id("ctx"), jen.Id("timeout")),
+							jen.Defer().Id("cancel").Call(),
+							jen.Return(jen.Id("next").Call(jen.Id("ctx"), jen.Id("request"))),
+						)),
+				)),
+		).Line()
+}
+
+// generateWithResilience 生成 EndpointSet.WithResilience，只包装带有 @rate-limit、@circuit-breaker、@timeout 注解的端点。
+func generateWithResilience(f *jen.File, service *domain.Service) error {
+	wrapped := make(map[string][]jen.Code)
+	for _, method := range service.Methods {
+		if !method.Func.Exported() {
+			continue
+		}
+
+		middlewares, err := resilienceMiddlewares(service, method)
+		if err != nil {
+			return err
+		}
+		if len(middlewares) > 0 {
+			wrapped[method.Func.Name()] = middlewares
+		}
+	}
+
+	generateEndpointTimeout(f)
+
+	// func (s EndpointSet) WithResilience() EndpointSet {
+	f.Comment("WithResilience 按方法注解 @rate-limit、@circuit-breaker、@timeout 包装对应的端点，没有注解的端点保持不变。")
+	f.Comment("每次调用都会创建新的限流器和熔断器，应当只调用一次。")
+	f.Func().
+		Params(jen.Id("s").Id("EndpointSet")).
+		Id("WithResilience").
+		Params().
+		Id("EndpointSet").
+		BlockFunc(func(g *jen.Group) {
+			for _, method := range service.Methods {
+				middlewares, ok := wrapped[method.Func.Name()]
+				if !ok {
+					continue
+				}
+
+				// s.XXXEndpoint = withMethodDesc(xxxMethodDesc)(endpoint.Chain(middlewares...)(s.XXXEndpoint))
+				endpointName := method.Func.Name() + "Endpoint"
+				chain := make([]jen.Code, 0, len(middlewares))
+				for _, middleware := range middlewares {
+					chain = append(chain, jen.Line().Add(middleware))
+				}
+				g.Id("s").Dot(endpointName).Op("=").Add(withMethodDesc(
+					method.Func.Name(),
+					jen.Qual("github.com/go-kit/kit/endpoint", "Chain").Call(chain...).Call(jen.Id("s").Dot(endpointName)),
+				))
+			}
+			g.Return(jen.Id("s"))
+		}).Line()
+
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/dave/jennifer/jen"
 	"github.com/nnnewb/jk/internal/domain"
 	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/utils"
 )
 
 func generateHTTPQueryStringEncoder(f *jen.File) {
@@ -22,12 +23,14 @@ func generateHTTPQueryStringEncoder(f *jen.File) {
 		).
 		Params(jen.Error()).
 		BlockFunc(func(g *jen.Group) {
-			// var values url.Values
-			g.Var().Id("values").Qual("net/url", "Values")
-			// err := schema.NewEncoder().Encode(request, values)
-			g.Err().Op(":=").
-				Qual("github.com/gorilla/schema", "NewEncoder").Call().
-				Dot("Encode").Call(jen.Id("request"), jen.Id("values"))
+			// values := url.Values{}
+			g.Id("values").Op(":=").Qual("net/url", "Values").Values()
+			// encoder := schema.NewEncoder()
+			g.Id("encoder").Op(":=").Qual("github.com/gorilla/schema", "NewEncoder").Call()
+			// encoder.SetAliasTag("json")
+			g.Id("encoder").Dot("SetAliasTag").Call(jen.Lit("json"))
+			// err := encoder.Encode(request, values)
+			g.Err().Op(":=").Id("encoder").Dot("Encode").Call(jen.Id("request"), jen.Id("values"))
 			// if err != nil {
 			//     return err
 			// }
@@ -43,10 +46,197 @@ func generateHTTPQueryStringEncoder(f *jen.File) {
 		}).Line()
 }
 
+// generateHTTPCodecHelpers 生成按 jkhttp.WithCodec 选择 Codec 的请求编码函数和设置 Accept 请求头的函数。
+func generateHTTPCodecHelpers(f *jen.File) {
+	// func httpCodecRequestEncoder(ctx context.Context, r *http.Request, request any) error {
+	//   return jkhttp.EncodeRequest(ctx, r, request)
+	// }
+	f.Func().
+		Id("httpCodecRequestEncoder").
+		Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("r").Op("*").Qual("net/http", "Request"),
+			jen.Id("request").Any(),
+		).
+		Params(jen.Error()).
+		Block(
+			jen.Return(jen.Qual(utils.FilePackage, "EncodeRequest").Call(jen.Id("ctx"), jen.Id("r"), jen.Id("request"))),
+		).Line()
+
+	// func httpCodecAccept(ctx context.Context, r *http.Request) context.Context {
+	//   if codec, err := jkhttp.ContextCodec(ctx); err == nil {
+	//     r.Header.Set("Accept", codec.ContentType())
+	//   }
+	//   return ctx
+	// }
+	f.Func().
+		Id("httpCodecAccept").
+		Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("r").Op("*").Qual("net/http", "Request"),
+		).
+		Qual("context", "Context").
+		Block(
+			jen.If(
+				jen.List(jen.Id("codec"), jen.Err()).Op(":=").Qual(utils.FilePackage, "ContextCodec").Call(jen.Id("ctx")),
+				jen.Err().Op("==").Nil(),
+			).Block(
+				jen.Id("r").Dot("Header").Dot("Set").Call(jen.Lit("Accept"), jen.Id("codec").Dot("ContentType").Call()),
+			),
+			jen.Return(jen.Id("ctx")),
+		).Line()
+}
+
+func generateHTTPFormEncoder(f *jen.File) {
+	// func httpFormEncoder(ctx context.Context, r *http.Request, request any) error {
+	f.Func().
+		Id("httpFormEncoder").
+		Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("r").Op("*").Qual("net/http", "Request"),
+			jen.Id("request").Any(),
+		).
+		Params(jen.Error()).
+		BlockFunc(func(g *jen.Group) {
+			// values := url.Values{}
+			g.Id("values").Op(":=").Qual("net/url", "Values").Values()
+			// encoder := schema.NewEncoder()
+			g.Id("encoder").Op(":=").Qual("github.com/gorilla/schema", "NewEncoder").Call()
+			// encoder.SetAliasTag("json")
+			g.Id("encoder").Dot("SetAliasTag").Call(jen.Lit("json"))
+			// err := encoder.Encode(request, values)
+			g.Err().Op(":=").Id("encoder").Dot("Encode").Call(jen.Id("request"), jen.Id("values"))
+			// if err != nil {
+			//     return err
+			// }
+			g.If(jen.Err().Op("!=").Nil()).
+				Block(jen.Return(jen.Err()))
+
+			// body := values.Encode()
+			g.Id("body").Op(":=").Id("values").Dot("Encode").Call()
+			// r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			g.Id("r").Dot("Header").Dot("Set").Call(jen.Lit("Content-Type"), jen.Lit(common.ContentTypeForm))
+			// r.ContentLength = int64(len(body))
+			g.Id("r").Dot("ContentLength").Op("=").Int64().Call(jen.Len(jen.Id("body")))
+			// r.Body = io.NopCloser(strings.NewReader(body))
+			g.Id("r").Dot("Body").Op("=").Qual("io", "NopCloser").Call(jen.Qual("strings", "NewReader").Call(jen.Id("body")))
+			// r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(body)), nil }
+			g.Id("r").Dot("GetBody").Op("=").Func().Params().Params(jen.Qual("io", "ReadCloser"), jen.Error()).Block(
+				jen.Return(jen.Qual("io", "NopCloser").Call(jen.Qual("strings", "NewReader").Call(jen.Id("body"))), jen.Nil()),
+			)
+			// return nil
+			g.Return(jen.Nil())
+		}).Line()
+}
+
+func generateHTTPMultipartEncoder(f *jen.File) {
+	// func httpMultipartEncoder(ctx context.Context, r *http.Request, request any) error {
+	//   return jkhttp.EncodeMultipartRequest(r, request)
+	// }
+	f.Func().
+		Id("httpMultipartEncoder").
+		Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("r").Op("*").Qual("net/http", "Request"),
+			jen.Id("request").Any(),
+		).
+		Params(jen.Error()).
+		Block(
+			jen.Return(jen.Qual(utils.FilePackage, "EncodeMultipartRequest").Call(jen.Id("r"), jen.Id("request"))),
+		).Line()
+}
+
 func generateHTTPJSONResponseDecoder(f *jen.File) {
-	// func httpJSONResponseDecoder[T any](ctx context.Context, req *http.Response) (any, error) {
+	// err := json.NewDecoder(resp.Body).Decode(&response)
+	decode := jen.Qual("encoding/json", "NewDecoder").Call(jen.Id("resp").Dot("Body")).Dot("Decode").
+		Call(jen.Op("&").Id("response"))
+	generateHTTPResponseDecoder(f, "httpJSONResponseDecoder", decode)
+}
+
+// generateHTTPCodecResponseDecoder 生成按 Content-Type 响应头选择 Codec 的响应解码函数。
+func generateHTTPCodecResponseDecoder(f *jen.File) {
+	// err := jkhttp.DecodeResponse(resp, &response)
+	decode := jen.Qual(utils.FilePackage, "DecodeResponse").Call(jen.Id("resp"), jen.Op("&").Id("response"))
+	generateHTTPResponseDecoder(f, "httpCodecResponseDecoder", decode)
+}
+
+// generateHTTPStreamResponseDecoder 生成流式响应的解码函数，响应体不会被关闭，由调用方读取和关闭。
+func generateHTTPStreamResponseDecoder(f *jen.File) {
+	// func httpStreamResponseDecoder[T any](ctx context.Context, resp *http.Response) (any, error) {
 	f.Func().
-		Id("httpJSONResponseDecoder").
+		Id("httpStreamResponseDecoder").
+		Types(jen.Id("T").Any()).
+		Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("resp").Op("*").Qual("net/http", "Response")).
+		Params(
+			jen.Any(),
+			jen.Error()).
+		BlockFunc(func(g *jen.Group) {
+			// var response T
+			g.Var().Id("response").Id("T")
+			// if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			//   defer resp.Body.Close()
+			//   body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			//   return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Body: body}
+			// }
+			g.If(jen.Id("resp").Dot("StatusCode").Op("<").Lit(200).Op("||").Id("resp").Dot("StatusCode").Op(">").Lit(299)).Block(
+				jen.Defer().Id("resp").Dot("Body").Dot("Close").Call(),
+				jen.List(jen.Id("body"), jen.Id("_")).Op(":=").Qual("io", "ReadAll").Call(
+					jen.Qual("io", "LimitReader").Call(jen.Id("resp").Dot("Body"), jen.Lit(4096))),
+				jen.Return(jen.Nil(), jen.Op("&").Id("HTTPStatusError").Values(jen.Dict{
+					jen.Id("StatusCode"): jen.Id("resp").Dot("StatusCode"),
+					jen.Id("Body"):       jen.Id("body"),
+				})),
+			)
+			// err := jkhttp.DecodeStreamResponse(resp, &response)
+			g.Err().Op(":=").Qual(utils.FilePackage, "DecodeStreamResponse").Call(jen.Id("resp"), jen.Op("&").Id("response"))
+			// if err != nil { resp.Body.Close(); return nil, err }
+			g.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Id("resp").Dot("Body").Dot("Close").Call(),
+				jen.Return(jen.Nil(), jen.Err()),
+			)
+			// return &response, nil
+			g.Return(jen.Op("&").Id("response"), jen.Nil())
+		}).Line()
+}
+
+// generateHTTPEventStreamResponseDecoder 生成 SSE 响应的解码函数，返回的事件通道在响应结束或 ctx 结束时关闭。
+func generateHTTPEventStreamResponseDecoder(f *jen.File) {
+	// func httpEventStreamResponseDecoder[T any](ctx context.Context, resp *http.Response) (any, error) {
+	f.Func().
+		Id("httpEventStreamResponseDecoder").
+		Types(jen.Id("T").Any()).
+		Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("resp").Op("*").Qual("net/http", "Response")).
+		Params(
+			jen.Any(),
+			jen.Error()).
+		BlockFunc(func(g *jen.Group) {
+			// if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			//   defer resp.Body.Close()
+			//   body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			//   return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Body: body}
+			// }
+			g.If(jen.Id("resp").Dot("StatusCode").Op("<").Lit(200).Op("||").Id("resp").Dot("StatusCode").Op(">").Lit(299)).Block(
+				jen.Defer().Id("resp").Dot("Body").Dot("Close").Call(),
+				jen.List(jen.Id("body"), jen.Id("_")).Op(":=").Qual("io", "ReadAll").Call(
+					jen.Qual("io", "LimitReader").Call(jen.Id("resp").Dot("Body"), jen.Lit(4096))),
+				jen.Return(jen.Nil(), jen.Op("&").Id("HTTPStatusError").Values(jen.Dict{
+					jen.Id("StatusCode"): jen.Id("resp").Dot("StatusCode"),
+					jen.Id("Body"):       jen.Id("body"),
+				})),
+			)
+			// return jkhttp.ReadEvents[T](ctx, resp.Body), nil
+			g.Return(jen.Qual(utils.FilePackage, "ReadEvents").Types(jen.Id("T")).Call(jen.Id("ctx"), jen.Id("resp").Dot("Body")), jen.Nil())
+		}).Line()
+}
+
+func generateHTTPResponseDecoder(f *jen.File, name string, decode jen.Code) {
+	// func NAME[T any](ctx context.Context, req *http.Response) (any, error) {
+	f.Func().
+		Id(name).
 		Types(jen.Id("T").Any()).
 		Params(
 			jen.Id("ctx").Qual("context", "Context"),
@@ -59,9 +249,19 @@ func generateHTTPJSONResponseDecoder(f *jen.File) {
 			g.Var().Id("response").Id("T")
 			// defer resp.Body.Close()
 			g.Defer().Id("resp").Dot("Body").Dot("Close").Call()
-			// err := json.NewDecoder(resp.Body).Decode(&response)
-			g.Err().Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Id("resp").Dot("Body")).Dot("Decode").
-				Call(jen.Op("&").Id("response"))
+			// if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			//   body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			//   return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Body: body}
+			// }
+			g.If(jen.Id("resp").Dot("StatusCode").Op("<").Lit(200).Op("||").Id("resp").Dot("StatusCode").Op(">").Lit(299)).Block(
+				jen.List(jen.Id("body"), jen.Id("_")).Op(":=").Qual("io", "ReadAll").Call(
+					jen.Qual("io", "LimitReader").Call(jen.Id("resp").Dot("Body"), jen.Lit(4096))),
+				jen.Return(jen.Nil(), jen.Op("&").Id("HTTPStatusError").Values(jen.Dict{
+					jen.Id("StatusCode"): jen.Id("resp").Dot("StatusCode"),
+					jen.Id("Body"):       jen.Id("body"),
+				})),
+			)
+			g.Err().Op(":=").Add(decode)
 			// if err != nil { return nil, err }
 			g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err()))
 			// return response, nil
@@ -69,6 +269,137 @@ func generateHTTPJSONResponseDecoder(f *jen.File) {
 		}).Line()
 }
 
+// generateClientURLHelpers 生成拼接请求地址和解析服务实例地址的辅助函数。
+func generateClientURLHelpers(f *jen.File) {
+	// func httpClientURL(base *url.URL, p string) *url.URL {
+	f.Func().
+		Id("httpClientURL").
+		Params(
+			jen.Id("base").Op("*").Qual("net/url", "URL"),
+			jen.Id("p").String()).
+		Op("*").Qual("net/url", "URL").
+		BlockFunc(func(g *jen.Group) {
+			// u := *base
+			g.Id("u").Op(":=").Op("*").Id("base")
+			// u.Path = strings.TrimRight(base.Path, "/") + p
+			g.Id("u").Dot("Path").Op("=").
+				Qual("strings", "TrimRight").Call(jen.Id("base").Dot("Path"), jen.Lit("/")).
+				Op("+").Id("p")
+			// u.RawPath = ""
+			g.Id("u").Dot("RawPath").Op("=").Lit("")
+			// return &u
+			g.Return(jen.Op("&").Id("u"))
+		}).Line()
+
+	// func httpInstanceURL(instance string) (*url.URL, error) {
+	f.Comment("httpInstanceURL 解析服务发现得到的实例地址，没有 scheme 的实例（如 host:port）视为 http。")
+	f.Func().
+		Id("httpInstanceURL").
+		Params(jen.Id("instance").String()).
+		Params(jen.Op("*").Qual("net/url", "URL"), jen.Error()).
+		BlockFunc(func(g *jen.Group) {
+			g.If(jen.Op("!").Qual("strings", "Contains").Call(jen.Id("instance"), jen.Lit("://"))).Block(
+				jen.Id("instance").Op("=").Lit("http://").Op("+").Id("instance"),
+			)
+			g.Return(jen.Qual("net/url", "Parse").Call(jen.Id("instance")))
+		}).Line()
+}
+
+// generateMethodClient 生成方法对应的 go-kit HTTP 客户端构造函数 newXXXClient。
+func generateMethodClient(f *jen.File, service *domain.Service, methodData *domain.Method) {
+	method := methodData.Func
+
+	// check http-method annotation
+	httpRequestEncoder := jen.Qual("github.com/go-kit/kit/transport/http", "EncodeJSONRequest")
+	httpMethod := jen.Qual("net/http", "MethodPost")
+	switch methodData.Annotations.HTTPMethod {
+	case http.MethodGet:
+		httpMethod = jen.Qual("net/http", "MethodGet")
+		httpRequestEncoder = jen.Id("httpQueryStringEncoder")
+	case http.MethodDelete:
+		httpMethod = jen.Qual("net/http", "MethodDelete")
+		httpRequestEncoder = jen.Id("httpQueryStringEncoder")
+	case http.MethodPost:
+		httpMethod = jen.Qual("net/http", "MethodPost")
+	case http.MethodPatch:
+		httpMethod = jen.Qual("net/http", "MethodPatch")
+	case http.MethodPut:
+		httpMethod = jen.Qual("net/http", "MethodPut")
+	default:
+		log.Printf("unexpected http-method annotation %s for method %s, fallback to POST", strings.ToUpper(methodData.Annotations.HTTPMethod), method.Name())
+	}
+	codecs := common.HasCodecs(service)
+	switch {
+	case common.IsMultipart(methodData):
+		httpRequestEncoder = jen.Id("httpMultipartEncoder")
+	case common.IsForm(methodData):
+		httpRequestEncoder = jen.Id("httpFormEncoder")
+	case codecs && common.IsJSONBody(methodData):
+		httpRequestEncoder = jen.Id("httpCodecRequestEncoder")
+	}
+
+	httpResponseDecoder := jen.Id("httpJSONResponseDecoder")
+	var extraOptions []jen.Code
+	switch {
+	case methodData.IsEventStream():
+		httpResponseDecoder = jen.Id("httpEventStreamResponseDecoder")
+		// khttp.BufferedStream(true)
+		// 响应体在事件通道关闭时关闭
+		extraOptions = append(extraOptions, jen.Qual("github.com/go-kit/kit/transport/http", "BufferedStream").Call(jen.True()))
+	case common.IsStream(methodData):
+		httpResponseDecoder = jen.Id("httpStreamResponseDecoder")
+		// khttp.BufferedStream(true)
+		// 响应体由调用方读取和关闭
+		extraOptions = append(extraOptions, jen.Qual("github.com/go-kit/kit/transport/http", "BufferedStream").Call(jen.True()))
+	case codecs:
+		httpResponseDecoder = jen.Id("httpCodecResponseDecoder")
+		// khttp.ClientBefore(httpCodecAccept)
+		extraOptions = append(extraOptions, jen.Qual("github.com/go-kit/kit/transport/http", "ClientBefore").Call(jen.Id("httpCodecAccept")))
+	}
+	if methodData.Annotations.Idempotent {
+		// khttp.ClientBefore(jkhttp.SetIdempotencyKey)
+		// 幂等键由 httpClient 在每次调用时生成并放进 ctx，重试时复用
+		extraOptions = append(extraOptions, jen.Qual("github.com/go-kit/kit/transport/http", "ClientBefore").Call(jen.Qual(utils.FilePackage, "SetIdempotencyKey")))
+	}
+
+	if methodData.AuthScheme() != "" {
+		// khttp.ClientBefore(jkhttp.SetCredentials)
+		// 凭据由 httpClient 在每次调用时从 TokenSource 获取并放进 ctx
+		extraOptions = append(extraOptions, jen.Qual("github.com/go-kit/kit/transport/http", "ClientBefore").Call(jen.Qual(utils.FilePackage, "SetCredentials")))
+	}
+
+	// options... 或 append(options, extraOptions...)...
+	options := jen.Id("options").Op("...")
+	if len(extraOptions) > 0 {
+		options = jen.Append(append([]jen.Code{jen.Id("options")}, extraOptions...)...).Op("...")
+	}
+
+	// func newXXXClient(base *url.URL, options ...http.ClientOption) *http.Client {
+	//   return http.NewClient(
+	//     http.MethodPost,
+	//     httpClientURL(base, "/api/v1/SERVICE/ENDPOINT"),
+	//     httpJSONRequestEncoder,
+	//     httpJSONResponseDecoder[RESP],
+	//     options...,
+	//   )
+	// }
+	f.Func().
+		Id("new"+method.Name()+"Client").
+		Params(
+			jen.Id("base").Op("*").Qual("net/url", "URL"),
+			jen.Id("options").Op("...").Qual("github.com/go-kit/kit/transport/http", "ClientOption")).
+		Op("*").Qual("github.com/go-kit/kit/transport/http", "Client").
+		Block(
+			jen.Return(jen.Qual("github.com/go-kit/kit/transport/http", "NewClient").Call(
+				jen.Line().Add(httpMethod),
+				jen.Line().Id("httpClientURL").Call(jen.Id("base"), jen.Lit(methodData.Annotations.HTTPPath)),
+				jen.Line().Add(httpRequestEncoder),
+				jen.Line().Add(httpResponseDecoder).Types(methodData.ResponseTypeCodeJen()),
+				jen.Line().Add(options),
+			)),
+		).Line()
+}
+
 func generateClientSet(f *jen.File, service *domain.Service) {
 	common.HTTPPopulateDefaultAnnotations(service)
 	interfaceType := service.Interface.Underlying().(*types.Interface)
@@ -84,17 +415,25 @@ func generateClientSet(f *jen.File, service *domain.Service) {
 		}
 	}).Line()
 
-	// func NewHTTPClientSet(scheme, host string, port int, options ...http.ClientOptions) HTTPClientSet {
+	generateClientURLHelpers(f)
+	for _, methodData := range service.Methods {
+		if !methodData.Func.Exported() {
+			continue
+		}
+		generateMethodClient(f, service, methodData)
+	}
+
+	// func newHTTPClientSet(base *url.URL, options ...http.ClientOption) HTTPClientSet {
 	f.Func().
-		Id("NewHTTPClientSet").
+		Id("newHTTPClientSet").
 		Params(
-			jen.Id("scheme"),
-			jen.Id("host").String(),
-			jen.Id("port").Int(),
+			jen.Id("base").Op("*").Qual("net/url", "URL"),
 			jen.Id("options").Op("...").Qual("github.com/go-kit/kit/transport/http", "ClientOption")).
 		Id("HTTPClientSet").
 		BlockFunc(func(g *jen.Group) {
 			// return HTTPClientSet{
+			//   XXXClient: newXXXClient(base, options...),
+			// }
 			g.Return(jen.Id("HTTPClientSet")).Values(jen.DictFunc(func(d jen.Dict) {
 				for _, methodData := range service.Methods {
 					method := methodData.Func
@@ -102,61 +441,99 @@ func generateClientSet(f *jen.File, service *domain.Service) {
 						continue
 					}
 
-					signature := method.Type().(*types.Signature)
-					respPtrType := signature.Results().At(0).Type().(*types.Pointer)
-					respType := respPtrType.Elem().(*types.Named)
-
-					// check http-method annotation
-					httpRequestEncoder := jen.Qual("github.com/go-kit/kit/transport/http", "EncodeJSONRequest")
-					httpMethod := jen.Qual("net/http", "MethodPost")
-					switch methodData.Annotations.HTTPMethod {
-					case http.MethodGet:
-						httpMethod = jen.Qual("net/http", "MethodGet")
-						httpRequestEncoder = jen.Id("httpQueryStringEncoder")
-					case http.MethodDelete:
-						httpMethod = jen.Qual("net/http", "MethodDelete")
-						httpRequestEncoder = jen.Id("httpQueryStringEncoder")
-					case http.MethodPost:
-						httpMethod = jen.Qual("net/http", "MethodPost")
-					case http.MethodPatch:
-						httpMethod = jen.Qual("net/http", "MethodPatch")
-					case http.MethodPut:
-						httpMethod = jen.Qual("net/http", "MethodPut")
-					default:
-						log.Printf("unexpected http-method annotation %s for method %s, fallback to POST", strings.ToUpper(methodData.Annotations.HTTPMethod), method.Name())
-					}
-
-					// XXXClient: http.NewClient(
-					//   http.MethodPost,
-					//   url.URL{
-					//     Scheme: scheme,
-					//     Host: fmt.Sprintf("%s:%d", host, port),
-					//     Path: "/api/v1/SERVICE/ENDPOINT",
-					//   },
-					//   httpJSONRequestEncoder[REQ],
-					//   httpJSONResponseDecoder[RESP],
-					//   options...,
-					// )
-					d[jen.Id(method.Name()+"Client")] = jen.
-						Qual("github.com/go-kit/kit/transport/http", "NewClient").
-						Call(
-							jen.Line().Add(httpMethod),
-							jen.Line().Op("&").Qual("net/url", "URL").Values(jen.DictFunc(func(d jen.Dict) {
-								d[jen.Id("Scheme")] = jen.Id("scheme")
-								d[jen.Id("Host")] = jen.Qual("fmt", "Sprintf").Call(
-									jen.Lit("%s:%d"),
-									jen.Id("host"),
-									jen.Id("port"))
-								d[jen.Id("Path")] = jen.Lit(methodData.Annotations.HTTPPath)
-							})),
-							jen.Line().Add(httpRequestEncoder),
-							jen.Line().Id("httpJSONResponseDecoder").Types(jen.Qual(respType.Obj().Pkg().Path(), respType.Obj().Name())),
-							jen.Line().Id("options").Op("..."),
-						)
+					d[jen.Id(method.Name()+"Client")] = jen.Id("new"+method.Name()+"Client").
+						Call(jen.Id("base"), jen.Id("options").Op("..."))
 				}
 			}))
 		}).Line()
 
+	// func NewHTTPClientSet(scheme, host string, port int, options ...http.ClientOptions) HTTPClientSet {
+	f.Func().
+		Id("NewHTTPClientSet").
+		Params(
+			jen.Id("scheme"),
+			jen.Id("host").String(),
+			jen.Id("port").Int(),
+			jen.Id("options").Op("...").Qual("github.com/go-kit/kit/transport/http", "ClientOption")).
+		Id("HTTPClientSet").
+		BlockFunc(func(g *jen.Group) {
+			// return newHTTPClientSet(&url.URL{Scheme: scheme, Host: fmt.Sprintf("%s:%d", host, port)}, options...)
+			g.Return(jen.Id("newHTTPClientSet").Call(
+				jen.Op("&").Qual("net/url", "URL").Values(jen.Dict{
+					jen.Id("Scheme"): jen.Id("scheme"),
+					jen.Id("Host"): jen.Qual("fmt", "Sprintf").Call(
+						jen.Lit("%s:%d"),
+						jen.Id("host"),
+						jen.Id("port")),
+				}),
+				jen.Id("options").Op("..."),
+			))
+		}).Line()
+
+	// func NewHTTPClientSetWithBaseURL(baseURL string, options ...http.ClientOption) (HTTPClientSet, error) {
+	f.Comment("NewHTTPClientSetWithBaseURL 使用基础地址创建客户端，基础地址的路径会作为所有请求路径的前缀，")
+	f.Comment("例如 https://example.com/gateway 。")
+	f.Func().
+		Id("NewHTTPClientSetWithBaseURL").
+		Params(
+			jen.Id("baseURL").String(),
+			jen.Id("options").Op("...").Qual("github.com/go-kit/kit/transport/http", "ClientOption")).
+		Params(jen.Id("HTTPClientSet"), jen.Error()).
+		BlockFunc(func(g *jen.Group) {
+			g.List(jen.Id("base"), jen.Err()).Op(":=").Qual("net/url", "Parse").Call(jen.Id("baseURL"))
+			g.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Id("HTTPClientSet").Values(), jen.Err()),
+			)
+			g.Return(jen.Id("newHTTPClientSet").Call(jen.Id("base"), jen.Id("options").Op("...")), jen.Nil())
+		}).Line()
+
+	// func NewLoadBalancedEndpointSet(instancer sd.Instancer, logger log.Logger, retryMax int, retryTimeout time.Duration, options ...http.ClientOption) EndpointSet {
+	f.Comment("NewLoadBalancedEndpointSet 从服务发现的实例中轮询选择，失败时重试，最多重试 retryMax 次，总耗时不超过 retryTimeout。")
+	f.Comment("instancer 可以是 sd.FixedInstancer、dnssrv.NewInstancer 等，实例地址形如 host:port 或 https://host:port/prefix 。")
+	f.Func().
+		Id("NewLoadBalancedEndpointSet").
+		Params(
+			jen.Id("instancer").Qual("github.com/go-kit/kit/sd", "Instancer"),
+			jen.Id("logger").Qual("github.com/go-kit/log", "Logger"),
+			jen.Id("retryMax").Int(),
+			jen.Id("retryTimeout").Qual("time", "Duration"),
+			jen.Id("options").Op("...").Qual("github.com/go-kit/kit/transport/http", "ClientOption")).
+		Id("EndpointSet").
+		BlockFunc(func(g *jen.Group) {
+			g.Var().Id("endpoints").Id("EndpointSet")
+			for _, methodData := range service.Methods {
+				method := methodData.Func
+				if !method.Exported() {
+					continue
+				}
+
+				// factory := func(instance string) (endpoint.Endpoint, io.Closer, error) {...}
+				g.Block(
+					jen.Id("factory").Op(":=").Func().
+						Params(jen.Id("instance").String()).
+						Params(jen.Qual("github.com/go-kit/kit/endpoint", "Endpoint"), jen.Qual("io", "Closer"), jen.Error()).
+						Block(
+							jen.List(jen.Id("base"), jen.Err()).Op(":=").Id("httpInstanceURL").Call(jen.Id("instance")),
+							jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Nil(), jen.Err())),
+							jen.Return(
+								jen.Id("new"+method.Name()+"Client").Call(jen.Id("base"), jen.Id("options").Op("...")).Dot("Endpoint").Call(),
+								jen.Nil(),
+								jen.Nil()),
+						),
+					// endpointer := sd.NewEndpointer(instancer, factory, logger)
+					jen.Id("endpointer").Op(":=").Qual("github.com/go-kit/kit/sd", "NewEndpointer").
+						Call(jen.Id("instancer"), jen.Id("factory"), jen.Id("logger")),
+					// endpoints.XXXEndpoint = lb.Retry(retryMax, retryTimeout, lb.NewRoundRobin(endpointer))
+					jen.Id("endpoints").Dot(method.Name()+"Endpoint").Op("=").
+						Qual("github.com/go-kit/kit/sd/lb", "Retry").Call(
+						jen.Id("retryMax"),
+						jen.Id("retryTimeout"),
+						jen.Qual("github.com/go-kit/kit/sd/lb", "NewRoundRobin").Call(jen.Id("endpointer"))),
+				)
+			}
+			g.Return(jen.Id("endpoints"))
+		}).Line()
+
 	// func (s HTTPClientSet) EndpointSet() EndpointSet {
 	f.Func().
 		Params(jen.Id("s").Id("HTTPClientSet")).
@@ -182,8 +559,238 @@ func generateClientSet(f *jen.File, service *domain.Service) {
 		}).Line()
 }
 
+// generateHTTPClientErrors 生成客户端返回的错误类型。
+func generateHTTPClientErrors(f *jen.File) {
+	// type HTTPStatusError struct {
+	//   StatusCode int
+	//   Body       []byte
+	// }
+	f.Comment("HTTPStatusError 表示服务端返回了非 2xx 状态码，Body 是响应体的开头部分。")
+	f.Type().Id("HTTPStatusError").Struct(
+		jen.Id("StatusCode").Int(),
+		jen.Id("Body").Index().Byte(),
+	).Line()
+
+	f.Func().
+		Params(jen.Id("e").Op("*").Id("HTTPStatusError")).
+		Id("Error").
+		Params().
+		String().
+		Block(
+			jen.Return(jen.Qual("fmt", "Sprintf").Call(
+				jen.Lit("unexpected http status %d %s: %s"),
+				jen.Id("e").Dot("StatusCode"),
+				jen.Qual("net/http", "StatusText").Call(jen.Id("e").Dot("StatusCode")),
+				jen.Id("e").Dot("Body"))),
+		).Line()
+
+	// type ServiceError struct {
+	//   Code    int
+	//   Message string
+	// }
+	f.Comment("ServiceError 表示响应中的业务错误码不为 0。")
+	f.Type().Id("ServiceError").Struct(
+		jen.Id("Code").Int(),
+		jen.Id("Message").String(),
+	).Line()
+
+	f.Func().
+		Params(jen.Id("e").Op("*").Id("ServiceError")).
+		Id("Error").
+		Params().
+		String().
+		Block(
+			jen.Return(jen.Qual("fmt", "Sprintf").Call(
+				jen.Lit("service error %d: %s"),
+				jen.Id("e").Dot("Code"),
+				jen.Id("e").Dot("Message"))),
+		).Line()
+}
+
+// generateClient 生成实现服务接口的客户端，把业务错误码转换为 *ServiceError。
+func generateClient(f *jen.File, service *domain.Service) {
+	serviceType := jen.Qual(service.Interface.Obj().Pkg().Path(), service.Name())
+
+	// type httpClient struct {
+	//   endpoints   EndpointSet
+	//   tokenSource jkhttp.TokenSource
+	// }
+	f.Type().Id("httpClient").StructFunc(func(g *jen.Group) {
+		g.Id("endpoints").Id("EndpointSet")
+		if common.HasAuth(service) {
+			g.Id("tokenSource").Qual(utils.FilePackage, "TokenSource")
+		}
+	}).Line()
+
+	// var _ Service = (*httpClient)(nil)
+	f.Var().Id("_").Add(serviceType).Op("=").Parens(jen.Op("*").Id("httpClient")).Call(jen.Nil()).Line()
+
+	// func NewHTTPClient(baseURL string, options ...khttp.ClientOption) (Service, error) {
+	f.Comment("NewHTTPClient 创建访问 baseURL 的服务客户端。")
+	f.Comment("非 2xx 状态码返回 *HTTPStatusError，业务错误码不为 0 时返回 *ServiceError。")
+	f.Func().
+		Id("NewHTTPClient").
+		Params(
+			jen.Id("baseURL").String(),
+			jen.Id("options").Op("...").Qual("github.com/go-kit/kit/transport/http", "ClientOption")).
+		Params(serviceType.Clone(), jen.Error()).
+		Block(
+			jen.List(jen.Id("clientSet"), jen.Err()).Op(":=").Id("NewHTTPClientSetWithBaseURL").
+				Call(jen.Id("baseURL"), jen.Id("options").Op("...")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err())),
+			jen.Return(jen.Id("NewClient").Call(jen.Id("clientSet").Dot("EndpointSet").Call()), jen.Nil()),
+		).Line()
+
+	// func NewClient(endpoints EndpointSet) Service {
+	f.Comment("NewClient 使用 endpoints 创建服务客户端，endpoints 可以来自 NewLoadBalancedEndpointSet 或经过中间件包装。")
+	f.Func().
+		Id("NewClient").
+		Params(jen.Id("endpoints").Id("EndpointSet")).
+		Add(serviceType.Clone()).
+		Block(
+			jen.Return(jen.Op("&").Id("httpClient").Values(jen.Dict{
+				jen.Id("endpoints"): jen.Id("endpoints"),
+			})),
+		).Line()
+
+	if common.HasAuth(service) {
+		generateClientTokenSource(f, serviceType)
+	}
+
+	for _, method := range service.Methods {
+		if !method.Func.Exported() {
+			continue
+		}
+
+		// func (c *httpClient) XXX(ctx context.Context, req *REQ) (*RESP, error) {
+		//   resp, err := c.endpoints.XXX(ctx, req)
+		//   if err != nil {
+		//     return nil, err
+		//   }
+		//   if resp.Code != 0 {
+		//     return nil, &ServiceError{Code: resp.Code, Message: resp.Message}
+		//   }
+		//   return resp, nil
+		// }
+		f.Func().
+			Params(jen.Id("c").Op("*").Id("httpClient")).
+			Id(method.Func.Name()).
+			Params(
+				jen.Id("ctx").Qual("context", "Context"),
+				jen.Id("req").Op("*").Add(method.RequestTypeCodeJen())).
+			Params(method.ResultTypeCodeJen(), jen.Error()).
+			BlockFunc(func(g *jen.Group) {
+				if method.Annotations.Idempotent {
+					// 每次调用使用一个新的幂等键，调用方可以用 jkhttp.WithIdempotencyKey 指定
+					g.If(jen.List(jen.Id("_"), jen.Id("ok")).Op(":=").Qual(utils.FilePackage, "IdempotencyKey").Call(jen.Id("ctx")), jen.Op("!").Id("ok")).Block(
+						jen.Id("ctx").Op("=").Qual(utils.FilePackage, "WithIdempotencyKey").Call(jen.Id("ctx"), jen.Qual(utils.FilePackage, "NewIdempotencyKey").Call()),
+					)
+				}
+				if scheme := method.AuthScheme(); scheme != "" {
+					// ctx, err := c.credentials(ctx, jkhttp.AuthBearer)
+					g.List(jen.Id("ctx"), jen.Err()).Op(":=").Id("c").Dot("credentials").Call(jen.Id("ctx"), common.AuthSchemeJen(scheme))
+					g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err()))
+				}
+				g.List(jen.Id("resp"), jen.Err()).Op(":=").Id("c").Dot("endpoints").Dot(method.Func.Name()).
+					Call(jen.Id("ctx"), jen.Id("req"))
+				g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err()))
+				// 流式响应和事件没有 Code 和 Message 字段
+				if !common.IsStream(method) && !method.IsEventStream() {
+					g.If(jen.Id("resp").Dot("Code").Op("!=").Lit(0)).Block(
+						jen.Return(jen.Nil(), jen.Op("&").Id("ServiceError").Values(jen.Dict{
+							jen.Id("Code"):    jen.Id("resp").Dot("Code"),
+							jen.Id("Message"): jen.Id("resp").Dot("Message"),
+						})),
+					)
+				}
+				g.Return(jen.Id("resp"), jen.Nil())
+			}).Line()
+	}
+}
+
+// generateClientTokenSource 生成使用 TokenSource 的客户端构造函数，以及调用需要认证的方法前获取凭据的 credentials 方法。
+func generateClientTokenSource(f *jen.File, serviceType *jen.Statement) {
+	tokenSource := jen.Qual(utils.FilePackage, "TokenSource")
+
+	// func NewHTTPClientWithTokenSource(baseURL string, src jkhttp.TokenSource, options ...khttp.ClientOption) (Service, error) {
+	f.Comment("NewHTTPClientWithTokenSource 创建访问 baseURL 的服务客户端，调用需要认证的方法时从 src 获取凭据。")
+	f.Func().
+		Id("NewHTTPClientWithTokenSource").
+		Params(
+			jen.Id("baseURL").String(),
+			jen.Id("src").Add(tokenSource.Clone()),
+			jen.Id("options").Op("...").Qual("github.com/go-kit/kit/transport/http", "ClientOption")).
+		Params(serviceType.Clone(), jen.Error()).
+		Block(
+			jen.List(jen.Id("clientSet"), jen.Err()).Op(":=").Id("NewHTTPClientSetWithBaseURL").
+				Call(jen.Id("baseURL"), jen.Id("options").Op("...")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err())),
+			jen.Return(jen.Id("NewClientWithTokenSource").Call(jen.Id("clientSet").Dot("EndpointSet").Call(), jen.Id("src")), jen.Nil()),
+		).Line()
+
+	// func NewClientWithTokenSource(endpoints EndpointSet, src jkhttp.TokenSource) Service {
+	f.Comment("NewClientWithTokenSource 使用 endpoints 创建服务客户端，调用需要认证的方法时从 src 获取凭据。")
+	f.Comment("ctx 中已经有 jkhttp.WithCredentials 设置的同一认证方式的凭据时不再获取。")
+	f.Func().
+		Id("NewClientWithTokenSource").
+		Params(jen.Id("endpoints").Id("EndpointSet"), jen.Id("src").Add(tokenSource.Clone())).
+		Add(serviceType.Clone()).
+		Block(
+			jen.Return(jen.Op("&").Id("httpClient").Values(jen.Dict{
+				jen.Id("endpoints"):   jen.Id("endpoints"),
+				jen.Id("tokenSource"): jen.Id("src"),
+			})),
+		).Line()
+
+	// func (c *httpClient) credentials(ctx context.Context, scheme string) (context.Context, error) {
+	//   if cred, ok := jkhttp.CredentialsFromContext(ctx); (ok && cred.Scheme == scheme) || c.tokenSource == nil {
+	//     return ctx, nil
+	//   }
+	//   cred, err := c.tokenSource.Credentials(ctx, scheme)
+	//   if err != nil {
+	//     return nil, err
+	//   }
+	//   return jkhttp.WithCredentials(ctx, cred), nil
+	// }
+	f.Comment("credentials 返回带有认证方式 scheme 的凭据的 ctx，同一次调用的重试使用同一个凭据。")
+	f.Func().
+		Params(jen.Id("c").Op("*").Id("httpClient")).
+		Id("credentials").
+		Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("scheme").String()).
+		Params(jen.Qual("context", "Context"), jen.Error()).
+		Block(
+			jen.If(
+				jen.List(jen.Id("cred"), jen.Id("ok")).Op(":=").Qual(utils.FilePackage, "CredentialsFromContext").Call(jen.Id("ctx")),
+				jen.Parens(jen.Id("ok").Op("&&").Id("cred").Dot("Scheme").Op("==").Id("scheme")).Op("||").Id("c").Dot("tokenSource").Op("==").Nil(),
+			).Block(jen.Return(jen.Id("ctx"), jen.Nil())),
+			jen.List(jen.Id("cred"), jen.Err()).Op(":=").Id("c").Dot("tokenSource").Dot("Credentials").Call(jen.Id("ctx"), jen.Id("scheme")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err())),
+			jen.Return(jen.Qual(utils.FilePackage, "WithCredentials").Call(jen.Id("ctx"), jen.Id("cred")), jen.Nil()),
+		).Line()
+}
+
 func GenerateHTTPTransportClient(f *jen.File, service *domain.Service) {
+	common.HTTPPopulateDefaultAnnotations(service)
+	generateHTTPClientErrors(f)
 	generateHTTPJSONResponseDecoder(f)
 	generateHTTPQueryStringEncoder(f)
+	if common.HasStream(service) {
+		generateHTTPStreamResponseDecoder(f)
+	}
+	if common.HasEventStream(service) {
+		generateHTTPEventStreamResponseDecoder(f)
+	}
+	if common.HasCodecs(service) {
+		generateHTTPCodecResponseDecoder(f)
+		generateHTTPCodecHelpers(f)
+	}
+	if common.HasContentType(service, common.ContentTypeForm) {
+		generateHTTPFormEncoder(f)
+	}
+	if common.HasContentType(service, common.ContentTypeMultipart) {
+		generateHTTPMultipartEncoder(f)
+	}
 	generateClientSet(f, service)
+	generateClient(f, service)
+	generatePaginators(f, service)
 }
@@ -0,0 +1,105 @@
+package std
+
+import (
+	"go/types"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/nnnewb/jk/internal/domain"
+)
+
+// typeCode 返回类型 typ 的代码。
+func typeCode(typ types.Type) *jen.Statement {
+	switch t := typ.(type) {
+	case *types.Named:
+		if t.Obj().Pkg() == nil {
+			return jen.Id(t.Obj().Name())
+		}
+		return jen.Qual(t.Obj().Pkg().Path(), t.Obj().Name())
+	case *types.Pointer:
+		return jen.Op("*").Add(typeCode(t.Elem()))
+	case *types.Slice:
+		return jen.Index().Add(typeCode(t.Elem()))
+	case *types.Array:
+		return jen.Index(jen.Lit(int(t.Len()))).Add(typeCode(t.Elem()))
+	case *types.Map:
+		return jen.Map(typeCode(t.Key())).Add(typeCode(t.Elem()))
+	default:
+		return jen.Id(typ.String())
+	}
+}
+
+// generatePaginators 为 @paginated 方法生成 iter.Seq2 风格的迭代器 XXXSeq，按需逐页调用方法。
+// 迭代器的类型是 func(yield func(ITEM, error) bool)，Go 1.23 起可以直接用 for range 遍历。
+func generatePaginators(f *jen.File, service *domain.Service) {
+	serviceType := jen.Qual(service.Interface.Obj().Pkg().Path(), service.Name())
+	for _, method := range service.Methods {
+		if !method.Func.Exported() || method.Pagination == nil {
+			continue
+		}
+
+		p := method.Pagination
+		name := method.Func.Name()
+		item := typeCode(p.ItemType())
+
+		// func ListOrdersSeq(ctx context.Context, svc Service, req *REQ) func(yield func(ITEM, error) bool) {
+		// 	return func(yield func(ITEM, error) bool) {
+		// 		page := *req
+		// 		for {
+		// 			resp, err := svc.ListOrders(ctx, &page)
+		// 			if err != nil {
+		// 				var zero ITEM
+		// 				yield(zero, err)
+		// 				return
+		// 			}
+		// 			for _, item := range resp.Items {
+		// 				if !yield(item, nil) {
+		// 					return
+		// 				}
+		// 			}
+		// 			if resp.NextPageToken == "" {
+		// 				return
+		// 			}
+		// 			page.PageToken = resp.NextPageToken
+		// 		}
+		// 	}
+		// }
+		seqType := jen.Func().Params(jen.Id("yield").Func().Params(item.Clone(), jen.Error()).Bool())
+		f.Commentf("%sSeq 返回逐个产出 %s 每页数据的迭代器，从 req 指定的页开始，遍历完一页后才请求下一页，直到最后一页。", name, name)
+		f.Comment("出错时产出零值和错误后结束，调用方停止遍历时不再请求下一页。")
+		f.Func().
+			Id(name+"Seq").
+			Params(
+				jen.Id("ctx").Qual("context", "Context"),
+				jen.Id("svc").Add(serviceType.Clone()),
+				jen.Id("req").Op("*").Add(method.RequestTypeCodeJen())).
+			Add(seqType.Clone()).
+			Block(
+				jen.Return(seqType.Clone().Block(
+					jen.Id("page").Op(":=").Op("*").Id("req"),
+					jen.For().Block(
+						jen.List(jen.Id("resp"), jen.Err()).Op(":=").Id("svc").Dot(name).Call(jen.Id("ctx"), jen.Op("&").Id("page")),
+						jen.If(jen.Err().Op("!=").Nil()).Block(
+							jen.Var().Id("zero").Add(item.Clone()),
+							jen.Id("yield").Call(jen.Id("zero"), jen.Err()),
+							jen.Return(),
+						),
+						jen.For(jen.List(jen.Id("_"), jen.Id("item")).Op(":=").Range().Id("resp").Dot(p.Items.Name())).Block(
+							jen.If(jen.Op("!").Id("yield").Call(jen.Id("item"), jen.Nil())).Block(jen.Return()),
+						),
+						jen.If(jen.Id("resp").Dot(p.NextPageToken.Name()).Op("==").Lit("")).Block(jen.Return()),
+						jen.Id("page").Dot(p.PageToken.Name()).Op("=").Add(pageToken(p)),
+					),
+				)),
+			).
+			Line()
+	}
+}
+
+// pageToken 返回把响应的下一页令牌赋给请求的表达式，两个字段的类型不同时需要转换。
+func pageToken(p *domain.Pagination) *jen.Statement {
+	next := jen.Id("resp").Dot(p.NextPageToken.Name())
+	if types.Identical(p.PageToken.Type(), p.NextPageToken.Type()) {
+		return next
+	}
+	return typeCode(p.PageToken.Type()).Call(next)
+}
@@ -0,0 +1,793 @@
+package httpx
+
+import (
+	"fmt"
+	"go/types"
+	"io"
+	"net/http"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/iancoleman/strcase"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+// pythonReserved 是不能直接作为 dataclass 字段名的标识符，包括关键字和生成代码里用到的内置名。
+var pythonReserved = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true,
+	"async": true, "await": true, "break": true, "class": true, "continue": true, "def": true,
+	"del": true, "elif": true, "else": true, "except": true, "finally": true, "for": true,
+	"from": true, "global": true, "if": true, "import": true, "in": true, "is": true,
+	"lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true, "raise": true,
+	"return": true, "try": true, "while": true, "with": true, "yield": true,
+	"bool": true, "bytes": true, "dict": true, "float": true, "int": true, "list": true,
+	"str": true, "base64": true, "dataclasses": true, "datetime": true, "httpx": true, "json": true, "re": true,
+	"uuid": true, "cls": true, "self": true,
+}
+
+func pythonFieldName(name string) string {
+	ret := strcase.ToSnake(name)
+	if pythonReserved[ret] {
+		ret += "_"
+	}
+	return ret
+}
+
+// pythonFieldNames 返回结构体字段对应的 dataclass 字段名。userID 和 user_id 这样的 Go 字段名会转换成同一个名字，
+// 后出现的字段依次加上 _2、_3 等后缀。
+func pythonFieldNames(fields []common.JSONField) []string {
+	names := make([]string, 0, len(fields))
+	used := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		name := pythonFieldName(field.Var.Name())
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s_%d", pythonFieldName(field.Var.Name()), i)
+		}
+		used[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+func isBytes(t *types.Slice) bool {
+	b, ok := t.Elem().(*types.Basic)
+	return ok && b.Kind() == types.Uint8
+}
+
+// pythonType 返回 Go 类型对应的 python 类型注解。
+func pythonType(typ types.Type) (string, error) {
+	switch t := typ.(type) {
+	case *types.Named:
+		switch {
+		case utils.IsTimeType(t):
+			return "datetime.datetime", nil
+		case utils.IsJSONMarshaler(t):
+			return "Any", nil
+		case utils.IsTextMarshaler(t):
+			return "str", nil
+		}
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			return t.Obj().Name(), nil
+		}
+		return pythonType(t.Underlying())
+	case *types.Pointer:
+		elem, err := pythonType(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Optional[%s]", elem), nil
+	case *types.Array:
+		elem, err := pythonType(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("List[%s]", elem), nil
+	case *types.Slice:
+		if isBytes(t) {
+			return "bytes", nil
+		}
+		elem, err := pythonType(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("List[%s]", elem), nil
+	case *types.Map:
+		elem, err := pythonType(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Dict[str, %s]", elem), nil
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool:
+			return "bool", nil
+		case types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+			types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+			return "int", nil
+		case types.Float32, types.Float64:
+			return "float", nil
+		case types.String:
+			return "str", nil
+		default:
+			return "", errors.Errorf("unserializable basic type %v", t.Kind())
+		}
+	case *types.Struct:
+		return "Dict[str, Any]", nil
+	default:
+		return "", errors.Errorf("unserializable type %v", typ)
+	}
+}
+
+// pythonDefault 返回 dataclass 字段的默认值。
+func pythonDefault(typ types.Type) string {
+	switch t := typ.(type) {
+	case *types.Named:
+		switch {
+		case utils.IsTimeType(t):
+			return "_ZERO_TIME"
+		case utils.IsJSONMarshaler(t):
+			return "None"
+		case utils.IsTextMarshaler(t):
+			return `""`
+		}
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			return fmt.Sprintf("dataclasses.field(default_factory=lambda: %s())", t.Obj().Name())
+		}
+		return pythonDefault(t.Underlying())
+	case *types.Pointer:
+		return "None"
+	case *types.Array:
+		return "dataclasses.field(default_factory=list)"
+	case *types.Slice:
+		if isBytes(t) {
+			return `b""`
+		}
+		return "dataclasses.field(default_factory=list)"
+	case *types.Map, *types.Struct:
+		return "dataclasses.field(default_factory=dict)"
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool:
+			return "False"
+		case types.Float32, types.Float64:
+			return "0.0"
+		case types.String:
+			return `""`
+		default:
+			return "0"
+		}
+	default:
+		return "None"
+	}
+}
+
+// pythonEncode 返回把 python 值 v 转换为 JSON 兼容值的表达式，File 保持原样，由 _multipart 作为文件上传。
+func pythonEncode(typ types.Type, v string, depth int) string {
+	switch t := typ.(type) {
+	case *types.Named:
+		switch {
+		case utils.IsTimeType(t):
+			return fmt.Sprintf("_format_time(%s)", v)
+		case utils.IsFileType(t), common.IsOpaqueType(t):
+			return v
+		}
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			return fmt.Sprintf("%s.to_dict()", v)
+		}
+		return pythonEncode(t.Underlying(), v, depth)
+	case *types.Pointer:
+		elem := pythonEncode(t.Elem(), v, depth)
+		if elem == v {
+			return v
+		}
+		return fmt.Sprintf("None if %s is None else %s", v, elem)
+	case *types.Slice:
+		if isBytes(t) {
+			return fmt.Sprintf(`base64.b64encode(%s).decode("ascii")`, v)
+		}
+		item := fmt.Sprintf("v%d", depth)
+		elem := pythonEncode(t.Elem(), item, depth+1)
+		if elem == item {
+			return fmt.Sprintf("list(%s)", v)
+		}
+		return fmt.Sprintf("[%s for %s in %s]", elem, item, v)
+	case *types.Array:
+		item := fmt.Sprintf("v%d", depth)
+		elem := pythonEncode(t.Elem(), item, depth+1)
+		if elem == item {
+			return fmt.Sprintf("list(%s)", v)
+		}
+		return fmt.Sprintf("[%s for %s in %s]", elem, item, v)
+	case *types.Map:
+		key, item := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+		elem := pythonEncode(t.Elem(), item, depth+1)
+		if elem == item {
+			return fmt.Sprintf("dict(%s)", v)
+		}
+		return fmt.Sprintf("{%s: %s for %s, %s in %s.items()}", key, elem, key, item, v)
+	default:
+		return v
+	}
+}
+
+// pythonDecode 返回把 JSON 解码结果 v 转换为 python 类型的表达式。
+func pythonDecode(typ types.Type, v string, depth int) string {
+	switch t := typ.(type) {
+	case *types.Named:
+		switch {
+		case utils.IsTimeType(t):
+			return fmt.Sprintf("_parse_time(%s)", v)
+		case utils.IsFileType(t), common.IsOpaqueType(t):
+			return v
+		}
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			return fmt.Sprintf("%s.from_dict(%s)", t.Obj().Name(), v)
+		}
+		return pythonDecode(t.Underlying(), v, depth)
+	case *types.Pointer:
+		elem := pythonDecode(t.Elem(), v, depth)
+		if elem == v {
+			return v
+		}
+		return fmt.Sprintf("None if %s is None else %s", v, elem)
+	case *types.Slice:
+		if isBytes(t) {
+			return fmt.Sprintf(`base64.b64decode(%s or "")`, v)
+		}
+		item := fmt.Sprintf("v%d", depth)
+		elem := pythonDecode(t.Elem(), item, depth+1)
+		if elem == item {
+			return fmt.Sprintf("list(%s or [])", v)
+		}
+		return fmt.Sprintf("[%s for %s in (%s or [])]", elem, item, v)
+	case *types.Array:
+		item := fmt.Sprintf("v%d", depth)
+		elem := pythonDecode(t.Elem(), item, depth+1)
+		if elem == item {
+			return fmt.Sprintf("list(%s or [])", v)
+		}
+		return fmt.Sprintf("[%s for %s in (%s or [])]", elem, item, v)
+	case *types.Map:
+		key, item := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+		elem := pythonDecode(t.Elem(), item, depth+1)
+		if elem == item {
+			return fmt.Sprintf("dict(%s or {})", v)
+		}
+		return fmt.Sprintf("{%s: %s for %s, %s in (%s or {}).items()}", key, elem, key, item, v)
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Float32, types.Float64:
+			// JSON 里的整数会被解码为 int
+			return fmt.Sprintf("float(%s)", v)
+		default:
+			return v
+		}
+	default:
+		return v
+	}
+}
+
+func generateDataclass(wr io.Writer, named *types.Named) error {
+	structType := named.Underlying().(*types.Struct)
+	fields := common.JSONFields(structType)
+	names := pythonFieldNames(fields)
+
+	_, err := fmt.Fprintf(wr, "\n\n@dataclasses.dataclass\nclass %s:\n", named.Obj().Name())
+	if err != nil {
+		return err
+	}
+
+	for i, field := range fields {
+		typ, err := pythonType(field.Var.Type())
+		if err != nil {
+			return errors.Wrapf(err, "field %s.%s", named.Obj().Name(), field.Var.Name())
+		}
+
+		_, err = fmt.Fprintf(wr, "    %s: %s = %s\n", names[i], typ, pythonDefault(field.Var.Type()))
+		if err != nil {
+			return err
+		}
+	}
+
+	// to_dict
+	_, err = io.WriteString(wr, "\n    def to_dict(self) -> Dict[str, Any]:\n        data: Dict[str, Any] = {}\n")
+	if err != nil {
+		return err
+	}
+	for i, field := range fields {
+		attr := "self." + names[i]
+		if _, ok := field.Var.Type().(*types.Pointer); ok && field.OmitEmpty {
+			// omitempty 只会省略 nil 指针
+			_, err = fmt.Fprintf(wr, "        if %s is not None:\n            data[%q] = %s\n", attr, field.Name, pythonEncode(field.Var.Type(), attr, 0))
+		} else if field.OmitEmpty {
+			_, err = fmt.Fprintf(wr, "        if %s:\n            data[%q] = %s\n", attr, field.Name, pythonEncode(field.Var.Type(), attr, 0))
+		} else {
+			_, err = fmt.Fprintf(wr, "        data[%q] = %s\n", field.Name, pythonEncode(field.Var.Type(), attr, 0))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(wr, "        return data\n")
+	if err != nil {
+		return err
+	}
+
+	// from_dict
+	_, err = fmt.Fprintf(wr, "\n    @classmethod\n    def from_dict(cls, data: Dict[str, Any]) -> %s:\n        return cls(\n", named.Obj().Name())
+	if err != nil {
+		return err
+	}
+	for i, field := range fields {
+		var value string
+		if _, ok := field.Var.Type().Underlying().(*types.Basic); ok {
+			value = fmt.Sprintf("data.get(%q, %s)", field.Name, pythonDefault(field.Var.Type()))
+		} else {
+			value = fmt.Sprintf("data.get(%q)", field.Name)
+		}
+		_, err = fmt.Fprintf(wr, "            %s=%s,\n", names[i], pythonDecode(field.Var.Type(), value, 0))
+		if err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(wr, "        )\n")
+	return err
+}
+
+// generatePayload 返回方法请求体的表达式。带有 jk:"file" 标签的 bytes 字段在 to_dict 中编码为 base64，
+// multipart/form-data 请求需要先替换为 File 才会作为文件上传，这时生成局部变量 payload。
+func generatePayload(wr io.Writer, method *domain.Method) (string, error) {
+	if !common.IsMultipart(method) {
+		return "req.to_dict()", nil
+	}
+
+	var fields []common.JSONField
+	var attrs []string
+	reqType := method.RequestType().(*types.Pointer).Elem()
+	all := common.JSONFields(reqType.Underlying().(*types.Struct))
+	for i, name := range pythonFieldNames(all) {
+		field := all[i]
+		if isFile, multiple := common.FilePart(field); isFile && !multiple {
+			if _, ptr := field.Var.Type().(*types.Pointer); !ptr {
+				fields = append(fields, field)
+				attrs = append(attrs, "req."+name)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return "req.to_dict()", nil
+	}
+
+	_, err := io.WriteString(wr, "        payload = req.to_dict()\n")
+	if err != nil {
+		return "", err
+	}
+	for i, field := range fields {
+		attr := attrs[i]
+		_, err = fmt.Fprintf(wr, "        payload[%q] = File(%q, %s) if %s else None\n", field.Name, field.Name, attr, attr)
+		if err != nil {
+			return "", err
+		}
+	}
+	return "payload", nil
+}
+
+// hasFiles 判断服务是否需要上传文件，生成的代码只在这时包含 File 和 _multipart。
+func hasFiles(service *domain.Service) bool {
+	if common.HasContentType(service, common.ContentTypeMultipart) {
+		return true
+	}
+	for _, named := range common.NamedStructs(service) {
+		for _, field := range common.JSONFields(named.Underlying().(*types.Struct)) {
+			if isFile, _ := common.FilePart(field); isFile {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasTime 判断服务的请求和响应是否包含 time.Time，生成的代码只在这时包含 datetime 的转换函数。
+func hasTime(service *domain.Service) bool {
+	for _, named := range common.NamedStructs(service) {
+		for _, field := range common.JSONFields(named.Underlying().(*types.Struct)) {
+			if containsTime(field.Var.Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsTime(typ types.Type) bool {
+	switch t := typ.(type) {
+	case *types.Named:
+		return utils.IsTimeType(t)
+	case *types.Pointer:
+		return containsTime(t.Elem())
+	case *types.Slice:
+		return containsTime(t.Elem())
+	case *types.Array:
+		return containsTime(t.Elem())
+	case *types.Map:
+		return containsTime(t.Elem())
+	default:
+		return false
+	}
+}
+
+// timePython 是 time.Time 的编码和解码函数。time.Time 编码为 RFC 3339 格式，没有时区的 datetime 按本地时间处理；
+// 解码时把 Z 后缀和纳秒精度转换成 datetime.fromisoformat 在 python 3.11 之前也能识别的格式。
+const timePython = `
+
+_ZERO_TIME = datetime.datetime(1, 1, 1, tzinfo=datetime.timezone.utc)
+_TIME_FRACTION = re.compile(r"\.(\d+)")
+
+
+def _format_time(value: datetime.datetime) -> str:
+    if value.tzinfo is None:
+        value = value.astimezone()
+    text = value.isoformat()
+    if text.endswith("+00:00"):
+        text = text[:-6] + "Z"
+    return text
+
+
+def _parse_time(value: Optional[str]) -> datetime.datetime:
+    if not value:
+        return _ZERO_TIME
+    if value[-1] in "Zz":
+        value = value[:-1] + "+00:00"
+    value = _TIME_FRACTION.sub(lambda m: "." + m.group(1)[:6].ljust(6, "0"), value, count=1)
+    return datetime.datetime.fromisoformat(value)
+`
+
+// formValuePython 把表单中的单个值转换为字符串，复合类型编码为 JSON。
+const formValuePython = `
+
+def _form_value(value: Any) -> str:
+    if isinstance(value, bool):
+        return "true" if value else "false"
+    if isinstance(value, (dict, list)):
+        return json.dumps(value)
+    return str(value)
+`
+
+// formPython 是 application/x-www-form-urlencoded 请求体的编码函数，编码方式和 multipart/form-data 的表单值相同。
+const formPython = `
+
+def _form(payload: Dict[str, Any]) -> Dict[str, List[str]]:
+    data: Dict[str, List[str]] = {}
+    for name, value in payload.items():
+        if value is None:
+            continue
+        if isinstance(value, list) and all(not isinstance(item, (dict, list)) for item in value):
+            data[name] = [_form_value(item) for item in value]
+        else:
+            data[name] = [_form_value(value)]
+    return data
+`
+
+// multipartPython 是 File 类型和 multipart/form-data 请求体的编码函数，编码方式和 jkhttp.EncodeMultipartRequest 相同：
+// File 作为文件，标量和标量列表作为表单值，其他值编码为 JSON。
+const multipartPython = `
+
+@dataclasses.dataclass
+class File:
+    """File uploaded by multipart/form-data requests."""
+
+    name: str = ""
+    content: bytes = b""
+    content_type: str = "application/octet-stream"
+
+
+def _quote(value: str) -> bytes:
+    return value.replace("\\", "\\\\").replace('"', '\\"').encode()
+
+
+def _multipart(payload: Dict[str, Any]) -> Tuple[bytes, str]:
+    boundary = uuid.uuid4().hex.encode()
+    body = bytearray()
+    for name, value in payload.items():
+        if value is None:
+            continue
+        if isinstance(value, list) and all(isinstance(item, File) or not isinstance(item, (dict, list)) for item in value):
+            items = value
+        else:
+            items = [value]
+        for item in items:
+            body += b"--" + boundary + b"\r\n"
+            if isinstance(item, File):
+                body += b'Content-Disposition: form-data; name="' + _quote(name) + b'"; filename="' + _quote(item.name) + b'"\r\n'
+                body += b"Content-Type: " + (item.content_type or "application/octet-stream").encode() + b"\r\n\r\n"
+                body += item.content
+            else:
+                body += b'Content-Disposition: form-data; name="' + _quote(name) + b'"\r\n\r\n'
+                body += _form_value(item).encode()
+            body += b"\r\n"
+    body += b"--" + boundary + b"--\r\n"
+    return bytes(body), "multipart/form-data; boundary=" + boundary.decode()
+`
+
+// generateRequestOptions 生成 _request_options，按请求方法和 @http-content-type 返回 httpx 发送请求体的参数。
+func generateRequestOptions(wr io.Writer, service *domain.Service) error {
+	_, err := io.WriteString(wr, `
+
+def _request_options(method: str, payload: Dict[str, Any], content_type: str) -> Dict[str, Any]:
+    if method in ("GET", "DELETE"):
+        return {"params": _query_params(payload)}
+`)
+	if err != nil {
+		return err
+	}
+
+	if common.HasContentType(service, common.ContentTypeMultipart) {
+		_, err = io.WriteString(wr, `    if content_type == "multipart/form-data":
+        content, multipart_type = _multipart(payload)
+        return {"content": content, "headers": {"Content-Type": multipart_type}}
+`)
+		if err != nil {
+			return err
+		}
+	}
+
+	if common.HasContentType(service, common.ContentTypeForm) {
+		_, err = io.WriteString(wr, `    if content_type == "application/x-www-form-urlencoded":
+        return {"data": _form(payload)}
+`)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(wr, "    return {\"json\": payload}\n")
+	return err
+}
+
+func generateClientClass(wr io.Writer, service *domain.Service) error {
+	_, err := fmt.Fprintf(wr, `
+
+class %sClient:
+    """HTTP client of %s."""
+
+    def __init__(self, base_url: str, client: Optional[httpx.Client] = None) -> None:
+        self._base_url = base_url.rstrip("/")
+        self._client = client if client is not None else httpx.Client()
+
+    def close(self) -> None:
+        self._client.close()
+
+    def __enter__(self) -> %sClient:
+        return self
+
+    def __exit__(self, *args: Any) -> None:
+        self.close()
+
+    def _request(self, method: str, path: str, payload: Dict[str, Any], content_type: str) -> httpx.Response:
+        url = self._base_url + path
+        resp = self._client.request(method, url, **_request_options(method, payload, content_type))
+        resp.raise_for_status()
+        return resp
+
+    def _call(
+        self,
+        method: str,
+        path: str,
+        payload: Dict[str, Any],
+        decode: Callable[[Dict[str, Any]], T],
+        content_type: str = "application/json",
+    ) -> T:
+        data = self._request(method, path, payload, content_type).json()
+        code = int(data.get("code", 0))
+        if code != 0:
+            raise APIError(code, str(data.get("message", "")))
+        return decode(data)
+
+    def _download(
+        self,
+        method: str,
+        path: str,
+        payload: Dict[str, Any],
+        content_type: str = "application/json",
+    ) -> bytes:
+        return self._request(method, path, payload, content_type).content
+
+    def _events(
+        self,
+        method: str,
+        path: str,
+        payload: Dict[str, Any],
+        decode: Callable[[Dict[str, Any]], T],
+        content_type: str = "application/json",
+    ) -> Iterator[T]:
+        url = self._base_url + path
+        options = _request_options(method, payload, content_type)
+        with self._client.stream(method, url, timeout=None, **options) as resp:
+            resp.raise_for_status()
+            data: List[str] = []
+            for line in resp.iter_lines():
+                if line.startswith("data:"):
+                    data.append(line[5:].lstrip(" "))
+                elif line == "" and data:
+                    yield decode(json.loads("\n".join(data)))
+                    data = []
+`, service.Name(), service.Name(), service.Name())
+	if err != nil {
+		return err
+	}
+
+	for _, method := range service.Methods {
+		if !method.Func.Exported() {
+			continue
+		}
+
+		httpMethod := method.Annotations.HTTPMethod
+		switch httpMethod {
+		case http.MethodGet, http.MethodDelete, http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			httpMethod = http.MethodPost
+		}
+
+		// 流式响应返回下载的内容，SSE 返回事件的迭代器
+		responseType := method.ResponseTypeName()
+		if common.IsStream(method) {
+			responseType = "bytes"
+		} else if method.IsEventStream() {
+			responseType = fmt.Sprintf("Iterator[%s]", responseType)
+		}
+
+		_, err = fmt.Fprintf(wr, "\n    def %s(self, req: %s) -> %s:\n",
+			pythonFieldName(method.Func.Name()),
+			method.RequestTypeName(),
+			responseType)
+		if err != nil {
+			return err
+		}
+
+		if doc := method.Doc(); doc != "" {
+			_, err = fmt.Fprintf(wr, "        \"\"\"%s\"\"\"\n", strings.ReplaceAll(doc, `"""`, `\"\"\"`))
+			if err != nil {
+				return err
+			}
+		}
+
+		payload, err := generatePayload(wr, method)
+		if err != nil {
+			return err
+		}
+
+		// JSON 请求使用默认的 content_type 参数
+		var contentType string
+		if common.IsMultipart(method) || common.IsForm(method) {
+			contentType = fmt.Sprintf(", %q", method.Annotations.HTTPContentType)
+		}
+		if common.IsStream(method) {
+			_, err = fmt.Fprintf(wr, "        return self._download(%q, %q, %s%s)\n",
+				httpMethod,
+				method.Annotations.HTTPPath,
+				payload,
+				contentType)
+		} else if method.IsEventStream() {
+			_, err = fmt.Fprintf(wr, "        return self._events(%q, %q, %s, %s.from_dict%s)\n",
+				httpMethod,
+				method.Annotations.HTTPPath,
+				payload,
+				method.ResponseTypeName(),
+				contentType)
+		} else {
+			_, err = fmt.Fprintf(wr, "        return self._call(%q, %q, %s, %s.from_dict%s)\n",
+				httpMethod,
+				method.Annotations.HTTPPath,
+				payload,
+				method.ResponseTypeName(),
+				contentType)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GeneratePythonClient 生成基于 httpx 的 python 客户端，请求和响应类型生成为 dataclass。
+func GeneratePythonClient(wr io.Writer, service *domain.Service) error {
+	common.HTTPPopulateDefaultAnnotations(service)
+
+	err := common.CheckTypeNames(common.NamedStructs(service))
+	if err != nil {
+		return err
+	}
+
+	files, times := hasFiles(service), hasTime(service)
+
+	modules, typing := []string{"base64", "dataclasses", "json"}, "Any, Callable, Dict, Iterator, List, Optional, TypeVar"
+	if times {
+		modules = []string{"base64", "dataclasses", "datetime", "json", "re"}
+	}
+	if files {
+		modules, typing = append(modules, "uuid"), "Any, Callable, Dict, Iterator, List, Optional, Tuple, TypeVar"
+	}
+
+	_, err = fmt.Fprintf(wr, `from __future__ import annotations
+
+import %s
+from typing import %s
+
+import httpx
+`, strings.Join(modules, "\nimport "), typing)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(wr, `
+T = TypeVar("T")
+
+
+class APIError(Exception):
+    """Raised when the response envelope carries a non-zero code."""
+
+    def __init__(self, code: int, message: str) -> None:
+        super().__init__("code %d: %s" % (code, message))
+        self.code = code
+        self.message = message
+
+
+def _query_params(payload: Dict[str, Any]) -> Dict[str, str]:
+    params: Dict[str, str] = {}
+    for key, value in payload.items():
+        if value is None:
+            continue
+        if isinstance(value, bool):
+            params[key] = "true" if value else "false"
+        else:
+            params[key] = str(value)
+    return params
+`)
+	if err != nil {
+		return err
+	}
+
+	if times {
+		_, err = io.WriteString(wr, timePython)
+		if err != nil {
+			return err
+		}
+	}
+
+	form := common.HasContentType(service, common.ContentTypeForm)
+	if files || form {
+		_, err = io.WriteString(wr, formValuePython)
+		if err != nil {
+			return err
+		}
+	}
+	if form {
+		_, err = io.WriteString(wr, formPython)
+		if err != nil {
+			return err
+		}
+	}
+	if files {
+		_, err = io.WriteString(wr, multipartPython)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = generateRequestOptions(wr, service)
+	if err != nil {
+		return err
+	}
+
+	for _, named := range common.NamedStructs(service) {
+		err = generateDataclass(wr, named)
+		if err != nil {
+			return errors.Wrapf(err, "generate dataclass for %s", named.Obj().Name())
+		}
+	}
+
+	return generateClientClass(wr, service)
+}
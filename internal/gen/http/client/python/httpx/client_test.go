@@ -0,0 +1,46 @@
+package httpx
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nnnewb/jk/internal/domain/domaintest"
+)
+
+func TestGeneratePythonClient(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		src  string
+		want []string
+		err  string
+	}{
+		// time.Time 转换为 datetime，嵌入结构体的字段提升到外层，同名的字段名加上后缀
+		{"Embedded", domaintest.EmbeddedSource, []string{
+			"import datetime\n",
+			"    tenant: str = \"\"\n",
+			"    created_at: datetime.datetime = _ZERO_TIME\n",
+			"    source: str = \"\"\n",
+			"    user_id: str = \"\"\n",
+			"    user_id_2: str = \"\"\n",
+			`data["created_at"] = _format_time(self.created_at)`,
+			`data["user_id"] = self.user_id_2`,
+			`created_at=_parse_time(data.get("created_at")),`,
+			`deadline=None if data.get("deadline") is None else _parse_time(data.get("deadline")),`,
+		}, ""},
+		// dataclass 只用类型名命名，不同包的同名类型无法区分
+		{"Collision", domaintest.CollisionSource, nil, "have the same name FileHeader"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := GeneratePythonClient(&buf, domaintest.Service(t, c.src, "Service"))
+			if c.err != "" {
+				domaintest.ErrorContains(t, err, c.err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("generate failed: %+v", err)
+			}
+			domaintest.Contains(t, buf.String(), c.want...)
+		})
+	}
+}
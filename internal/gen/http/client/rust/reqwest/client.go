@@ -0,0 +1,697 @@
+package reqwest
+
+import (
+	"fmt"
+	"go/types"
+	"io"
+	"net/http"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/iancoleman/strcase"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+// rustKeywords 不能直接作为字段名，需要写成 raw identifier。
+var rustKeywords = map[string]bool{
+	"as": true, "async": true, "await": true, "break": true, "const": true, "continue": true,
+	"crate": true, "dyn": true, "else": true, "enum": true, "extern": true, "false": true,
+	"fn": true, "for": true, "if": true, "impl": true, "in": true, "let": true, "loop": true,
+	"match": true, "mod": true, "move": true, "mut": true, "pub": true, "ref": true,
+	"return": true, "static": true, "struct": true, "trait": true, "true": true, "type": true,
+	"unsafe": true, "use": true, "where": true, "while": true, "abstract": true, "become": true,
+	"box": true, "do": true, "final": true, "macro": true, "override": true, "priv": true,
+	"typeof": true, "unsized": true, "virtual": true, "yield": true, "try": true,
+}
+
+func rustFieldName(name string) string {
+	ret := strcase.ToSnake(name)
+	if rustKeywords[ret] {
+		ret = "r#" + ret
+	}
+	return ret
+}
+
+// rustFieldNames 返回结构体字段对应的 rust 字段名。userID 和 user_id 这样的 Go 字段名会转换成同一个名字，
+// 后出现的字段依次加上 _2、_3 等后缀。
+func rustFieldNames(fields []common.JSONField) []string {
+	names := make([]string, 0, len(fields))
+	used := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		name := rustFieldName(field.Var.Name())
+		for i := 2; used[name]; i++ {
+			name = rustFieldName(fmt.Sprintf("%s_%d", strcase.ToSnake(field.Var.Name()), i))
+		}
+		used[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// embeddedNamed 返回嵌入字段的具名结构体类型，嵌入字段在 rust 里生成为 #[serde(flatten)] 的字段。
+func embeddedNamed(field common.JSONField) (*types.Named, bool) {
+	if common.EmbeddedStruct(field) == nil {
+		return nil, false
+	}
+	typ := field.Var.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	return named, ok
+}
+
+// rustStructs 返回需要生成的结构体，在 common.NamedStructs 的基础上加入嵌入的结构体。
+func rustStructs(service *domain.Service) []*types.Named {
+	var (
+		ret  []*types.Named
+		seen = make(map[*types.TypeName]bool)
+		add  func(named *types.Named)
+	)
+	add = func(named *types.Named) {
+		if seen[named.Obj()] {
+			return
+		}
+		seen[named.Obj()] = true
+		for _, field := range common.DeclaredJSONFields(named.Underlying().(*types.Struct)) {
+			if embedded, ok := embeddedNamed(field); ok {
+				add(embedded)
+			}
+		}
+		ret = append(ret, named)
+	}
+	for _, named := range common.NamedStructs(service) {
+		add(named)
+	}
+	return ret
+}
+
+// checkFlatten 检查嵌入结构体能否用 #[serde(flatten)] 表示。encoding/json 会丢弃被遮蔽或者有歧义的同名字段，
+// flatten 做不到，这时返回错误；嵌入自身的结构体在 rust 里无法展开，也返回错误。
+func checkFlatten(named *types.Named) error {
+	var (
+		count    int
+		visiting = make(map[*types.Struct]bool)
+		walk     func(t *types.Struct) error
+	)
+	walk = func(t *types.Struct) error {
+		visiting[t] = true
+		defer delete(visiting, t)
+		for _, field := range common.DeclaredJSONFields(t) {
+			embedded := common.EmbeddedStruct(field)
+			if embedded == nil {
+				count++
+				continue
+			}
+			if visiting[embedded] {
+				return errors.Errorf("recursive embedded field %s is not supported by rust client", field.Var.Name())
+			}
+			if err := walk(embedded); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	structType := named.Underlying().(*types.Struct)
+	if err := walk(structType); err != nil {
+		return err
+	}
+	if count != len(common.JSONFields(structType)) {
+		return errors.New("fields of embedded struct are shadowed, which is not supported by rust client")
+	}
+	return nil
+}
+
+// rustFieldPath 返回访问 field 的 rust 表达式，field 是 common.JSONFields(named) 的字段，提升的字段经过 flatten 字段访问。
+func rustFieldPath(named *types.Named, field common.JSONField) (string, error) {
+	var (
+		path = []string{"self"}
+		cur  = named.Underlying().(*types.Struct)
+	)
+	for _, v := range append(field.Path[:len(field.Path):len(field.Path)], field.Var) {
+		fields := common.DeclaredJSONFields(cur)
+		for i, name := range rustFieldNames(fields) {
+			if fields[i].Var != v {
+				continue
+			}
+			path = append(path, name)
+			cur = common.EmbeddedStruct(fields[i])
+			break
+		}
+		if _, ok := v.Type().(*types.Pointer); ok && v != field.Var {
+			return "", errors.Errorf("field %s is promoted through embedded pointer %s", field.Var.Name(), v.Name())
+		}
+	}
+	return strings.Join(path, "."), nil
+}
+
+// reachesByValue 判断 from 是否不经过 Vec/HashMap 间接引用就能到达 target。
+// 这样的引用链在 rust 里会形成无限大小的类型，需要 Box。
+func reachesByValue(from types.Type, target *types.Named, seen map[*types.TypeName]bool) bool {
+	switch t := from.(type) {
+	case *types.Named:
+		if t.Obj() == target.Obj() {
+			return true
+		}
+		structType, ok := t.Underlying().(*types.Struct)
+		if !ok || seen[t.Obj()] {
+			return false
+		}
+		seen[t.Obj()] = true
+		for _, field := range common.DeclaredJSONFields(structType) {
+			if reachesByValue(field.Var.Type(), target, seen) {
+				return true
+			}
+		}
+		return false
+	case *types.Pointer:
+		return reachesByValue(t.Elem(), target, seen)
+	default:
+		return false
+	}
+}
+
+// rustType 返回 Go 类型对应的 rust 类型，owner 是字段所属的结构体。
+func rustType(typ types.Type, owner *types.Named) (string, error) {
+	switch t := typ.(type) {
+	case *types.Named:
+		switch {
+		case utils.IsFileType(t):
+			return "", errors.New("jkhttp.File is not supported by rust client")
+		case utils.IsTimeType(t):
+			// time.Time 编码为 RFC 3339 字符串，不引入 chrono 依赖
+			return "String", nil
+		case utils.IsJSONMarshaler(t):
+			return "serde_json::Value", nil
+		case utils.IsTextMarshaler(t):
+			return "String", nil
+		}
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			return t.Obj().Name(), nil
+		}
+		return rustType(t.Underlying(), owner)
+	case *types.Pointer:
+		elem, err := rustType(t.Elem(), owner)
+		if err != nil {
+			return "", err
+		}
+		if reachesByValue(t.Elem(), owner, map[*types.TypeName]bool{}) {
+			return fmt.Sprintf("Option<Box<%s>>", elem), nil
+		}
+		return fmt.Sprintf("Option<%s>", elem), nil
+	case *types.Array:
+		elem, err := rustType(t.Elem(), owner)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Vec<%s>", elem), nil
+	case *types.Slice:
+		if b, ok := t.Elem().(*types.Basic); ok && b.Kind() == types.Uint8 {
+			// encoding/json 把 []byte 编码为 base64 字符串
+			return "String", nil
+		}
+		elem, err := rustType(t.Elem(), owner)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Vec<%s>", elem), nil
+	case *types.Map:
+		elem, err := rustType(t.Elem(), owner)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("HashMap<String, %s>", elem), nil
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool:
+			return "bool", nil
+		case types.Int, types.Int64:
+			return "i64", nil
+		case types.Int8:
+			return "i8", nil
+		case types.Int16:
+			return "i16", nil
+		case types.Int32:
+			return "i32", nil
+		case types.Uint, types.Uint64:
+			return "u64", nil
+		case types.Uint8:
+			return "u8", nil
+		case types.Uint16:
+			return "u16", nil
+		case types.Uint32:
+			return "u32", nil
+		case types.Float32:
+			return "f32", nil
+		case types.Float64:
+			return "f64", nil
+		case types.String:
+			return "String", nil
+		default:
+			return "", errors.Errorf("unserializable basic type %v", t.Kind())
+		}
+	case *types.Struct:
+		return "serde_json::Value", nil
+	default:
+		return "", errors.Errorf("unserializable type %v", typ)
+	}
+}
+
+// serdeAttributes 返回字段的 serde 属性参数。
+func serdeAttributes(field common.JSONField, rustTyp string) []string {
+	attrs := []string{fmt.Sprintf("rename = %q", field.Name)}
+
+	nullable := strings.HasPrefix(rustTyp, "Vec<") || strings.HasPrefix(rustTyp, "HashMap<")
+	if nullable {
+		// Go 的 nil 切片和映射会被序列化为 null
+		attrs = append(attrs, `deserialize_with = "null_as_default"`)
+	}
+
+	if utils.IsTimeType(field.Var.Type()) {
+		// 空字符串不是合法的时间，省略后由 Go 解码为零值
+		attrs = append(attrs, `skip_serializing_if = "String::is_empty"`)
+	} else if field.OmitEmpty {
+		switch {
+		case strings.HasPrefix(rustTyp, "Option<"):
+			attrs = append(attrs, `skip_serializing_if = "Option::is_none"`)
+		case strings.HasPrefix(rustTyp, "Vec<"):
+			attrs = append(attrs, `skip_serializing_if = "Vec::is_empty"`)
+		case strings.HasPrefix(rustTyp, "HashMap<"):
+			attrs = append(attrs, `skip_serializing_if = "HashMap::is_empty"`)
+		case rustTyp == "String":
+			attrs = append(attrs, `skip_serializing_if = "String::is_empty"`)
+		default:
+			attrs = append(attrs, `skip_serializing_if = "is_default"`)
+		}
+	}
+
+	return attrs
+}
+
+func generateStruct(wr io.Writer, named *types.Named) error {
+	err := checkFlatten(named)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(wr, "\n#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]\n#[serde(default)]\npub struct %s {\n", named.Obj().Name())
+	if err != nil {
+		return err
+	}
+
+	fields := common.DeclaredJSONFields(named.Underlying().(*types.Struct))
+	for i, name := range rustFieldNames(fields) {
+		field := fields[i]
+		typ, err := rustType(field.Var.Type(), named)
+		if err != nil {
+			return errors.Wrapf(err, "field %s.%s", named.Obj().Name(), field.Var.Name())
+		}
+
+		// 嵌入结构体的字段和 encoding/json 一样展开到外层
+		attrs := serdeAttributes(field, typ)
+		if common.EmbeddedStruct(field) != nil {
+			attrs = []string{"flatten"}
+		}
+
+		_, err = fmt.Fprintf(wr, "    #[serde(%s)]\n    pub %s: %s,\n",
+			strings.Join(attrs, ", "),
+			name,
+			typ)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(wr, "}\n")
+	return err
+}
+
+// generateEnvelope 为响应类型实现 Envelope，客户端据此检查业务错误码。
+func generateEnvelope(wr io.Writer, method *domain.Method) error {
+	named, ok := method.ResponseType().(*types.Pointer).Elem().(*types.Named)
+	if !ok {
+		return errors.Errorf("response type of method %s is not a named struct", method.Func.Name())
+	}
+
+	paths := map[string]string{"code": "self.code", "message": "self.message"}
+	for _, field := range common.JSONFields(named.Underlying().(*types.Struct)) {
+		if field.Name != "code" && field.Name != "message" {
+			continue
+		}
+		path, err := rustFieldPath(named, field)
+		if err != nil {
+			return errors.Wrapf(err, "method %s", method.Func.Name())
+		}
+		paths[field.Name] = path
+	}
+
+	_, err := fmt.Fprintf(wr, `
+impl Envelope for %s {
+    fn code(&self) -> i64 {
+        %s as i64
+    }
+
+    fn message(&self) -> &str {
+        &%s
+    }
+}
+`, method.ResponseTypeName(), paths["code"], paths["message"])
+	return err
+}
+
+// eventsStruct 逐个读取 SSE 响应中的事件。
+const eventsStruct = `
+/// Server-sent events returned by event stream methods.
+pub struct Events<T> {
+    resp: reqwest::Response,
+    buf: Vec<u8>,
+    data: Vec<String>,
+    _event: std::marker::PhantomData<T>,
+}
+
+impl<T: DeserializeOwned> Events<T> {
+    /// Returns the next event, or None when the server closes the stream.
+    pub async fn next(&mut self) -> Result<Option<T>, Error> {
+        loop {
+            while let Some(pos) = self.buf.iter().position(|b| *b == b'\n') {
+                let line: Vec<u8> = self.buf.drain(..=pos).collect();
+                let line = String::from_utf8_lossy(&line);
+                let line = line.trim_end_matches(|c| c == '\r' || c == '\n');
+                if let Some(value) = line.strip_prefix("data:") {
+                    self.data.push(value.strip_prefix(' ').unwrap_or(value).to_string());
+                } else if line.is_empty() && !self.data.is_empty() {
+                    let data = self.data.join("\n");
+                    self.data.clear();
+                    return serde_json::from_str(&data).map(Some).map_err(Error::Decode);
+                }
+            }
+
+            match self.resp.chunk().await? {
+                Some(chunk) => self.buf.extend_from_slice(&chunk),
+                None => return Ok(None),
+            }
+        }
+    }
+}
+`
+
+// formValuesFn 把请求编码为表单，编码方式和 Go 客户端相同：标量和标量数组作为表单值，其他值编码为 JSON。
+// serde_urlencoded 不支持序列，所以不能直接用 .form(req)。
+const formValuesFn = `
+fn form_values<T: Serialize>(req: &T) -> Vec<(String, String)> {
+    let mut pairs = Vec::new();
+    if let Ok(serde_json::Value::Object(fields)) = serde_json::to_value(req) {
+        for (name, value) in fields {
+            let items = match value {
+                serde_json::Value::Array(items) if items.iter().all(|item| !item.is_object() && !item.is_array()) => items,
+                value => vec![value],
+            };
+            for item in items {
+                match item {
+                    serde_json::Value::Null => {}
+                    serde_json::Value::String(value) => pairs.push((name.clone(), value)),
+                    value => pairs.push((name.clone(), value.to_string())),
+                }
+            }
+        }
+    }
+    pairs
+}
+`
+
+func generateClient(wr io.Writer, service *domain.Service) error {
+	_, err := fmt.Fprintf(wr, `
+/// HTTP client of %s.
+#[derive(Debug, Clone)]
+pub struct %sClient {
+    base_url: String,
+    http: reqwest::Client,
+}
+
+impl %sClient {
+    pub fn new(base_url: impl Into<String>) -> Self {
+        Self::with_client(base_url, reqwest::Client::new())
+    }
+
+    pub fn with_client(base_url: impl Into<String>, http: reqwest::Client) -> Self {
+        let base_url = base_url.into().trim_end_matches('/').to_string();
+        Self { base_url, http }
+    }
+
+    async fn decode<T: DeserializeOwned + Envelope>(resp: reqwest::Response) -> Result<T, Error> {
+        let status = resp.status();
+        if !status.is_success() {
+            let body = resp.text().await.unwrap_or_default();
+            return Err(Error::Status { status, body });
+        }
+
+        let body: T = resp.json().await?;
+        if body.code() != 0 {
+            return Err(Error::Api {
+                code: body.code(),
+                message: body.message().to_string(),
+            });
+        }
+        Ok(body)
+    }
+`, service.Name(), service.Name(), service.Name())
+	if err != nil {
+		return err
+	}
+
+	if common.HasStream(service) {
+		_, err = io.WriteString(wr, `
+    async fn download(resp: reqwest::Response) -> Result<Vec<u8>, Error> {
+        let status = resp.status();
+        if !status.is_success() {
+            let body = resp.text().await.unwrap_or_default();
+            return Err(Error::Status { status, body });
+        }
+
+        Ok(resp.bytes().await?.to_vec())
+    }
+`)
+		if err != nil {
+			return err
+		}
+	}
+
+	if common.HasEventStream(service) {
+		_, err = io.WriteString(wr, `
+    async fn events<T: DeserializeOwned>(resp: reqwest::Response) -> Result<Events<T>, Error> {
+        let status = resp.status();
+        if !status.is_success() {
+            let body = resp.text().await.unwrap_or_default();
+            return Err(Error::Status { status, body });
+        }
+
+        Ok(Events {
+            resp,
+            buf: Vec::new(),
+            data: Vec::new(),
+            _event: std::marker::PhantomData,
+        })
+    }
+`)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, method := range service.Methods {
+		if !method.Func.Exported() {
+			continue
+		}
+
+		var (
+			httpMethod = "POST"
+			payload    = ".json(req)"
+		)
+		switch method.Annotations.HTTPMethod {
+		case http.MethodGet:
+			httpMethod, payload = "GET", ".query(req)"
+		case http.MethodDelete:
+			httpMethod, payload = "DELETE", ".query(req)"
+		case http.MethodPut:
+			httpMethod = "PUT"
+		case http.MethodPatch:
+			httpMethod = "PATCH"
+		}
+		if common.IsForm(method) && httpMethod != "GET" && httpMethod != "DELETE" {
+			payload = ".form(&form_values(req))"
+		}
+
+		if doc := method.Doc(); doc != "" {
+			for _, line := range strings.Split(doc, "\n") {
+				_, err = fmt.Fprintf(wr, "\n    /// %s", line)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// 流式响应返回下载的内容，SSE 返回事件流
+		responseType, decode := method.ResponseTypeName(), "decode"
+		if common.IsStream(method) {
+			responseType, decode = "Vec<u8>", "download"
+		} else if method.IsEventStream() {
+			responseType, decode = fmt.Sprintf("Events<%s>", responseType), "events"
+		}
+
+		_, err = fmt.Fprintf(wr, `
+    pub async fn %s(&self, req: &%s) -> Result<%s, Error> {
+        let resp = self
+            .http
+            .request(reqwest::Method::%s, format!("{}%s", self.base_url))
+            %s
+            .send()
+            .await?;
+        Self::%s(resp).await
+    }
+`,
+			rustFieldName(method.Func.Name()),
+			method.RequestTypeName(),
+			responseType,
+			httpMethod,
+			strings.NewReplacer("{", "{{", "}", "}}").Replace(method.Annotations.HTTPPath),
+			payload,
+			decode)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(wr, "}\n")
+	return err
+}
+
+// GenerateRustClient 生成基于 serde 和 reqwest 的 rust 客户端模块。
+//
+// 生成的代码依赖 serde (derive)、serde_json 和 reqwest (json, query)。不支持 multipart/form-data 请求。
+func GenerateRustClient(wr io.Writer, service *domain.Service) error {
+	common.HTTPPopulateDefaultAnnotations(service)
+
+	for _, method := range service.Methods {
+		if method.Func.Exported() && common.IsMultipart(method) {
+			return errors.Errorf("method %s: multipart/form-data request is not supported by rust client", method.Func.Name())
+		}
+	}
+
+	structs := rustStructs(service)
+	err := common.CheckTypeNames(structs)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(wr, `#![allow(dead_code, non_camel_case_types)]
+
+use std::collections::HashMap;
+use std::fmt;
+
+use serde::de::DeserializeOwned;
+use serde::{Deserialize, Deserializer, Serialize};
+
+/// Errors returned by the generated client.
+#[derive(Debug)]
+pub enum Error {
+    /// Transport or decoding failure.
+    Http(reqwest::Error),
+    /// Server responded with a non-2xx status.
+    Status {
+        status: reqwest::StatusCode,
+        body: String,
+    },
+    /// Response envelope carries a non-zero code.
+    Api { code: i64, message: String },
+    /// Server-sent event data is not valid JSON.
+    Decode(serde_json::Error),
+}
+
+impl fmt::Display for Error {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Error::Http(err) => write!(f, "http error: {}", err),
+            Error::Status { status, body } => write!(f, "unexpected status {}: {}", status, body),
+            Error::Api { code, message } => write!(f, "code {}: {}", code, message),
+            Error::Decode(err) => write!(f, "decode event: {}", err),
+        }
+    }
+}
+
+impl std::error::Error for Error {
+    fn source(&self) -> Option<&(dyn std::error::Error + 'static)> {
+        match self {
+            Error::Http(err) => Some(err),
+            Error::Decode(err) => Some(err),
+            _ => None,
+        }
+    }
+}
+
+impl From<reqwest::Error> for Error {
+    fn from(err: reqwest::Error) -> Self {
+        Error::Http(err)
+    }
+}
+
+/// Response envelope shared by all response types.
+pub trait Envelope {
+    fn code(&self) -> i64;
+    fn message(&self) -> &str;
+}
+
+fn null_as_default<'de, D, T>(deserializer: D) -> Result<T, D::Error>
+where
+    D: Deserializer<'de>,
+    T: Default + Deserialize<'de>,
+{
+    Ok(Option::<T>::deserialize(deserializer)?.unwrap_or_default())
+}
+
+fn is_default<T: Default + PartialEq>(value: &T) -> bool {
+    *value == T::default()
+}
+`)
+	if err != nil {
+		return err
+	}
+
+	for _, named := range structs {
+		err = generateStruct(wr, named)
+		if err != nil {
+			return errors.Wrapf(err, "generate rust struct for %s", named.Obj().Name())
+		}
+	}
+
+	if common.HasContentType(service, common.ContentTypeForm) {
+		_, err = io.WriteString(wr, formValuesFn)
+		if err != nil {
+			return err
+		}
+	}
+
+	if common.HasEventStream(service) {
+		_, err = io.WriteString(wr, eventsStruct)
+		if err != nil {
+			return err
+		}
+	}
+
+	responses := make(map[string]bool)
+	for _, method := range service.Methods {
+		if !method.Func.Exported() || responses[method.ResponseTypeName()] || common.IsStream(method) || method.IsEventStream() {
+			continue
+		}
+		responses[method.ResponseTypeName()] = true
+
+		err = generateEnvelope(wr, method)
+		if err != nil {
+			return err
+		}
+	}
+
+	return generateClient(wr, service)
+}
@@ -0,0 +1,92 @@
+package reqwest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nnnewb/jk/internal/domain/domaintest"
+)
+
+const shadowedSource = `package order
+
+import "context"
+
+type Base struct {
+	ID string ` + "`json:\"id\"`" + `
+}
+
+type GetRequest struct {
+	Base
+	ID int ` + "`json:\"id\"`" + `
+}
+
+type GetResponse struct {
+	Code    int    ` + "`json:\"code\"`" + `
+	Message string ` + "`json:\"message\"`" + `
+}
+
+type Service interface {
+	Get(ctx context.Context, req *GetRequest) (*GetResponse, error)
+}
+`
+
+const statusSource = `package order
+
+import "context"
+
+type Status struct {
+	Code    int    ` + "`json:\"code\"`" + `
+	Message string ` + "`json:\"message\"`" + `
+}
+
+type GetRequest struct {
+	ID string ` + "`json:\"id\"`" + `
+}
+
+type GetResponse struct {
+	Status
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type Service interface {
+	Get(ctx context.Context, req *GetRequest) (*GetResponse, error)
+}
+`
+
+func TestGenerateRustClient(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		src  string
+		want []string
+		err  string
+	}{
+		// time.Time 是字符串，嵌入结构体生成为 flatten 字段，同名的字段名加上后缀
+		{"Embedded", domaintest.EmbeddedSource, []string{
+			"pub struct Base {",
+			"    #[serde(rename = \"created_at\", skip_serializing_if = \"String::is_empty\")]\n    pub created_at: String,",
+			"    #[serde(flatten)]\n    pub base: Base,",
+			"    #[serde(flatten)]\n    pub meta: Option<Meta>,",
+			"    #[serde(rename = \"user_id\")]\n    pub user_id_2: String,",
+			"    pub deadline: Option<String>,",
+		}, ""},
+		// 响应的 code 和 message 来自嵌入结构体时经过 flatten 字段访问
+		{"EmbeddedStatus", statusSource, []string{"self.status.code as i64", "&self.status.message"}, ""},
+		// 被遮蔽的嵌入字段无法用 flatten 表示
+		{"Shadowed", shadowedSource, nil, "fields of embedded struct are shadowed"},
+		// 结构体只用类型名命名，不同包的同名类型无法区分
+		{"Collision", domaintest.CollisionSource, nil, "have the same name FileHeader"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := GenerateRustClient(&buf, domaintest.Service(t, c.src, "Service"))
+			if c.err != "" {
+				domaintest.ErrorContains(t, err, c.err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("generate failed: %+v", err)
+			}
+			domaintest.Contains(t, buf.String(), c.want...)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package fetch
 
 import (
+	"bytes"
 	"fmt"
 	"go/types"
 	"io"
@@ -11,6 +12,7 @@ import (
 	"github.com/iancoleman/strcase"
 	"github.com/nnnewb/jk/internal/domain"
 	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/utils"
 )
 
 func generateNamedInterfaceDeclaration(wr io.Writer, memo map[string]bool, named *types.Named) error {
@@ -18,7 +20,7 @@ func generateNamedInterfaceDeclaration(wr io.Writer, memo map[string]bool, named
 		return nil
 	}
 
-	if memo[named.Obj().Name()] {
+	if memo[named.Obj().Name()] || utils.IsFileType(named) || common.IsOpaqueType(named) {
 		return nil
 	}
 
@@ -38,13 +40,8 @@ func generateNamedInterfaceDeclaration(wr io.Writer, memo map[string]bool, named
 	case *types.Named:
 		return generateNamedInterfaceDeclaration(wr, memo, t)
 	case *types.Struct:
-		for i := 0; i < t.NumFields(); i++ {
-			f := t.Field(i)
-			if !f.Exported() {
-				continue
-			}
-
-			switch ft := f.Type().(type) {
+		for _, field := range common.JSONFields(t) {
+			switch ft := field.Var.Type().(type) {
 			case *types.Pointer:
 				if underlying, ok := ft.Elem().(*types.Named); ok {
 					err := generateNamedInterfaceDeclaration(wr, memo, underlying)
@@ -91,10 +88,10 @@ func generateInterfaceDeclaration(wr io.Writer, service *domain.Service) error {
 		if err != nil {
 			return err
 		}
-		if allNamedType[method.ResponseTypeName()] {
+		if allNamedType[method.ResponseTypeName()] || common.IsStream(method) {
 			continue
 		}
-		err = generateNamedInterfaceDeclaration(wr, allNamedType, method.ResponseType().(*types.Pointer).Elem().(*types.Named))
+		err = generateNamedInterfaceDeclaration(wr, allNamedType, method.ResponseNamed())
 		if err != nil {
 			return err
 		}
@@ -112,29 +109,234 @@ func generateAjaxTypescript(wr io.Writer) error {
 	return nil
 }
 
-func generateAPIPathTypescript(wr io.Writer, method *domain.Method) error {
+// generateFormTypescript 生成把请求转换为 URLSearchParams 的函数，和 gorilla/schema 一样，数组的每个元素是一个同名参数。
+func generateFormTypescript(wr io.Writer) error {
+	_, err := fmt.Fprint(wr, `
+function formURLEncoded(payload: object): URLSearchParams {
+	const params = new URLSearchParams();
+	for (const [name, value] of Object.entries(payload)) {
+		if (value === undefined || value === null) {
+			continue;
+		}
+		for (const item of Array.isArray(value) ? value : [value]) {
+			params.append(name, String(item));
+		}
+	}
+	return params;
+}
+`)
+	return err
+}
+
+// generateMultipartTypescript 生成把请求转换为 FormData 的函数，编码方式和 jkhttp.EncodeMultipartRequest 相同：
+// Blob 作为文件，标量和标量数组作为表单值，其他值编码为 JSON。
+func generateMultipartTypescript(wr io.Writer) error {
+	_, err := fmt.Fprint(wr, `
+function multipartFormData(payload: object): FormData {
+	const form = new FormData();
+	for (const [name, value] of Object.entries(payload)) {
+		if (value === undefined || value === null) {
+			continue;
+		}
+		const items = Array.isArray(value) && value.every(item => item instanceof Blob || typeof item !== "object") ? value : [value];
+		for (const item of items) {
+			if (item instanceof Blob) {
+				form.append(name, item);
+			} else if (typeof item === "object") {
+				form.append(name, JSON.stringify(item));
+			} else {
+				form.append(name, String(item));
+			}
+		}
+	}
+	return form;
+}
+`)
+	return err
+}
+
+// generateEventStreamTypescript 生成读取 text/event-stream 响应的异步迭代器，每个事件的 data 按 JSON 解析，
+// 忽略心跳等注释行。调用方提前结束迭代时取消读取，关闭连接。
+func generateEventStreamTypescript(wr io.Writer) error {
+	_, err := fmt.Fprint(wr, `
+async function* eventStream<T>(resp: Response): AsyncGenerator<T> {
+	if (!resp.ok || resp.body === null) {
+		throw new Error(`+"`unexpected event stream response status ${resp.status}`"+`);
+	}
+	const reader = resp.body.pipeThrough(new TextDecoderStream()).getReader();
+	let buffer = "";
+	let data: string[] = [];
+	try {
+		for (;;) {
+			const { value, done } = await reader.read();
+			if (done) {
+				return;
+			}
+			buffer += value;
+			let index: number;
+			while ((index = buffer.indexOf("\n")) >= 0) {
+				const line = buffer.slice(0, index).replace(/\r$/, "");
+				buffer = buffer.slice(index + 1);
+				if (line === "" && data.length > 0) {
+					yield JSON.parse(data.join("\n")) as T;
+					data = [];
+				} else if (line.startsWith("data:")) {
+					data.push(line.slice(5).replace(/^ /, ""));
+				}
+			}
+		}
+	} finally {
+		await reader.cancel();
+	}
+}
+`)
+	return err
+}
+
+// generateAuthTypescript 生成需要认证的方法使用的 authorize，调用 tokenSource 获取凭据并按认证方式设置请求头。
+func generateAuthTypescript(wr io.Writer) error {
+	_, err := fmt.Fprint(wr, `
+interface Credentials {
+	token?: string;
+	username?: string;
+	password?: string;
+}
+
+type TokenSource = (scheme: "bearer" | "basic" | "apikey") => Credentials | Promise<Credentials>;
+
+async function authorize(init: RequestInit, scheme: "bearer" | "basic" | "apikey", tokenSource: TokenSource | null): Promise<void> {
+	if (tokenSource === null) {
+		return;
+	}
+	const cred = await tokenSource(scheme);
+	const headers = new Headers(init.headers);
+	switch (scheme) {
+	case "bearer":
+		headers.set("Authorization", "Bearer " + (cred.token ?? ""));
+		break;
+	case "basic": {
+		const bytes = new TextEncoder().encode((cred.username ?? "") + ":" + (cred.password ?? ""));
+		headers.set("Authorization", "Basic " + btoa(Array.from(bytes, b => String.fromCharCode(b)).join("")));
+		break;
+	}
+	case "apikey":
+		headers.set("X-API-Key", cred.token ?? "");
+		break;
+	}
+	init.headers = headers;
+}
+`)
+	return err
+}
+
+// generateIdempotentTypescript 生成 @idempotent 方法使用的 fetchIdempotent，为一次调用生成幂等键，
+// 网络错误和 5xx 响应时用同一个幂等键重试，服务端会重放已经成功的响应而不是重复处理。
+func generateIdempotentTypescript(wr io.Writer) error {
+	_, err := fmt.Fprint(wr, `
+async function fetchIdempotent(u: URL, init: RequestInit, retries: number): Promise<Response> {
+	const headers = new Headers(init.headers);
+	if (!headers.has("Idempotency-Key")) {
+		headers.set("Idempotency-Key", crypto.randomUUID());
+	}
+	init = { ...init, headers };
+	for (let attempt = 0; ; attempt++) {
+		try {
+			const resp = await fetch(new Request(u, init), init);
+			if (resp.status < 500 || attempt >= retries) {
+				return resp;
+			}
+		} catch (e) {
+			if (attempt >= retries || init.signal?.aborted) {
+				throw e;
+			}
+		}
+		await new Promise(resolve => setTimeout(resolve, 100 * 2 ** attempt));
+	}
+}
+`)
+	return err
+}
+
+func generateAPIPathTypescript(wr io.Writer, service *domain.Service, method *domain.Method, withZod bool) error {
 	var initPayload string
 	switch method.Annotations.HTTPMethod {
 	case http.MethodGet, http.MethodDelete:
 		initPayload = `Object.getOwnPropertyNames(payload).map(prop => u.searchParams.append(prop, payload[prop]));`
 	case http.MethodPost, http.MethodPut, http.MethodPatch:
 		initPayload = `init.body = JSON.stringify(payload);`
+		switch {
+		case common.IsMultipart(method):
+			initPayload = `init.body = multipartFormData(payload);`
+		case common.IsForm(method):
+			initPayload = `init.body = formURLEncoded(payload);`
+		}
+	}
+
+	function := "async function"
+	responseType := fmt.Sprintf("Promise<%s>", method.ResponseTypeName())
+	returnResponse := `return await resp.json();`
+	switch {
+	case method.IsEventStream() && withZod:
+		function = "async function*"
+		responseType = fmt.Sprintf("AsyncGenerator<%s>", method.ResponseTypeName())
+		returnResponse = fmt.Sprintf(`for await (const data of eventStream<unknown>(resp)) {
+			yield this.validateResponse ? parseResponse<%s>("%s.%s", %sSchema, data) : data as %s;
+		}`,
+			method.ResponseTypeName(),
+			service.Name(),
+			method.Func.Name(),
+			method.ResponseTypeName(),
+			method.ResponseTypeName(),
+		)
+	case method.IsEventStream():
+		// SSE 方法返回事件的异步迭代器
+		function = "async function*"
+		responseType = fmt.Sprintf("AsyncGenerator<%s>", method.ResponseTypeName())
+		returnResponse = fmt.Sprintf(`yield* eventStream<%s>(resp);`, method.ResponseTypeName())
+	case common.IsStream(method):
+		// 流式响应作为文件下载
+		responseType = "Promise<Blob>"
+		returnResponse = `return await resp.blob();`
+	case withZod:
+		returnResponse = fmt.Sprintf(`const data = await resp.json();
+		if (this.validateResponse) {
+			return parseResponse<%s>("%s.%s", %sSchema, data);
+		}
+		return data;`,
+			method.ResponseTypeName(),
+			service.Name(),
+			method.Func.Name(),
+			method.ResponseTypeName(),
+		)
+	}
+
+	doFetch := `const req = new Request(u, init);
+		const resp = await fetch(req, init);`
+	if method.Annotations.Idempotent {
+		doFetch = `const resp = await fetchIdempotent(u, init, this.idempotentRetries);`
 	}
+	if scheme := method.AuthScheme(); scheme != "" {
+		doFetch = fmt.Sprintf(`await authorize(init, "%s", this.tokenSource);
+		%s`, scheme, doFetch)
+	}
+
 	_, err := fmt.Fprintf(wr, `
-	%s: async function(payload: %s, init?: RequestInit): Promise<%s> {
+	%s: %s(payload: %s, init?: RequestInit): %s {
 		const u = new URL("%s", this.baseURL);
 		%s
 		init.method = "%s";
-		const req = new Request(u, init);
-		const resp = await fetch(req, init);
-		return await resp.json();
+		%s
+		%s
 	},`,
 		strcase.ToSnake(method.Func.Name()),
+		function,
 		method.RequestTypeName(),
-		method.ResponseTypeName(),
+		responseType,
 		method.Annotations.HTTPPath,
 		initPayload,
 		method.Annotations.HTTPMethod,
+		doFetch,
+		returnResponse,
 	)
 	if err != nil {
 		return err
@@ -142,9 +344,62 @@ func generateAPIPathTypescript(wr io.Writer, method *domain.Method) error {
 	return nil
 }
 
+// generatePaginatorTypescript 为 @paginated 方法生成 xxx_iter 异步迭代器，按需逐页请求并逐个产出数据。
+func generatePaginatorTypescript(wr io.Writer, method *domain.Method) error {
+	names, ok := common.Pagination(method)
+	if !ok {
+		return nil
+	}
+
+	var item bytes.Buffer
+	err := generateTypescriptSchema(&item, method.Pagination.ItemType(), 1)
+	if err != nil {
+		return err
+	}
+
+	name := strcase.ToSnake(method.Func.Name())
+	_, err = fmt.Fprintf(wr, `
+	%s_iter: async function*(payload: %s, init?: RequestInit): AsyncGenerator<%s> {
+		let page = { ...payload };
+		for (;;) {
+			const resp = await this.%s(page, init);
+			yield* (resp.%s || []);
+			if (!resp.%s) {
+				return;
+			}
+			page = { ...page, %s: resp.%s };
+		}
+	},`,
+		name,
+		method.RequestTypeName(),
+		item.String(),
+		name,
+		names.Items,
+		names.NextPageToken,
+		names.PageToken,
+		names.NextPageToken,
+	)
+	return err
+}
+
 func generateTypescriptSchema(wr io.Writer, typ types.Type, depth int) error {
 	switch t := typ.(type) {
 	case *types.Named:
+		if utils.IsFileType(t) {
+			_, err := io.WriteString(wr, "Blob")
+			return err
+		}
+		if utils.IsJSONMarshaler(t) && !utils.IsTimeType(t) {
+			// MarshalJSON 决定的格式无法得知
+			_, err := io.WriteString(wr, "any")
+			return err
+		}
+		if common.IsOpaqueType(t) {
+			// time.Time 和 encoding.TextMarshaler 编码为字符串
+			_, err := io.WriteString(wr, "string")
+			return err
+		}
+
 		if depth == 0 {
 			_, err := fmt.Fprintf(wr, "interface %s {\n", t.Obj().Name())
 			if err != nil {
@@ -244,39 +499,30 @@ func generateTypescriptSchema(wr io.Writer, typ types.Type, depth int) error {
 			panic(errors.Errorf("unserializable basic type %v", t.Kind()))
 		}
 	case *types.Struct:
-		// Iterate over all the fields of the struct
-		for i := 0; i < t.NumFields(); i++ {
-			field := t.Field(i)
-			if !field.Exported() {
-				continue
-			}
-
-			var (
-				jsonName string
-				ok       bool
-			)
-			if jsonName, ok = common.GetJsonName(t.Tag(i)); !ok {
-				jsonName = field.Name()
-			} else if jsonName == "-" {
-				continue
+		// 和 encoding/json 一样展开嵌入结构体的字段
+		for _, field := range common.JSONFields(t) {
+			_, err := fmt.Fprintf(wr, "%s%s: ", strings.Repeat(" ", 2), field.Name)
+			if err != nil {
+				return err
 			}
 
-			// Check if the field is exported
-			if field.Exported() {
-				_, err := fmt.Fprintf(wr, "%s%s: ", strings.Repeat(" ", 2), jsonName)
+			if isFile, multiple := common.FilePart(field); isFile && !multiple {
+				// 带有 jk:"file" 标签的 []byte 作为文件上传
+				_, err = io.WriteString(wr, "Blob;\n")
 				if err != nil {
 					return err
 				}
+				continue
+			}
 
-				err = generateTypescriptSchema(wr, field.Type(), depth+1)
-				if err != nil {
-					return err
-				}
+			err = generateTypescriptSchema(wr, field.Var.Type(), depth+1)
+			if err != nil {
+				return err
+			}
 
-				_, err = io.WriteString(wr, ";\n")
-				if err != nil {
-					return err
-				}
+			_, err = io.WriteString(wr, ";\n")
+			if err != nil {
+				return err
 			}
 		}
 	default:
@@ -285,29 +531,102 @@ func generateTypescriptSchema(wr io.Writer, typ types.Type, depth int) error {
 	return nil
 }
 
-func GenerateTypeScriptClient(wr io.Writer, service *domain.Service) error {
+// GenerateTypeScriptClient 生成 typescript 客户端，withZod 为 true 时额外生成 zod schema 并支持校验响应。
+func GenerateTypeScriptClient(wr io.Writer, service *domain.Service, withZod bool) error {
 	common.HTTPPopulateDefaultAnnotations(service)
+	// 接口和 zod schema 都只用类型名命名
+	if err := common.CheckTypeNames(common.NamedStructs(service)); err != nil {
+		return err
+	}
+	if withZod {
+		err := generateZodSchemas(wr, service)
+		if err != nil {
+			return err
+		}
+	}
+
 	err := generateInterfaceDeclaration(wr, service)
 	if err != nil {
 		return err
 	}
 
+	if common.HasContentType(service, common.ContentTypeForm) {
+		err = generateFormTypescript(wr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if common.HasContentType(service, common.ContentTypeMultipart) {
+		err = generateMultipartTypescript(wr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if common.HasEventStream(service) {
+		err = generateEventStreamTypescript(wr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if common.HasAuth(service) {
+		err = generateAuthTypescript(wr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if common.HasIdempotent(service) {
+		err = generateIdempotentTypescript(wr)
+		if err != nil {
+			return err
+		}
+	}
+
 	_, err = io.WriteString(wr, `
 export default {
 	baseURL: "",
 `)
-
 	if err != nil {
 		return err
 	}
 
+	if common.HasAuth(service) {
+		// 需要认证的方法从 tokenSource 获取凭据，为 null 时不设置认证请求头
+		_, err = io.WriteString(wr, "\ttokenSource: null as TokenSource | null,\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	if common.HasIdempotent(service) {
+		// @idempotent 方法网络错误和 5xx 响应时的重试次数
+		_, err = io.WriteString(wr, "\tidempotentRetries: 2,\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	if withZod {
+		_, err = io.WriteString(wr, "\tvalidateResponse: false,\n")
+		if err != nil {
+			return err
+		}
+	}
+
 	err = generateAjaxTypescript(wr)
 	if err != nil {
 		return err
 	}
 
 	for _, method := range service.Methods {
-		err := generateAPIPathTypescript(wr, method)
+		err := generateAPIPathTypescript(wr, service, method, withZod)
+		if err != nil {
+			return err
+		}
+		err = generatePaginatorTypescript(wr, method)
 		if err != nil {
 			return err
 		}
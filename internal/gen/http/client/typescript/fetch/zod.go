@@ -0,0 +1,181 @@
+package fetch
+
+import (
+	"fmt"
+	"go/types"
+	"io"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+// zodSchemaName 返回命名结构体对应的 zod schema 常量名。
+func zodSchemaName(named *types.Named) string {
+	return named.Obj().Name() + "Schema"
+}
+
+// zodWalker 输出 zod schema，引用尚未声明的 schema 时使用 z.lazy 延迟求值。
+type zodWalker struct {
+	wr       io.Writer
+	declared map[*types.TypeName]bool
+}
+
+func (w *zodWalker) declare(named *types.Named) error {
+	_, err := fmt.Fprintf(w.wr, "export const %s = z.object({\n", zodSchemaName(named))
+	if err != nil {
+		return err
+	}
+
+	for _, field := range common.JSONFields(named.Underlying().(*types.Struct)) {
+		expr, err := w.fieldExpr(field)
+		if err != nil {
+			return errors.Wrapf(err, "field %s.%s", named.Obj().Name(), field.Var.Name())
+		}
+		if field.Optional() {
+			expr += ".optional()"
+		}
+		_, err = fmt.Fprintf(w.wr, "  %q: %s,\n", field.Name, expr)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w.wr, "});\n")
+	if err != nil {
+		return err
+	}
+
+	w.declared[named.Obj()] = true
+	return nil
+}
+
+// fieldExpr 返回结构体字段对应的 zod 表达式，带有 jk:"file" 标签的 []byte 是上传的文件。
+func (w *zodWalker) fieldExpr(field common.JSONField) (string, error) {
+	if isFile, multiple := common.FilePart(field); isFile && !multiple {
+		return "z.instanceof(Blob).nullable()", nil
+	}
+	return w.expr(field.Var.Type())
+}
+
+// expr 返回类型 typ 对应的 zod 表达式。Go 的 nil 指针、切片和映射都会被序列化为 null，所以它们都是 nullable。
+func (w *zodWalker) expr(typ types.Type) (string, error) {
+	switch t := typ.(type) {
+	case *types.Named:
+		switch {
+		case utils.IsFileType(t):
+			return "z.instanceof(Blob)", nil
+		case utils.IsTimeType(t):
+			// encoding/json 把 time.Time 编码为带时区偏移的 RFC 3339 字符串
+			return "z.string().datetime({ offset: true })", nil
+		case utils.IsJSONMarshaler(t):
+			// MarshalJSON 决定的格式无法得知
+			return "z.any()", nil
+		case utils.IsTextMarshaler(t):
+			return "z.string()", nil
+		}
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			if !w.declared[t.Obj()] {
+				// 递归引用，schema 常量此时尚未初始化
+				return fmt.Sprintf("z.lazy(() => %s)", zodSchemaName(t)), nil
+			}
+			return zodSchemaName(t), nil
+		}
+		return w.expr(t.Underlying())
+	case *types.Pointer:
+		elem, err := w.expr(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return elem + ".nullable()", nil
+	case *types.Array:
+		elem, err := w.expr(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("z.array(%s)", elem), nil
+	case *types.Slice:
+		if b, ok := t.Elem().(*types.Basic); ok && b.Kind() == types.Uint8 {
+			// encoding/json 把 []byte 编码为 base64 字符串
+			return "z.string().nullable()", nil
+		}
+		elem, err := w.expr(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("z.array(%s).nullable()", elem), nil
+	case *types.Map:
+		elem, err := w.expr(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("z.record(z.string(), %s).nullable()", elem), nil
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool:
+			return "z.boolean()", nil
+		case types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+			types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+			return "z.number().int()", nil
+		case types.Float32, types.Float64:
+			return "z.number()", nil
+		case types.String:
+			return "z.string()", nil
+		default:
+			return "", errors.Errorf("unserializable basic type %v", t.Kind())
+		}
+	case *types.Struct:
+		fields := make([]string, 0, t.NumFields())
+		for _, field := range common.JSONFields(t) {
+			expr, err := w.fieldExpr(field)
+			if err != nil {
+				return "", err
+			}
+			if field.Optional() {
+				expr += ".optional()"
+			}
+			fields = append(fields, fmt.Sprintf("%q: %s", field.Name, expr))
+		}
+		return fmt.Sprintf("z.object({ %s })", strings.Join(fields, ", ")), nil
+	default:
+		return "", errors.Errorf("unserializable type %v", typ)
+	}
+}
+
+// generateZodSchemas 为服务方法可达的全部请求、响应类型生成 zod schema。
+func generateZodSchemas(wr io.Writer, service *domain.Service) error {
+	_, err := io.WriteString(wr, "import { z } from \"zod\";\n\n")
+	if err != nil {
+		return err
+	}
+
+	w := &zodWalker{wr: wr, declared: make(map[*types.TypeName]bool)}
+	for _, named := range common.NamedStructs(service) {
+		err := w.declare(named)
+		if err != nil {
+			return errors.Wrapf(err, "generate zod schema for %s", named.Obj().Name())
+		}
+	}
+
+	_, err = fmt.Fprint(wr, `
+export class ResponseValidationError extends Error {
+	constructor(public readonly method: string, public readonly path: string, public readonly issues: z.ZodIssue[]) {
+		super(`+"`${method}: invalid response at ${path}: ${issues[0].message}`"+`);
+		this.name = "ResponseValidationError";
+	}
+}
+
+function parseResponse<T>(method: string, schema: z.ZodTypeAny, data: unknown): T {
+	const result = schema.safeParse(data);
+	if (!result.success) {
+		const issue = result.error.issues[0];
+		const path = "$" + issue.path.map(p => typeof p === "number" ? `+"`[${p}]`"+` : `+"`.${p}`"+`).join("");
+		throw new ResponseValidationError(method, path, result.error.issues);
+	}
+	return result.data as T;
+}
+`)
+	return err
+}
@@ -0,0 +1,42 @@
+package fetch
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nnnewb/jk/internal/domain/domaintest"
+)
+
+func TestGenerateZodSchemas(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		src  string
+		want []string
+		err  string
+	}{
+		// time.Time 是带时区的 RFC 3339 字符串，嵌入结构体的字段提升到外层，嵌入指针中的字段可选
+		{"Embedded", domaintest.EmbeddedSource, []string{
+			`"tenant": z.string(),`,
+			`"created_at": z.string().datetime({ offset: true }),`,
+			`"source": z.string().optional(),`,
+			`"userID": z.string(),`,
+			`"user_id": z.string(),`,
+			`"deadline": z.string().datetime({ offset: true }).nullable().optional(),`,
+		}, ""},
+		// schema 只用类型名命名，不同包的同名类型无法区分
+		{"Collision", domaintest.CollisionSource, nil, "have the same name FileHeader"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := GenerateTypeScriptClient(&buf, domaintest.Service(t, c.src, "Service"), true)
+			if c.err != "" {
+				domaintest.ErrorContains(t, err, c.err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("generate failed: %+v", err)
+			}
+			domaintest.Contains(t, buf.String(), c.want...)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"github.com/dave/jennifer/jen"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+// authSchemeConsts 是认证方式在 jkhttp 中对应的常量名。
+var authSchemeConsts = map[string]string{
+	"bearer": "AuthBearer",
+	"basic":  "AuthBasic",
+	"apikey": "AuthAPIKey",
+}
+
+// AuthSchemeJen 返回认证方式对应的 jkhttp 常量。
+func AuthSchemeJen(scheme string) *jen.Statement {
+	return jen.Qual(utils.FilePackage, authSchemeConsts[scheme])
+}
+
+// AuthSchemes 按 bearer、basic、apikey 的顺序返回服务中方法用到的认证方式。
+func AuthSchemes(service *domain.Service) []string {
+	used := make(map[string]bool)
+	for _, method := range service.Methods {
+		if method.Func.Exported() && method.AuthScheme() != "" {
+			used[method.AuthScheme()] = true
+		}
+	}
+	var ret []string
+	for _, scheme := range []string{"bearer", "basic", "apikey"} {
+		if used[scheme] {
+			ret = append(ret, scheme)
+		}
+	}
+	return ret
+}
+
+// HasAuth 判断服务是否有需要认证的方法。
+func HasAuth(service *domain.Service) bool {
+	return len(AuthSchemes(service)) > 0
+}
@@ -2,13 +2,17 @@ package common
 
 import (
 	"fmt"
+	"go/types"
+	"log"
 	"net/http"
 	"path"
 	"reflect"
 	"strings"
 
+	"emperror.dev/errors"
 	"github.com/iancoleman/strcase"
 	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/utils"
 )
 
 func HTTPPopulateDefaultAnnotations(service *domain.Service) {
@@ -36,6 +40,16 @@ func HTTPPopulateDefaultAnnotations(service *domain.Service) {
 			method.Annotations.HTTPMethod = http.MethodPost
 		}
 
+		contentType := strings.ToLower(strings.TrimSpace(method.Annotations.HTTPContentType))
+		if alias, ok := contentTypeAliases[contentType]; ok {
+			contentType = alias
+		}
+		if contentType != "" && (method.Annotations.HTTPMethod == http.MethodGet || method.Annotations.HTTPMethod == http.MethodDelete) {
+			log.Printf("%s request of method %s has no body, ignore http-content-type %s", method.Annotations.HTTPMethod, method.Func.Name(), contentType)
+			contentType = ""
+		}
+		method.Annotations.HTTPContentType = contentType
+
 		if method.Annotations.HTTPPath == "" {
 			method.Annotations.HTTPPath = path.Join(service.Annotations.HTTPBasePath, strcase.ToKebab(method.Func.Name()))
 		}
@@ -62,3 +76,238 @@ func GetJsonName(tag string) (string, bool) {
 
 	return jsonName, true
 }
+
+// JSONField 是 encoding/json 序列化的结构体字段。
+type JSONField struct {
+	Var       *types.Var
+	Tag       string
+	Name      string       // 序列化的名称，来自 json 标签或字段名
+	OmitEmpty bool         // json 标签带有 omitempty
+	Path      []*types.Var // 字段被提升时经过的嵌入字段，从外到内，直接声明的字段为空
+}
+
+// Optional 判断字段是否可能不出现在 encoding/json 的输出中，即带有 omitempty，或者位于嵌入的结构体指针中，
+// 嵌入指针为 nil 时其中的字段都不输出。
+func (f JSONField) Optional() bool {
+	if f.OmitEmpty {
+		return true
+	}
+	for _, embedded := range f.Path {
+		if _, ok := embedded.Type().(*types.Pointer); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonField 按 encoding/json 的规则解析结构体 t 的第 i 个字段，不序列化的字段返回 false。
+func jsonField(t *types.Struct, i int) (JSONField, bool) {
+	field := t.Field(i)
+	jsonTag := reflect.StructTag(t.Tag(i)).Get("json")
+	if jsonTag == "-" {
+		return JSONField{}, false
+	}
+
+	item := JSONField{Var: field, Tag: t.Tag(i), Name: field.Name()}
+	if name := jsonTagName(item.Tag); name != "" {
+		item.Name = name
+	}
+	for _, opt := range strings.Split(jsonTag, ",")[1:] {
+		if opt == "omitempty" {
+			item.OmitEmpty = true
+		}
+	}
+	// 未导出的嵌入结构体的字段仍然会被提升
+	if !field.Exported() && EmbeddedStruct(item) == nil {
+		return JSONField{}, false
+	}
+	return item, true
+}
+
+// jsonTagName 返回 json 标签中的名称，没有时返回空字符串。
+func jsonTagName(tag string) string {
+	return strings.TrimSpace(strings.Split(reflect.StructTag(tag).Get("json"), ",")[0])
+}
+
+// EmbeddedStruct 返回字段被 encoding/json 提升到外层的嵌入结构体，
+// 即没有 json 名称的嵌入结构体或结构体指针，其他字段返回 nil。
+func EmbeddedStruct(field JSONField) *types.Struct {
+	if !field.Var.Anonymous() {
+		return nil
+	}
+	if jsonTagName(field.Tag) != "" {
+		return nil
+	}
+	typ := field.Var.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	structType, _ := typ.Underlying().(*types.Struct)
+	return structType
+}
+
+// DeclaredJSONFields 按声明顺序返回 t 中直接声明的序列化字段，嵌入结构体不展开，用 EmbeddedStruct 判断。
+func DeclaredJSONFields(t *types.Struct) []JSONField {
+	ret := make([]JSONField, 0, t.NumFields())
+	for i := 0; i < t.NumFields(); i++ {
+		if field, ok := jsonField(t, i); ok {
+			ret = append(ret, field)
+		}
+	}
+	return ret
+}
+
+// jsonFieldCandidate 是 JSONFields 展开嵌入结构体得到的字段，同名字段按 dominantField 取舍。
+type jsonFieldCandidate struct {
+	field  JSONField
+	tagged bool // 带有 json 名称
+}
+
+// dominantField 按 encoding/json 的规则从同名字段中选出序列化的字段，无法区分时返回 nil。
+func dominantField(candidates []*jsonFieldCandidate) *jsonFieldCandidate {
+	var ret []*jsonFieldCandidate
+	for _, c := range candidates {
+		switch {
+		case len(ret) == 0 || len(c.field.Path) < len(ret[0].field.Path):
+			ret = []*jsonFieldCandidate{c}
+		case len(c.field.Path) == len(ret[0].field.Path):
+			ret = append(ret, c)
+		}
+	}
+
+	var tagged []*jsonFieldCandidate
+	for _, c := range ret {
+		if c.tagged {
+			tagged = append(tagged, c)
+		}
+	}
+	switch {
+	case len(ret) == 1:
+		return ret[0]
+	case len(tagged) == 1:
+		return tagged[0]
+	default:
+		return nil
+	}
+}
+
+// JSONFields 返回 t 中 encoding/json 序列化的字段，嵌入结构体的字段和 encoding/json 一样提升到外层。
+//
+// 同名字段中嵌入层次最浅的字段胜出，层次相同时带有 json 名称的字段胜出，仍然无法区分时都不序列化。
+// 返回的字段按声明顺序排列，嵌入结构体的字段位于嵌入字段的位置。
+func JSONFields(t *types.Struct) []JSONField {
+	var (
+		candidates []*jsonFieldCandidate
+		visiting   = make(map[*types.Struct]bool)
+		walk       func(t *types.Struct, path []*types.Var)
+	)
+	walk = func(t *types.Struct, path []*types.Var) {
+		visiting[t] = true
+		defer delete(visiting, t)
+		for _, field := range DeclaredJSONFields(t) {
+			if embedded := EmbeddedStruct(field); embedded != nil {
+				if !visiting[embedded] {
+					walk(embedded, append(path[:len(path):len(path)], field.Var))
+				}
+				continue
+			}
+			field.Path = path
+			candidates = append(candidates, &jsonFieldCandidate{field: field, tagged: jsonTagName(field.Tag) != ""})
+		}
+	}
+	walk(t, nil)
+
+	byName := make(map[string][]*jsonFieldCandidate)
+	for _, c := range candidates {
+		byName[c.field.Name] = append(byName[c.field.Name], c)
+	}
+	ret := make([]JSONField, 0, len(candidates))
+	for _, c := range candidates {
+		if dominantField(byName[c.field.Name]) == c {
+			ret = append(ret, c.field)
+		}
+	}
+	return ret
+}
+
+// IsOpaqueType 判断 t 是否是 JSON 格式不由字段决定的类型，即 time.Time 和实现了 json.Marshaler 或
+// encoding.TextMarshaler 的类型，客户端不为它们生成结构体。
+func IsOpaqueType(t types.Type) bool {
+	return utils.IsTimeType(t) || utils.IsJSONMarshaler(t) || utils.IsTextMarshaler(t)
+}
+
+// CheckTypeNames 检查 named 中是否有来自不同包的同名类型，客户端只用类型名命名生成的类型，同名时无法区分。
+func CheckTypeNames(named []*types.Named) error {
+	seen := make(map[string]*types.Named)
+	for _, t := range named {
+		if other, ok := seen[t.Obj().Name()]; ok && other.Obj() != t.Obj() {
+			return errors.Errorf("types %s and %s have the same name %s, rename one of them", other, t, t.Obj().Name())
+		}
+		seen[t.Obj().Name()] = t
+	}
+	return nil
+}
+
+// NamedStructs 返回导出方法的请求和响应类型中出现的具名结构体，被依赖的类型排在前面，循环引用时除外。
+// 嵌入结构体的字段已经由 JSONFields 提升，嵌入结构体本身不包含在内。
+func NamedStructs(service *domain.Service) []*types.Named {
+	var (
+		ret      []*types.Named
+		visiting = make(map[*types.TypeName]bool)
+		done     = make(map[*types.TypeName]bool)
+		walk     func(typ types.Type)
+	)
+
+	walk = func(typ types.Type) {
+		switch t := typ.(type) {
+		case *types.Named:
+			if utils.IsFileType(t) || IsOpaqueType(t) {
+				return
+			}
+
+			structType, ok := t.Underlying().(*types.Struct)
+			if !ok {
+				walk(t.Underlying())
+				return
+			}
+
+			if visiting[t.Obj()] || done[t.Obj()] {
+				return
+			}
+
+			visiting[t.Obj()] = true
+			for _, field := range JSONFields(structType) {
+				walk(field.Var.Type())
+			}
+			delete(visiting, t.Obj())
+			done[t.Obj()] = true
+			ret = append(ret, t)
+		case *types.Pointer:
+			walk(t.Elem())
+		case *types.Array:
+			walk(t.Elem())
+		case *types.Slice:
+			walk(t.Elem())
+		case *types.Map:
+			walk(t.Elem())
+		case *types.Chan:
+			walk(t.Elem())
+		case *types.Struct:
+			for _, field := range JSONFields(t) {
+				walk(field.Var.Type())
+			}
+		}
+	}
+
+	for _, method := range service.Methods {
+		if !method.Func.Exported() {
+			continue
+		}
+		walk(method.RequestType())
+		if !IsStream(method) {
+			walk(method.ResponseType())
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,101 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nnnewb/jk/internal/domain/domaintest"
+)
+
+const jsonFieldsSource = `package order
+
+import "time"
+
+type Base struct {
+	Tenant    string    ` + "`json:\"tenant\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\"`" + `
+	ID        string    ` + "`json:\"id\"`" + `
+}
+
+type audit struct {
+	Operator string ` + "`json:\"operator\"`" + `
+}
+
+type Extra struct {
+	Note string
+}
+
+type Left struct {
+	Name string
+}
+
+type Right struct {
+	Name string
+}
+
+type Tagged struct {
+	Name string ` + "`json:\"Name\"`" + `
+}
+
+type Tags []string
+
+type Plain struct {
+	ID   string ` + "`json:\"id\"`" + `
+	Base
+	Skip string ` + "`json:\"-\"`" + `
+}
+
+type Pointer struct {
+	*Base
+	audit
+	Extra ` + "`json:\"extra\"`" + `
+	Tags
+}
+
+type Ambiguous struct {
+	Left
+	Right
+}
+
+type Dominant struct {
+	Left
+	Tagged
+}
+`
+
+func TestJSONFields(t *testing.T) {
+	type field struct {
+		Name string
+		Path []string
+	}
+	for _, c := range []struct {
+		typ  string
+		want []field
+	}{
+		// 外层字段胜过嵌入结构体的同名字段
+		{"Plain", []field{{"id", nil}, {"tenant", []string{"Base"}}, {"created_at", []string{"Base"}}}},
+		// 嵌入指针和未导出的结构体同样展开，有 json 名称的嵌入结构体和非结构体类型是普通字段
+		{"Pointer", []field{
+			{"tenant", []string{"Base"}}, {"created_at", []string{"Base"}}, {"id", []string{"Base"}},
+			{"operator", []string{"audit"}}, {"extra", nil}, {"Tags", nil},
+		}},
+		// 同一层次无法区分的同名字段都不序列化
+		{"Ambiguous", []field{}},
+		// 同一层次带有 json 名称的字段胜出
+		{"Dominant", []field{{"Name", []string{"Tagged"}}}},
+	} {
+		t.Run(c.typ, func(t *testing.T) {
+			got := []field{}
+			for _, f := range JSONFields(domaintest.Struct(t, jsonFieldsSource, c.typ)) {
+				var path []string
+				for _, embedded := range f.Path {
+					path = append(path, embedded.Name())
+				}
+				got = append(got, field{f.Name, path})
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("JSONFields(%s) = %v, want %v", c.typ, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"go/types"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+const (
+	// ContentTypeJSON 是默认的请求体和响应格式。
+	ContentTypeJSON = "application/json"
+	// ContentTypeMultipart 是 @http-content-type 注解的取值，方法的请求以 multipart/form-data 编码。
+	ContentTypeMultipart = "multipart/form-data"
+	// ContentTypeForm 是 @http-content-type 注解的取值，方法的请求以 application/x-www-form-urlencoded 编码，
+	// 编码方式和查询字符串相同，只支持标量、标量指针和标量切片字段。
+	ContentTypeForm = "application/x-www-form-urlencoded"
+)
+
+// contentTypeAliases 是 @http-content-type 注解取值的简写。
+var contentTypeAliases = map[string]string{
+	"multipart": ContentTypeMultipart,
+	"form":      ContentTypeForm,
+}
+
+// codecAliases 是 @http-codecs 注解取值的简写。
+var codecAliases = map[string]string{
+	"json":    ContentTypeJSON,
+	"msgpack": "application/msgpack",
+	"cbor":    "application/cbor",
+}
+
+// Codecs 返回服务支持的请求体和响应格式，第一个总是 JSON，其余来自以逗号或空格分隔的 @http-codecs 注解。
+// 注解不为空时，生成的代码按 Accept 和 Content-Type 请求头选择 jkhttp 中注册的 Codec。
+func Codecs(service *domain.Service) []string {
+	ret := []string{ContentTypeJSON}
+	for _, item := range strings.FieldsFunc(service.Annotations.HTTPCodecs, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		contentType := strings.ToLower(item)
+		if alias, ok := codecAliases[contentType]; ok {
+			contentType = alias
+		}
+
+		duplicated := false
+		for _, existing := range ret {
+			duplicated = duplicated || existing == contentType
+		}
+		if !duplicated {
+			ret = append(ret, contentType)
+		}
+	}
+	return ret
+}
+
+// HasCodecs 判断服务是否支持 JSON 以外的请求体和响应格式。
+func HasCodecs(service *domain.Service) bool {
+	return len(Codecs(service)) > 1
+}
+
+// IsJSONBody 判断方法的请求体是否以 JSON 或 @http-codecs 注解中的格式编码。GET 和 DELETE 请求没有请求体。
+func IsJSONBody(method *domain.Method) bool {
+	switch method.Annotations.HTTPMethod {
+	case http.MethodGet, http.MethodDelete:
+		return false
+	}
+	return method.Annotations.HTTPContentType == ""
+}
+
+// IsForm 判断方法的请求是否以 application/x-www-form-urlencoded 编码。
+func IsForm(method *domain.Method) bool {
+	return method.Annotations.HTTPContentType == ContentTypeForm
+}
+
+// IsMultipart 判断方法的请求是否以 multipart/form-data 编码。
+func IsMultipart(method *domain.Method) bool {
+	return method.Annotations.HTTPContentType == ContentTypeMultipart
+}
+
+// HasContentType 判断服务是否有以 contentType 编码请求的方法，生成的代码只在这时包含对应的编解码函数。
+func HasContentType(service *domain.Service, contentType string) bool {
+	for _, method := range service.Methods {
+		if method.Func.Exported() && method.Annotations.HTTPContentType == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStream 判断方法的响应是否是嵌入 jkhttp.Stream 的流式响应，流式响应直接写入响应体而不是编码为 JSON。
+func IsStream(method *domain.Method) bool {
+	ptr, ok := method.ResponseType().(*types.Pointer)
+	return ok && utils.IsStreamType(ptr.Elem())
+}
+
+// HasStream 判断服务是否有返回流式响应的方法。
+func HasStream(service *domain.Service) bool {
+	for _, method := range service.Methods {
+		if method.Func.Exported() && IsStream(method) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasEventStream 判断服务是否有以 SSE 推送事件的方法。
+func HasEventStream(service *domain.Service) bool {
+	for _, method := range service.Methods {
+		if method.Func.Exported() && method.IsEventStream() {
+			return true
+		}
+	}
+	return false
+}
+
+// FilePart 判断 multipart/form-data 请求的字段是否作为文件上传，返回值 multiple 表示字段可以有多个文件。
+// *jkhttp.File、[]*jkhttp.File 和带有 `jk:"file"` 标签的 []byte 字段是文件。
+func FilePart(field JSONField) (ok, multiple bool) {
+	typ := field.Var.Type()
+	switch t := typ.(type) {
+	case *types.Pointer:
+		return utils.IsFileType(t.Elem()), false
+	case *types.Slice:
+		if ptr, isPtr := t.Elem().(*types.Pointer); isPtr && utils.IsFileType(ptr.Elem()) {
+			return true, true
+		}
+	}
+	if t, isSlice := typ.Underlying().(*types.Slice); isSlice {
+		if b, isBasic := t.Elem().(*types.Basic); isBasic && b.Kind() == types.Uint8 {
+			return reflect.StructTag(field.Tag).Get("jk") == "file", false
+		}
+	}
+	return false, false
+}
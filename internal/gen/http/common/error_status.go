@@ -0,0 +1,57 @@
+package common
+
+import (
+	"github.com/dave/jennifer/jen"
+	"github.com/nnnewb/jk/internal/domain"
+)
+
+// GenerateErrorStatus 生成名为 name 的函数，把端点返回的错误映射为 HTTP 状态码。
+// 实现了 khttp.StatusCoder 的错误使用自己的状态码，限流、熔断和超时中间件的错误分别是 429、503 和 504。
+func GenerateErrorStatus(f *jen.File, service *domain.Service, name string) {
+	var rateLimit, circuitBreaker bool
+	for _, method := range service.Methods {
+		rateLimit = rateLimit || method.Annotations.RateLimit != ""
+		circuitBreaker = circuitBreaker || method.Annotations.CircuitBreaker
+	}
+
+	// func errorStatus(err error) int {
+	// 	var coder khttp.StatusCoder
+	// 	switch {
+	// 	case errors.As(err, &coder):
+	// 		return coder.StatusCode()
+	// 	case errors.Is(err, ratelimit.ErrLimited):
+	// 		return http.StatusTooManyRequests
+	// 	case errors.Is(err, gobreaker.ErrOpenState), errors.Is(err, gobreaker.ErrTooManyRequests):
+	// 		return http.StatusServiceUnavailable
+	// 	case errors.Is(err, context.DeadlineExceeded):
+	// 		return http.StatusGatewayTimeout
+	// 	default:
+	// 		return http.StatusInternalServerError
+	// 	}
+	// }
+	f.Commentf("%s 返回端点错误对应的 HTTP 状态码。", name)
+	f.Func().Id(name).
+		Params(jen.Err().Error()).
+		Int().
+		Block(
+			jen.Var().Id("coder").Qual("github.com/go-kit/kit/transport/http", "StatusCoder"),
+			jen.Switch().BlockFunc(func(g *jen.Group) {
+				g.Case(jen.Qual("errors", "As").Call(jen.Err(), jen.Op("&").Id("coder"))).
+					Return(jen.Id("coder").Dot("StatusCode").Call())
+				if rateLimit {
+					g.Case(jen.Qual("errors", "Is").Call(jen.Err(), jen.Qual("github.com/go-kit/kit/ratelimit", "ErrLimited"))).
+						Return(jen.Qual("net/http", "StatusTooManyRequests"))
+				}
+				if circuitBreaker {
+					g.Case(
+						jen.Qual("errors", "Is").Call(jen.Err(), jen.Qual("github.com/sony/gobreaker", "ErrOpenState")),
+						jen.Qual("errors", "Is").Call(jen.Err(), jen.Qual("github.com/sony/gobreaker", "ErrTooManyRequests")),
+					).Return(jen.Qual("net/http", "StatusServiceUnavailable"))
+				}
+				g.Case(jen.Qual("errors", "Is").Call(jen.Err(), jen.Qual("context", "DeadlineExceeded"))).
+					Return(jen.Qual("net/http", "StatusGatewayTimeout"))
+				g.Default().Return(jen.Qual("net/http", "StatusInternalServerError"))
+			}),
+		).
+		Line()
+}
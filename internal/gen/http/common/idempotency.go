@@ -0,0 +1,15 @@
+package common
+
+import (
+	"github.com/nnnewb/jk/internal/domain"
+)
+
+// HasIdempotent 判断服务是否有 @idempotent 方法。
+func HasIdempotent(service *domain.Service) bool {
+	for _, method := range service.Methods {
+		if method.Func.Exported() && method.Annotations.Idempotent {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"go/types"
+
+	"github.com/nnnewb/jk/internal/domain"
+)
+
+// PaginationNames 是 @paginated 方法的分页字段序列化后的名称。
+type PaginationNames struct {
+	PageToken     string
+	PageSize      string
+	NextPageToken string
+	Items         string
+}
+
+// Pagination 返回方法分页字段序列化后的名称，方法没有 @paginated 注解时返回 false。
+func Pagination(method *domain.Method) (PaginationNames, bool) {
+	p := method.Pagination
+	if p == nil {
+		return PaginationNames{}, false
+	}
+
+	reqType := method.RequestType().(*types.Pointer).Elem().Underlying().(*types.Struct)
+	respType := method.ResponseNamed().Underlying().(*types.Struct)
+	return PaginationNames{
+		PageToken:     jsonName(reqType, p.PageToken),
+		PageSize:      jsonName(reqType, p.PageSize),
+		NextPageToken: jsonName(respType, p.NextPageToken),
+		Items:         jsonName(respType, p.Items),
+	}, true
+}
+
+// jsonName 返回结构体字段 field 序列化后的名称。
+func jsonName(structType *types.Struct, field *types.Var) string {
+	for _, f := range JSONFields(structType) {
+		if f.Var == field {
+			return f.Name
+		}
+	}
+	return field.Name()
+}
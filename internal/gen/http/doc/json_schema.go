@@ -0,0 +1,227 @@
+package doc
+
+import (
+	"encoding/json"
+	"go/constant"
+	"go/types"
+	"io"
+	"sort"
+
+	"emperror.dev/errors"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema 是 JSON Schema draft 2020-12 的一个子集，足够描述可被 encoding/json 序列化的 Go 类型。
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 any                    `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	ContentEncoding      string                 `json:"contentEncoding,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	AnyOf                []*JSONSchema          `json:"anyOf,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// nullable 允许 schema 取 null，Go 的 nil 指针、切片、映射都会序列化为 null。
+func (s *JSONSchema) nullable() *JSONSchema {
+	switch t := s.Type.(type) {
+	case string:
+		s.Type = []string{t, "null"}
+		return s
+	case nil:
+		return &JSONSchema{AnyOf: []*JSONSchema{s, {Type: "null"}}}
+	default:
+		return s
+	}
+}
+
+type jsonSchemaBuilder struct {
+	service *domain.Service
+	defs    map[string]*JSONSchema
+}
+
+// define 把命名结构体加入 $defs，返回对它的引用。
+func (b *jsonSchemaBuilder) define(named *types.Named) *JSONSchema {
+	name := named.Obj().Name()
+	ref := &JSONSchema{Ref: "#/$defs/" + name}
+	if _, ok := b.defs[name]; ok {
+		return ref
+	}
+
+	// 先占位，避免递归类型无限展开
+	def := &JSONSchema{}
+	b.defs[name] = def
+	*def = *b.schema(named.Underlying())
+	def.Title = name
+	def.Description = b.service.ObjectDoc(named.Obj())
+	return ref
+}
+
+// schema 和 generateSchemaFromType 一样遍历 Go 类型，额外输出 required、format、enum 和文档注释。
+func (b *jsonSchemaBuilder) schema(typ types.Type) *JSONSchema {
+	switch t := typ.(type) {
+	case *types.Named:
+		switch {
+		case utils.IsFileType(t):
+			return &JSONSchema{Type: "string", Format: "binary"}
+		case utils.IsTimeType(t):
+			return &JSONSchema{Type: "string", Format: "date-time"}
+		case utils.IsJSONMarshaler(t):
+			// MarshalJSON 决定的格式无法得知，接受任意值
+			return &JSONSchema{Description: b.service.ObjectDoc(t.Obj())}
+		case utils.IsTextMarshaler(t):
+			return &JSONSchema{Type: "string", Description: b.service.ObjectDoc(t.Obj())}
+		}
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			return b.define(t)
+		}
+		ret := b.schema(t.Underlying())
+		ret.Description = b.service.ObjectDoc(t.Obj())
+		ret.Enum = enumValues(t)
+		return ret
+	case *types.Pointer:
+		return b.schema(t.Elem()).nullable()
+	case *types.Array:
+		return &JSONSchema{Type: "array", Items: b.schema(t.Elem())}
+	case *types.Slice:
+		if elem, ok := t.Elem().(*types.Basic); ok && elem.Kind() == types.Uint8 {
+			return (&JSONSchema{Type: "string", ContentEncoding: "base64"}).nullable()
+		}
+		return (&JSONSchema{Type: "array", Items: b.schema(t.Elem())}).nullable()
+	case *types.Map:
+		return (&JSONSchema{Type: "object", AdditionalProperties: b.schema(t.Elem())}).nullable()
+	case *types.Basic:
+		var zero float64
+		switch t.Kind() {
+		case types.Bool:
+			return &JSONSchema{Type: "boolean"}
+		case types.Int8, types.Int16, types.Int32:
+			return &JSONSchema{Type: "integer", Format: "int32"}
+		case types.Int, types.Int64:
+			return &JSONSchema{Type: "integer", Format: "int64"}
+		case types.Uint8, types.Uint16, types.Uint32:
+			return &JSONSchema{Type: "integer", Format: "int32", Minimum: &zero}
+		case types.Uint, types.Uint64:
+			return &JSONSchema{Type: "integer", Format: "int64", Minimum: &zero}
+		case types.Float32:
+			return &JSONSchema{Type: "number", Format: "float"}
+		case types.Float64:
+			return &JSONSchema{Type: "number", Format: "double"}
+		case types.String:
+			return &JSONSchema{Type: "string"}
+		default:
+			panic(errors.Errorf("unserializable basic type %v", t.Kind()))
+		}
+	case *types.Struct:
+		ret := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+		for _, field := range common.JSONFields(t) {
+			prop := b.schema(field.Var.Type())
+			if doc := b.service.ObjectDoc(field.Var); doc != "" {
+				if prop.Ref != "" {
+					// $ref 指向的定义可能被多处共享，复制一份再写描述
+					prop = &JSONSchema{Ref: prop.Ref}
+				}
+				prop.Description = doc
+			}
+			ret.Properties[field.Name] = prop
+
+			// 没有 omitempty 并且不在嵌入指针中的字段总会出现在 encoding/json 的输出里
+			if !field.Optional() {
+				ret.Required = append(ret.Required, field.Name)
+			}
+		}
+		return ret
+	default:
+		panic(errors.Errorf("unserializable type %v", typ))
+	}
+}
+
+// enumValues 收集与命名类型 named 同一个包里声明的该类型常量，按声明顺序返回。
+func enumValues(named *types.Named) []any {
+	pkg := named.Obj().Pkg()
+	if pkg == nil {
+		return nil
+	}
+
+	var consts []*types.Const
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		c, ok := scope.Lookup(name).(*types.Const)
+		if ok && types.Identical(c.Type(), named) {
+			consts = append(consts, c)
+		}
+	}
+	sort.Slice(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+
+	ret := make([]any, 0, len(consts))
+	for _, c := range consts {
+		switch c.Val().Kind() {
+		case constant.String:
+			ret = append(ret, constant.StringVal(c.Val()))
+		case constant.Int:
+			v, _ := constant.Int64Val(c.Val())
+			ret = append(ret, v)
+		case constant.Float:
+			v, _ := constant.Float64Val(c.Val())
+			ret = append(ret, v)
+		case constant.Bool:
+			ret = append(ret, constant.BoolVal(c.Val()))
+		}
+	}
+
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+func writeJSONSchema(wr io.Writer, schema *JSONSchema) error {
+	encoder := json.NewEncoder(wr)
+	encoder.SetIndent("", "  ")
+	return errors.WithStack(encoder.Encode(schema))
+}
+
+// GenerateJSONSchema 为命名类型 named 生成独立的 JSON Schema 文档，它引用的其他命名结构体放在 $defs 里。
+func GenerateJSONSchema(wr io.Writer, service *domain.Service, named *types.Named) error {
+	b := &jsonSchemaBuilder{service: service, defs: make(map[string]*JSONSchema)}
+	root := b.schema(named)
+	root.Schema = jsonSchemaDialect
+	root.Defs = b.defs
+	return writeJSONSchema(wr, root)
+}
+
+// GenerateJSONSchemaBundle 生成一个 JSON Schema 文档，服务所有请求、响应类型都放在 $defs 里。
+func GenerateJSONSchemaBundle(wr io.Writer, service *domain.Service) error {
+	b := &jsonSchemaBuilder{service: service, defs: make(map[string]*JSONSchema)}
+	for _, method := range service.Methods {
+		if !method.Func.Exported() {
+			continue
+		}
+		b.schema(method.RequestType())
+		switch {
+		case method.IsEventStream():
+			b.schema(method.ResponseType().(*types.Chan).Elem())
+		case !common.IsStream(method):
+			b.schema(method.ResponseType())
+		}
+	}
+
+	return writeJSONSchema(wr, &JSONSchema{
+		Schema:      jsonSchemaDialect,
+		Title:       service.Name(),
+		Description: service.Doc(),
+		Defs:        b.defs,
+	})
+}
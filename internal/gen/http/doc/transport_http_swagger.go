@@ -25,8 +25,8 @@ func GenerateSwagger(wr io.Writer, service *domain.Service) error {
 	root := spec.Swagger{
 		SwaggerProps: spec.SwaggerProps{
 			Swagger:  "2.0",
-			Consumes: []string{"application/json"},
-			Produces: []string{"application/json"},
+			Consumes: common.Codecs(service),
+			Produces: common.Codecs(service),
 			Schemes:  []string{"http", "https"},
 			Host:     "localhost",
 			BasePath: service.Annotations.HTTPBasePath,
@@ -36,7 +36,8 @@ func GenerateSwagger(wr io.Writer, service *domain.Service) error {
 					Version: service.Annotations.SwaggerInfoAPIVersion,
 				},
 			},
-			Paths: paths,
+			Paths:               paths,
+			SecurityDefinitions: securityDefinitions(service),
 		},
 	}
 
@@ -65,9 +66,15 @@ func generatePaths(service *domain.Service) (*spec.Paths, error) {
 		item := spec.PathItem{}
 		operation := spec.
 			NewOperation(strcase.ToKebab(method.Func.Name())).
-			WithProduces("application/json").
-			WithDefaultResponse(generateResponse(method.Func)).
 			WithTags(service.Interface.Obj().Name())
+		switch {
+		case method.IsEventStream():
+			operation.WithProduces("text/event-stream").WithDefaultResponse(generateEventStreamResponse(method))
+		case common.IsStream(method):
+			operation.WithProduces("application/octet-stream").WithDefaultResponse(generateStreamResponse())
+		default:
+			operation.WithProduces(common.Codecs(service)...).WithDefaultResponse(generateResponse(method.Func))
+		}
 
 		switch strings.ToLower(method.Annotations.HTTPMethod) {
 		case "get":
@@ -79,30 +86,118 @@ func generatePaths(service *domain.Service) (*spec.Paths, error) {
 			item.Delete = operation
 			item.Delete.Parameters = append(item.Delete.Parameters, parameters...)
 		case "put":
-			parameters := generatePostParameters(method.Func)
-			operation.WithConsumes("application/json")
+			parameters := generateBodyParameters(operation, service, method)
 			item.Put = operation
 			item.Put.Parameters = append(item.Put.Parameters, parameters...)
 		case "patch":
-			parameters := generatePostParameters(method.Func)
-			operation.WithConsumes("application/json")
+			parameters := generateBodyParameters(operation, service, method)
 			item.Patch = operation
 			item.Patch.Parameters = append(item.Patch.Parameters, parameters...)
 		case "post":
 			fallthrough
 		default:
-			parameters := generatePostParameters(method.Func)
-			operation.WithConsumes("application/json")
+			parameters := generateBodyParameters(operation, service, method)
 			item.Post = operation
 			item.Post.Parameters = append(item.Post.Parameters, parameters...)
 		}
 
+		documentPagination(operation, method)
+		documentIdempotency(operation, method)
+		if scheme := method.AuthScheme(); scheme != "" {
+			// 没有 scope 时也需要序列化为空数组而不是 null
+			operation.SecuredWith(scheme, []string{}...)
+		}
 		ret.Paths[method.Annotations.HTTPPath] = item
 	}
 
 	return ret, nil
 }
 
+// documentPagination 为 @paginated 方法的分页参数和响应字段添加说明，并以 x-pagination 扩展标注分页字段。
+func documentPagination(operation *spec.Operation, method *domain.Method) {
+	names, ok := common.Pagination(method)
+	if !ok {
+		return
+	}
+
+	descriptions := map[string]string{
+		names.PageToken: "token of the page to fetch, empty for the first page",
+		names.PageSize:  "maximum number of items per page",
+	}
+	for i, param := range operation.Parameters {
+		if param.In == "body" {
+			describeProperties(param.Schema, descriptions)
+			continue
+		}
+		if description, ok := descriptions[param.Name]; ok {
+			operation.Parameters[i].Description = description
+		}
+	}
+	if operation.Responses != nil && operation.Responses.Default != nil {
+		describeProperties(operation.Responses.Default.Schema, map[string]string{
+			names.NextPageToken: "token of the next page, empty on the last page",
+			names.Items:         "items of the current page",
+		})
+	}
+
+	operation.AddExtension("x-pagination", map[string]string{
+		"page_token":      names.PageToken,
+		"page_size":       names.PageSize,
+		"next_page_token": names.NextPageToken,
+		"items":           names.Items,
+	})
+}
+
+// securityDefinitions 返回服务用到的认证方式，名称和 @http-auth 的值相同。
+// Swagger 2.0 没有 bearer 类型，bearer 令牌描述为 Authorization 请求头中的 API 密钥。
+func securityDefinitions(service *domain.Service) spec.SecurityDefinitions {
+	schemes := common.AuthSchemes(service)
+	if len(schemes) == 0 {
+		return nil
+	}
+
+	ret := make(spec.SecurityDefinitions, len(schemes))
+	for _, scheme := range schemes {
+		switch scheme {
+		case "bearer":
+			ret[scheme] = spec.APIKeyAuth("Authorization", "header")
+			ret[scheme].Description = "bearer token, in the form of: Bearer <token>"
+		case "basic":
+			ret[scheme] = spec.BasicAuth()
+		case "apikey":
+			ret[scheme] = spec.APIKeyAuth("X-API-Key", "header")
+		}
+	}
+	return ret
+}
+
+// documentIdempotency 为 @idempotent 方法添加 Idempotency-Key 请求头参数。
+func documentIdempotency(operation *spec.Operation, method *domain.Method) {
+	if !method.Annotations.Idempotent {
+		return
+	}
+	param := spec.HeaderParam("Idempotency-Key").
+		Typed("string", "").
+		WithDescription("unique key of the call, retries with the same key replay the saved response instead of processing again")
+	if !method.IdempotencyKeyRequired() {
+		param.AsOptional()
+	}
+	operation.AddParam(param)
+}
+
+// describeProperties 设置 schema 中属性的说明。
+func describeProperties(schema *spec.Schema, descriptions map[string]string) {
+	if schema == nil {
+		return
+	}
+	for name, description := range descriptions {
+		if property, ok := schema.Properties[name]; ok {
+			property.Description = description
+			schema.Properties[name] = property
+		}
+	}
+}
+
 func generateQueryParameters(fun *types.Func) []spec.Parameter {
 	signature := fun.Type().(*types.Signature)
 	paramType := signature.Params().At(1).Type()
@@ -117,27 +212,13 @@ func generateQueryParameters(fun *types.Func) []spec.Parameter {
 	}
 
 	params := make([]spec.Parameter, 0, structType.NumFields())
-	for i := 0; i < structType.NumFields(); i++ {
-		f := structType.Field(i)
-		if !f.Exported() {
-			continue
-		}
-
+	for _, field := range common.JSONFields(structType) {
+		f := field.Var
 		if !utils.IsQueryStringSerializable(f.Type()) {
 			panic(fmt.Errorf("unserializable query string parameter type %s", f.Type()))
 		}
 
-		var (
-			jsonName string
-			ok       bool
-		)
-		if jsonName, ok = common.GetJsonName(structType.Tag(i)); !ok {
-			jsonName = f.Name()
-		} else if jsonName == "-" {
-			continue
-		}
-
-		param := spec.QueryParam(jsonName)
+		param := spec.QueryParam(field.Name)
 		switch ft := f.Type().(type) {
 		case *types.Basic:
 			switch ft.Kind() {
@@ -160,6 +241,89 @@ func generateQueryParameters(fun *types.Func) []spec.Parameter {
 	return params
 }
 
+// generateBodyParameters 生成请求体参数，并按 @http-content-type 和 @http-codecs 设置 operation 接受的格式。
+func generateBodyParameters(operation *spec.Operation, service *domain.Service, method *domain.Method) []spec.Parameter {
+	reqType := method.RequestType().(*types.Pointer).Elem()
+	switch {
+	case common.IsMultipart(method):
+		operation.WithConsumes(common.ContentTypeMultipart)
+		return multipartParameters(reqType.Underlying().(*types.Struct))
+	case common.IsForm(method):
+		operation.WithConsumes(common.ContentTypeForm)
+		return formParameters(reqType.Underlying().(*types.Struct))
+	default:
+		operation.WithConsumes(common.Codecs(service)...)
+		return generatePostParameters(method.Func)
+	}
+}
+
+// multipartParameters 为 multipart/form-data 请求的每个字段生成 formData 参数，文件字段的类型是 file。
+func multipartParameters(structType *types.Struct) []spec.Parameter {
+	var params []spec.Parameter
+	for _, field := range common.JSONFields(structType) {
+		param := spec.FormDataParam(field.Name)
+		if isFile, _ := common.FilePart(field); isFile {
+			param.Typed("file", "")
+		} else if !typedFormParameter(param, field.Var.Type()) {
+			param.Typed("string", "").WithDescription("JSON encoded")
+		}
+		params = append(params, *param)
+	}
+	return params
+}
+
+// formParameters 为 application/x-www-form-urlencoded 请求的每个字段生成 formData 参数，
+// 和查询字符串一样只支持标量、标量指针和标量切片。
+func formParameters(structType *types.Struct) []spec.Parameter {
+	var params []spec.Parameter
+	for _, field := range common.JSONFields(structType) {
+		param := spec.FormDataParam(field.Name)
+		if !typedFormParameter(param, field.Var.Type()) {
+			panic(errors.Errorf("unserializable form parameter type %s", field.Var.Type()))
+		}
+		params = append(params, *param)
+	}
+	return params
+}
+
+// typedFormParameter 设置标量、标量指针和标量切片参数的类型，其他类型返回 false。
+func typedFormParameter(param *spec.Parameter, typ types.Type) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if basic, ok := typ.Underlying().(*types.Basic); ok {
+		param.Typed(formDataType(basic), "")
+		return true
+	}
+	if slice, ok := typ.Underlying().(*types.Slice); ok && isFormDataScalar(slice.Elem()) {
+		// 标量切片的每个元素是一个同名表单值
+		param.CollectionOf(spec.NewItems().Typed(formDataType(slice.Elem().Underlying().(*types.Basic)), ""), "multi")
+		return true
+	}
+	return false
+}
+
+func isFormDataScalar(typ types.Type) bool {
+	basic, ok := typ.Underlying().(*types.Basic)
+	return ok && basic.Kind() != types.Uint8
+}
+
+func formDataType(basic *types.Basic) string {
+	switch basic.Kind() {
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+		types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+		return "integer"
+	case types.Float32, types.Float64:
+		return "number"
+	case types.Bool:
+		return "boolean"
+	case types.String:
+		return "string"
+	default:
+		panic(errors.Errorf("unserializable basic type %v", basic.Kind()))
+	}
+}
+
 func generatePostParameters(fun *types.Func) []spec.Parameter {
 	signature := fun.Type().(*types.Signature)
 	reqType := signature.Params().At(1)
@@ -178,6 +342,23 @@ func generateResponse(fun *types.Func) *spec.Response {
 		WithSchema(generateSchemaFromType(respType.Type()))
 }
 
+// generateStreamResponse 生成流式响应的文档，响应体是文件。
+func generateStreamResponse() *spec.Response {
+	return spec.
+		NewResponse().
+		WithSchema(&spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"file"}}}).
+		AddHeader("Content-Disposition", spec.ResponseHeader().Typed("string", "").
+			WithDescription("attachment; filename=..., present when the response has a filename"))
+}
+
+// generateEventStreamResponse 生成 SSE 响应的文档，Schema 描述每个事件 data 中的 JSON。
+func generateEventStreamResponse(method *domain.Method) *spec.Response {
+	return spec.
+		NewResponse().
+		WithDescription("server-sent events, data of each event is JSON encoded " + method.ResponseTypeName()).
+		WithSchema(generateSchemaFromType(method.ResponseType().(*types.Chan).Elem()))
+}
+
 func generateSchemaFromType(typ types.Type) *spec.Schema {
 	switch t := typ.(type) {
 	case *types.Named:
@@ -207,27 +388,9 @@ func generateSchemaFromType(typ types.Type) *spec.Schema {
 	case *types.Struct:
 		ret := &spec.Schema{}
 		ret.Properties = make(spec.SchemaProperties)
-		// Iterate over all the fields of the struct
-		for i := 0; i < t.NumFields(); i++ {
-			field := t.Field(i)
-			if !field.Exported() {
-				continue
-			}
-
-			var (
-				jsonName string
-				ok       bool
-			)
-			if jsonName, ok = common.GetJsonName(t.Tag(i)); !ok {
-				jsonName = field.Name()
-			} else if jsonName == "-" {
-				continue
-			}
-
-			// Check if the field is exported
-			if field.Exported() {
-				ret.Properties[jsonName] = *generateSchemaFromType(field.Type())
-			}
+		// 和 encoding/json 一样展开嵌入结构体的字段
+		for _, field := range common.JSONFields(t) {
+			ret.Properties[field.Name] = *generateSchemaFromType(field.Var.Type())
 		}
 		return ret
 	default:
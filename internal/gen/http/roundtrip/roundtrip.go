@@ -0,0 +1,548 @@
+package roundtrip
+
+import (
+	"fmt"
+	"go/types"
+	"net/http"
+
+	"emperror.dev/errors"
+	"github.com/dave/jennifer/jen"
+	"github.com/iancoleman/strcase"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+// sampler 为类型生成非零的示例值，用于检查编解码前后的值是否一致。
+type sampler struct {
+	ptrFunc string                   // 生成的取地址辅助函数名
+	query   bool                     // 只填充可以用查询字符串编码的字段
+	stack   map[*types.TypeName]bool // 正在展开的命名类型，避免递归类型无限展开
+}
+
+// typeCode 返回类型 typ 的代码。
+func typeCode(typ types.Type) (*jen.Statement, bool) {
+	switch t := typ.(type) {
+	case *types.Named:
+		if t.Obj().Pkg() == nil {
+			return jen.Id(t.Obj().Name()), true
+		}
+		return jen.Qual(t.Obj().Pkg().Path(), t.Obj().Name()), true
+	case *types.Basic:
+		return jen.Id(t.Name()), true
+	case *types.Pointer:
+		elem, ok := typeCode(t.Elem())
+		return jen.Op("*").Add(elem), ok
+	case *types.Slice:
+		elem, ok := typeCode(t.Elem())
+		return jen.Index().Add(elem), ok
+	case *types.Array:
+		elem, ok := typeCode(t.Elem())
+		return jen.Index(jen.Lit(int(t.Len()))).Add(elem), ok
+	case *types.Map:
+		key, ok := typeCode(t.Key())
+		if !ok {
+			return nil, false
+		}
+		elem, ok := typeCode(t.Elem())
+		return jen.Map(key).Add(elem), ok
+	default:
+		return nil, false
+	}
+}
+
+// basicValue 返回基本类型的示例值，name 用于区分不同字段的字符串值。
+func basicValue(t *types.Basic, name string) (*jen.Statement, bool) {
+	switch {
+	case t.Info()&types.IsBoolean != 0:
+		return jen.True(), true
+	case t.Info()&types.IsInteger != 0:
+		return jen.Lit(len(name)%100 + 1), true
+	case t.Info()&types.IsFloat != 0:
+		return jen.Lit(float64(len(name)%100) + 0.5), true
+	case t.Info()&types.IsString != 0:
+		return jen.Lit(name), true
+	default:
+		return nil, false
+	}
+}
+
+// isQueryScalar 判断类型是否可以由 gorilla/schema 编码为单个查询参数。
+func isQueryScalar(typ types.Type) bool {
+	basic, ok := typ.Underlying().(*types.Basic)
+	return ok && basic.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) != 0
+}
+
+// value 返回类型 typ 的示例值，无法生成时返回 false，字段保持零值。
+func (s *sampler) value(typ types.Type, name string) (*jen.Statement, bool) {
+	switch t := typ.(type) {
+	case *types.Named:
+		if utils.IsFileType(t) {
+			// 文件内容只能读取一次，无法比较
+			return nil, false
+		}
+		if common.IsOpaqueType(t) {
+			// 自定义编码的类型不一定能从字段值还原
+			return nil, false
+		}
+		structType, ok := t.Underlying().(*types.Struct)
+		if !ok {
+			// 命名的基本类型，无类型常量可以直接赋值
+			return s.value(t.Underlying(), name)
+		}
+		if s.stack[t.Obj()] {
+			return nil, false
+		}
+		s.stack[t.Obj()] = true
+		defer delete(s.stack, t.Obj())
+
+		code, _ := typeCode(t)
+		return code.Add(s.fields(structType, name)), true
+	case *types.Basic:
+		return basicValue(t, name)
+	case *types.Pointer:
+		elem, ok := s.value(t.Elem(), name)
+		if !ok {
+			return nil, false
+		}
+		if named, isNamed := t.Elem().(*types.Named); isNamed {
+			if _, isStruct := named.Underlying().(*types.Struct); isStruct {
+				return jen.Op("&").Add(elem), true
+			}
+		}
+		elemType, ok := typeCode(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return jen.Id(s.ptrFunc).Types(elemType).Call(elem), true
+	case *types.Slice:
+		if basic, ok := t.Elem().(*types.Basic); ok && basic.Kind() == types.Uint8 {
+			return jen.Index().Byte().Call(jen.Lit(name)), true
+		}
+		return s.collection(t, t.Elem(), name)
+	case *types.Array:
+		return s.collection(t, t.Elem(), name)
+	case *types.Map:
+		if _, ok := t.Key().Underlying().(*types.Basic); !ok {
+			return nil, false
+		}
+		key, ok := s.value(t.Key(), "key")
+		if !ok {
+			return nil, false
+		}
+		elem, ok := s.value(t.Elem(), name)
+		if !ok {
+			return nil, false
+		}
+		code, ok := typeCode(t)
+		if !ok {
+			return nil, false
+		}
+		return code.Values(jen.Dict{key: elem}), true
+	default:
+		return nil, false
+	}
+}
+
+func (s *sampler) collection(typ, elemType types.Type, name string) (*jen.Statement, bool) {
+	elem, ok := s.value(elemType, name)
+	if !ok {
+		return nil, false
+	}
+	code, ok := typeCode(typ)
+	if !ok {
+		return nil, false
+	}
+	return code.Values(elem), true
+}
+
+// fields 返回结构体字面量 {Field: value, ...}。
+func (s *sampler) fields(t *types.Struct, prefix string) *jen.Statement {
+	return jen.Values(s.dict(t, visibleFields(t), func(field common.JSONField) (string, bool) {
+		return prefix + "." + field.Name, true
+	}))
+}
+
+// visibleFields 返回 encoding/json 序列化的字段，被遮蔽的嵌入结构体字段不填充，否则往返后无法还原。
+func visibleFields(t *types.Struct) map[*types.Var]bool {
+	ret := make(map[*types.Var]bool)
+	for _, field := range common.JSONFields(t) {
+		ret[field.Var] = true
+	}
+	return ret
+}
+
+// dict 返回结构体 t 的字段和示例值，name 返回字段示例值使用的名称，不填充的字段返回 false。
+// 嵌入结构体生成为嵌套的字面量，其中的字段和外层字段一样只填充 visible 中的字段。
+func (s *sampler) dict(t *types.Struct, visible map[*types.Var]bool, name func(field common.JSONField) (string, bool)) jen.Dict {
+	d := jen.Dict{}
+	for _, field := range common.DeclaredJSONFields(t) {
+		if embedded := common.EmbeddedStruct(field); embedded != nil {
+			if value, ok := s.embedded(field, embedded, visible, name); ok {
+				d[jen.Id(field.Var.Name())] = value
+			}
+			continue
+		}
+		if !visible[field.Var] {
+			continue
+		}
+
+		n, ok := name(field)
+		if !ok {
+			continue
+		}
+		value, ok := s.value(field.Var.Type(), n)
+		if ok {
+			d[jen.Id(field.Var.Name())] = value
+		}
+	}
+	return d
+}
+
+// embedded 返回嵌入结构体字段的示例值，没有需要填充的字段时返回 false，嵌入指针保持 nil。
+// 未导出的嵌入结构体在其他包中无法构造，保持零值。
+func (s *sampler) embedded(field common.JSONField, t *types.Struct, visible map[*types.Var]bool, name func(field common.JSONField) (string, bool)) (*jen.Statement, bool) {
+	if !field.Var.Exported() {
+		return nil, false
+	}
+
+	typ := field.Var.Type()
+	ptr, isPtr := typ.(*types.Pointer)
+	if isPtr {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || s.stack[named.Obj()] {
+		return nil, false
+	}
+	s.stack[named.Obj()] = true
+	defer delete(s.stack, named.Obj())
+
+	d := s.dict(t, visible, name)
+	if len(d) == 0 {
+		return nil, false
+	}
+	code, _ := typeCode(named)
+	if isPtr {
+		return jen.Op("&").Add(code).Values(d), true
+	}
+	return code.Values(d), true
+}
+
+// top 返回请求或响应结构体的示例值。查询字符串请求只填充 gorilla/schema 能编码的字段，
+// 响应跳过 Code 字段，保证客户端不会把它当作业务错误。
+func (s *sampler) top(named *types.Named, response bool) *jen.Statement {
+	code, _ := typeCode(named)
+	s.stack[named.Obj()] = true
+	defer delete(s.stack, named.Obj())
+
+	structType := named.Underlying().(*types.Struct)
+	return jen.Op("&").Add(code).Values(s.dict(structType, visibleFields(structType), func(field common.JSONField) (string, bool) {
+		if response && field.Var.Name() == "Code" {
+			return "", false
+		}
+
+		typ := field.Var.Type()
+		if s.query && !response {
+			// 查询字符串只能表示标量、标量指针和标量切片
+			switch t := typ.(type) {
+			case *types.Pointer:
+				typ = t.Elem()
+			case *types.Slice:
+				typ = t.Elem()
+			}
+			if !isQueryScalar(typ) {
+				return "", false
+			}
+		}
+		return field.Name, true
+	}))
+}
+
+// names 是生成的测试代码中的标识符，不同框架使用不同前缀，避免同一个包内的测试文件冲突。
+type names struct {
+	service     string
+	ptr         string
+	server      string
+	test        string
+	credentials string
+}
+
+func newNames(framework string) names {
+	title := strcase.ToCamel(framework)
+	if framework == "http" {
+		title = "HTTP"
+	}
+	prefix := strcase.ToLowerCamel(framework)
+	return names{
+		service:     prefix + "RoundTripService",
+		ptr:         prefix + "RoundTripPtr",
+		server:      "new" + title + "RoundTripServer",
+		test:        "Test" + title + "RoundTrip",
+		credentials: prefix + "RoundTripCredentials",
+	}
+}
+
+func generateFakeService(f *jen.File, service *domain.Service, n names) {
+	// type xxxRoundTripService struct {
+	//   createOrder func(ctx context.Context, req *REQ) (*RESP, error)
+	// }
+	f.Commentf("%s 把调用转发给测试中设置的函数。", n.service)
+	f.Type().Id(n.service).StructFunc(func(g *jen.Group) {
+		for _, method := range service.Methods {
+			g.Id(strcase.ToLowerCamel(method.Func.Name())).Func().
+				Params(jen.Qual("context", "Context"), jen.Op("*").Add(method.RequestTypeCodeJen())).
+				Params(method.ResultTypeCodeJen(), jen.Error())
+		}
+	}).Line()
+
+	for _, method := range service.Methods {
+		f.Func().Params(jen.Id("s").Op("*").Id(n.service)).Id(method.Func.Name()).
+			Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("req").Op("*").Add(method.RequestTypeCodeJen())).
+			Params(method.ResultTypeCodeJen(), jen.Error()).
+			Block(
+				jen.Return(jen.Id("s").Dot(strcase.ToLowerCamel(method.Func.Name())).Call(jen.Id("ctx"), jen.Id("req"))),
+			).Line()
+	}
+
+	// func xxxRoundTripPtr[T any](v T) *T {
+	f.Func().Id(n.ptr).Types(jen.Id("T").Any()).Params(jen.Id("v").Id("T")).Op("*").Id("T").Block(
+		jen.Return(jen.Op("&").Id("v")),
+	).Line()
+}
+
+// generateCredentials 为需要认证的服务生成测试凭据，客户端从中获取凭据，服务端只接受这些凭据。
+func generateCredentials(f *jen.File, service *domain.Service, n names) {
+	// var xxxRoundTripCredentials = []*jkhttp.Credentials{...}
+	f.Commentf("%s 是客户端发送、服务端接受的凭据。", n.credentials)
+	f.Var().Id(n.credentials).Op("=").Index().Op("*").Qual(utils.FilePackage, "Credentials").ValuesFunc(func(g *jen.Group) {
+		for _, scheme := range common.AuthSchemes(service) {
+			values := jen.Dict{jen.Id("Scheme"): common.AuthSchemeJen(scheme)}
+			switch scheme {
+			case "basic":
+				values[jen.Id("Username")] = jen.Lit("round-trip")
+				values[jen.Id("Password")] = jen.Lit("secret")
+			default:
+				values[jen.Id("Token")] = jen.Lit("round-trip-" + scheme)
+			}
+			g.Values(values)
+		}
+	}).Line()
+}
+
+// authenticator 返回只接受 n.credentials 中凭据的 jkhttp.Authenticator。
+func authenticator(n names) *jen.Statement {
+	// jkhttp.AuthenticatorFunc(func(ctx context.Context, cred *jkhttp.Credentials) (context.Context, error) {
+	//   for _, want := range xxxRoundTripCredentials {
+	//     if *want == *cred {
+	//       return ctx, nil
+	//     }
+	//   }
+	//   return nil, errors.New("invalid credentials")
+	// })
+	return jen.Qual(utils.FilePackage, "AuthenticatorFunc").Call(
+		jen.Func().
+			Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("cred").Op("*").Qual(utils.FilePackage, "Credentials")).
+			Params(jen.Qual("context", "Context"), jen.Error()).
+			Block(
+				jen.For(jen.List(jen.Id("_"), jen.Id("want")).Op(":=").Range().Id(n.credentials)).Block(
+					jen.If(jen.Op("*").Id("want").Op("==").Op("*").Id("cred")).Block(jen.Return(jen.Id("ctx"), jen.Nil())),
+				),
+				jen.Return(jen.Nil(), jen.Qual("errors", "New").Call(jen.Lit("invalid credentials"))),
+			),
+	)
+}
+
+func generateServer(f *jen.File, service *domain.Service, framework string, n names) error {
+	svc := jen.Qual(service.Interface.Obj().Pkg().Path(), service.Name())
+	auth := common.HasAuth(service)
+	switch framework {
+	case "http":
+		// func newHTTPRoundTripServer(svc Service) *httptest.Server {
+		f.Func().Id(n.server).Params(jen.Id("svc").Add(svc)).Op("*").Qual("net/http/httptest", "Server").BlockFunc(func(g *jen.Group) {
+			g.Id("serverSet").Op(":=").Id("NewHTTPServerSet").Call(jen.Id("NewEndpointSet").Call(jen.Id("svc")))
+			if auth {
+				g.Id("serverSet").Dot("Authenticator").Op("=").Add(authenticator(n))
+			}
+			g.Id("mux").Op(":=").Qual("net/http", "NewServeMux").Call()
+			g.Id("serverSet").Dot("Register").Call(jen.Id("mux"))
+			g.Return(jen.Qual("net/http/httptest", "NewServer").Call(jen.Id("mux")))
+		}).Line()
+	case "gin":
+		// func newGinRoundTripServer(svc Service) *httptest.Server {
+		f.Func().Id(n.server).Params(jen.Id("svc").Add(svc)).Op("*").Qual("net/http/httptest", "Server").BlockFunc(func(g *jen.Group) {
+			g.Qual("github.com/gin-gonic/gin", "SetMode").Call(jen.Qual("github.com/gin-gonic/gin", "TestMode"))
+			g.Id("router").Op(":=").Qual("github.com/gin-gonic/gin", "New").Call()
+			g.Id("serverSet").Op(":=").Id("NewGinServerSet").Call(jen.Id("NewEndpointSet").Call(jen.Id("svc")))
+			if auth {
+				g.Id("serverSet").Dot("Authenticator").Op("=").Add(authenticator(n))
+			}
+			g.Id("serverSet").Dot("Register").Call(jen.Id("router"))
+			g.Return(jen.Qual("net/http/httptest", "NewServer").Call(jen.Id("router")))
+		}).Line()
+	default:
+		return errors.Errorf("round trip tests does not support framework %s", framework)
+	}
+	return nil
+}
+
+func generateMethodTest(g *jen.Group, n names, method *domain.Method) {
+	reqType := method.RequestType().(*types.Pointer).Elem().(*types.Named)
+	respType := method.ResponseNamed()
+	query := method.Annotations.HTTPMethod == http.MethodGet || method.Annotations.HTTPMethod == http.MethodDelete ||
+		common.IsForm(method)
+
+	reqSampler := &sampler{ptrFunc: n.ptr, query: query, stack: make(map[*types.TypeName]bool)}
+	respSampler := &sampler{ptrFunc: n.ptr, stack: make(map[*types.TypeName]bool)}
+	name := method.Func.Name()
+
+	// want := &RESP{...}
+	want := []jen.Code{jen.Id("want").Op(":=").Add(respSampler.top(respType, true))}
+	// if !reflect.DeepEqual(resp, want) { ... }
+	checkResponse := []jen.Code{
+		jen.If(jen.Op("!").Qual("reflect", "DeepEqual").Call(jen.Id("resp"), jen.Id("want"))).Block(
+			jen.Id("t").Dot("Errorf").Call(jen.Lit("client received %+v, want %+v"), jen.Id("resp"), jen.Id("want")),
+		),
+	}
+	// return want, nil
+	serve := []jen.Code{jen.Return(jen.Id("want"), jen.Nil())}
+	if method.IsEventStream() {
+		// SSE 方法发送两个事件后关闭通道，客户端读到的事件应当和发送的一致
+		event := respSampler.top(respType, true)
+		want = []jen.Code{jen.Id("want").Op(":=").Index().Op("*").Add(method.ResponseTypeCodeJen()).Values(event, event)}
+		serve = []jen.Code{
+			jen.Id("events").Op(":=").Make(jen.Chan().Op("*").Add(method.ResponseTypeCodeJen()), jen.Len(jen.Id("want"))),
+			jen.For(jen.List(jen.Id("_"), jen.Id("event")).Op(":=").Range().Id("want")).Block(
+				jen.Id("events").Op("<-").Id("event"),
+			),
+			jen.Close(jen.Id("events")),
+			jen.Return(jen.Id("events"), jen.Nil()),
+		}
+		checkResponse = []jen.Code{
+			jen.Var().Id("got").Index().Op("*").Add(method.ResponseTypeCodeJen()),
+			jen.For(jen.Id("event").Op(":=").Range().Id("resp")).Block(
+				jen.Id("got").Op("=").Append(jen.Id("got"), jen.Id("event")),
+			),
+			jen.If(jen.Op("!").Qual("reflect", "DeepEqual").Call(jen.Id("got"), jen.Id("want"))).Block(
+				jen.Id("t").Dot("Errorf").Call(jen.Lit("client received events %+v, want %+v"), jen.Id("got"), jen.Id("want")),
+			),
+		}
+	}
+	if common.IsStream(method) {
+		// 流式响应的 Body 不能比较，检查客户端读到的内容和响应头
+		want = []jen.Code{
+			jen.Id("want").Op(":=").Op("&").Add(method.ResponseTypeCodeJen()).Values(),
+			jen.Id("stream").Op(":=").Id("want").Dot("ContentStream").Call(),
+			jen.Id("stream").Dot("ContentType").Op("=").Lit("text/csv"),
+			jen.Id("stream").Dot("Filename").Op("=").Lit("export.csv"),
+			jen.Id("stream").Dot("Body").Op("=").Qual("io", "NopCloser").Call(
+				jen.Qual("strings", "NewReader").Call(jen.Lit("id,name\n1,jk\n"))),
+		}
+		checkResponse = []jen.Code{
+			jen.Id("got").Op(":=").Id("resp").Dot("ContentStream").Call(),
+			jen.Defer().Id("got").Dot("Body").Dot("Close").Call(),
+			jen.List(jen.Id("body"), jen.Err()).Op(":=").Qual("io", "ReadAll").Call(jen.Id("got").Dot("Body")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(jen.Id("t").Dot("Fatal").Call(jen.Err())),
+			jen.If(
+				jen.Id("got").Dot("ContentType").Op("!=").Lit("text/csv").
+					Op("||").Id("got").Dot("Filename").Op("!=").Lit("export.csv").
+					Op("||").String().Call(jen.Id("body")).Op("!=").Lit("id,name\n1,jk\n"),
+			).Block(
+				jen.Id("t").Dot("Errorf").Call(jen.Lit("client received stream %s %s %q"), jen.Id("got").Dot("ContentType"), jen.Id("got").Dot("Filename"), jen.Id("body")),
+			),
+		}
+	}
+
+	// t.Run("XXX", func(t *testing.T) {
+	g.Id("t").Dot("Run").Call(jen.Lit(name), jen.Func().Params(jen.Id("t").Op("*").Qual("testing", "T")).BlockFunc(func(g *jen.Group) {
+		// req := &REQ{...}
+		g.Id("req").Op(":=").Add(reqSampler.top(reqType, false))
+		for _, code := range want {
+			g.Add(code)
+		}
+		// received := make(chan *REQ, 1)
+		g.Id("received").Op(":=").Make(jen.Chan().Op("*").Add(method.RequestTypeCodeJen()), jen.Lit(1))
+		// svc.xxx = func(ctx context.Context, r *REQ) (*RESP, error) {
+		//   received <- r
+		//   return want, nil
+		// }
+		g.Id("svc").Dot(strcase.ToLowerCamel(name)).Op("=").Func().
+			Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("r").Op("*").Add(method.RequestTypeCodeJen())).
+			Params(method.ResultTypeCodeJen(), jen.Error()).
+			BlockFunc(func(g *jen.Group) {
+				g.Id("received").Op("<-").Id("r")
+				for _, code := range serve {
+					g.Add(code)
+				}
+			})
+		g.Line()
+		// resp, err := client.XXX(context.Background(), req)
+		g.List(jen.Id("resp"), jen.Err()).Op(":=").Id("client").Dot(name).
+			Call(jen.Qual("context", "Background").Call(), jen.Id("req"))
+		g.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Id("t").Dot("Fatalf").Call(jen.Lit(fmt.Sprintf("%s: %%v", name)), jen.Err()),
+		)
+		g.Line()
+		g.Select().Block(
+			jen.Case(jen.Id("got").Op(":=").Op("<-").Id("received")).Block(
+				jen.If(jen.Op("!").Qual("reflect", "DeepEqual").Call(jen.Id("got"), jen.Id("req"))).Block(
+					jen.Id("t").Dot("Errorf").Call(jen.Lit("server received %+v, want %+v"), jen.Id("got"), jen.Id("req")),
+				),
+			),
+			jen.Default().Block(
+				jen.Id("t").Dot("Fatal").Call(jen.Lit("service method was not called")),
+			),
+		)
+		for _, code := range checkResponse {
+			g.Add(code)
+		}
+	}))
+}
+
+// GenerateRoundTripTest 生成传输层往返测试：用 httptest.Server 启动 framework 对应的服务端，
+// 通过生成的 Go 客户端调用每个方法，检查请求和响应在编解码前后保持一致。
+//
+// 测试依赖 endpoints 和 Go HTTP 客户端生成的代码。
+func GenerateRoundTripTest(f *jen.File, service *domain.Service, framework string) error {
+	common.HTTPPopulateDefaultAnnotations(service)
+	n := newNames(framework)
+
+	generateFakeService(f, service, n)
+	if common.HasAuth(service) {
+		generateCredentials(f, service, n)
+	}
+	err := generateServer(f, service, framework, n)
+	if err != nil {
+		return err
+	}
+
+	// func TestXXXRoundTrip(t *testing.T) {
+	f.Func().Id(n.test).Params(jen.Id("t").Op("*").Qual("testing", "T")).BlockFunc(func(g *jen.Group) {
+		g.Id("svc").Op(":=").Op("&").Id(n.service).Values()
+		g.Id("server").Op(":=").Id(n.server).Call(jen.Id("svc"))
+		g.Defer().Id("server").Dot("Close").Call()
+		g.Line()
+		if common.HasAuth(service) {
+			// client, err := NewHTTPClientWithTokenSource(server.URL, jkhttp.StaticCredentials(xxxRoundTripCredentials...))
+			g.List(jen.Id("client"), jen.Err()).Op(":=").Id("NewHTTPClientWithTokenSource").Call(
+				jen.Id("server").Dot("URL"),
+				jen.Qual(utils.FilePackage, "StaticCredentials").Call(jen.Id(n.credentials).Op("...")))
+		} else {
+			g.List(jen.Id("client"), jen.Err()).Op(":=").Id("NewHTTPClient").Call(jen.Id("server").Dot("URL"))
+		}
+		g.If(jen.Err().Op("!=").Nil()).Block(jen.Id("t").Dot("Fatal").Call(jen.Err()))
+		g.Line()
+
+		for i, method := range service.Methods {
+			if !method.Func.Exported() {
+				continue
+			}
+			if i > 0 {
+				g.Line()
+			}
+			generateMethodTest(g, n, method)
+		}
+	}).Line()
+
+	return nil
+}
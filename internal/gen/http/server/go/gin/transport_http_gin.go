@@ -8,9 +8,10 @@ import (
 	"github.com/iancoleman/strcase"
 	"github.com/nnnewb/jk/internal/domain"
 	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/utils"
 )
 
-func generateCommonCode(f *jen.File) {
+func generateCommonCode(f *jen.File, service *domain.Service) {
 	// type GenericEndpoint[Request, Response any] func(ctx context.Context, req *Request) (*Response, error)
 	// f.Type().Id("GenericEndpoint").Types(jen.Id("Request").Any(), jen.Id("Response").Any()).
 	// 	Func().
@@ -33,8 +34,13 @@ func generateCommonCode(f *jen.File) {
 		Error().
 		Line()
 
+	// 查询参数按 JSON 字段名解析，AliasFormTags 兼容按 form 标签发送参数的旧客户端
 	// func QueryStringDecoder(c *gin.Context, req any) error {
-	// 	return c.BindQuery(req)
+	// 	decoder := schema.NewDecoder()
+	// 	decoder.SetAliasTag("json")
+	// 	values := c.Request.URL.Query()
+	// 	jkhttp.AliasFormTags(req, values)
+	// 	return decoder.Decode(req, values)
 	// }
 	f.Func().Id("QueryStringDecoder").
 		Params(
@@ -43,8 +49,12 @@ func generateCommonCode(f *jen.File) {
 		).
 		Error().
 		Block(
+			jen.Id("decoder").Op(":=").Qual("github.com/gorilla/schema", "NewDecoder").Call(),
+			jen.Id("decoder").Dot("SetAliasTag").Call(jen.Lit("json")),
+			jen.Id("values").Op(":=").Id("c").Dot("Request").Dot("URL").Dot("Query").Call(),
+			jen.Qual(utils.FilePackage, "AliasFormTags").Call(jen.Id("req"), jen.Id("values")),
 			jen.Return(
-				jen.Id("c").Dot("BindQuery").Call(jen.Id("req")),
+				jen.Id("decoder").Dot("Decode").Call(jen.Id("req"), jen.Id("values")),
 			),
 		).
 		Line()
@@ -65,6 +75,54 @@ func generateCommonCode(f *jen.File) {
 		).
 		Line()
 
+	if common.HasContentType(service, common.ContentTypeForm) {
+		// func FormDecoder(c *gin.Context, req any) error {
+		// 	err := c.Request.ParseForm()
+		// 	if err != nil {
+		// 		return err
+		// 	}
+		// 	decoder := schema.NewDecoder()
+		// 	decoder.SetAliasTag("json")
+		// 	decoder.IgnoreUnknownKeys(true)
+		// 	return decoder.Decode(req, c.Request.PostForm)
+		// }
+		f.Func().Id("FormDecoder").
+			Params(
+				jen.Id("c").Op("*").Qual("github.com/gin-gonic/gin", "Context"),
+				jen.Id("req").Any(),
+			).
+			Error().
+			Block(
+				jen.Err().Op(":=").Id("c").Dot("Request").Dot("ParseForm").Call(),
+				jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err())),
+				jen.Id("decoder").Op(":=").Qual("github.com/gorilla/schema", "NewDecoder").Call(),
+				jen.Id("decoder").Dot("SetAliasTag").Call(jen.Lit("json")),
+				jen.Id("decoder").Dot("IgnoreUnknownKeys").Call(jen.True()),
+				jen.Return(
+					jen.Id("decoder").Dot("Decode").Call(jen.Id("req"), jen.Id("c").Dot("Request").Dot("PostForm")),
+				),
+			).
+			Line()
+	}
+
+	if common.HasContentType(service, common.ContentTypeMultipart) {
+		// func MultipartDecoder(c *gin.Context, req any) error {
+		// 	return jkhttp.DecodeMultipartRequest(c.Request, req)
+		// }
+		f.Func().Id("MultipartDecoder").
+			Params(
+				jen.Id("c").Op("*").Qual("github.com/gin-gonic/gin", "Context"),
+				jen.Id("req").Any(),
+			).
+			Error().
+			Block(
+				jen.Return(
+					jen.Qual(utils.FilePackage, "DecodeMultipartRequest").Call(jen.Id("c").Dot("Request"), jen.Id("req")),
+				),
+			).
+			Line()
+	}
+
 	// type ResponseEncoder func(c *gin.Context, resp any)
 	f.Type().Id("ResponseEncoder").
 		Func().
@@ -87,6 +145,238 @@ func generateCommonCode(f *jen.File) {
 		).
 		Line()
 
+	if common.HasCodecs(service) {
+		// func CodecBodyDecoder(c *gin.Context, req any) error {
+		// 	return jkhttp.DecodeRequest(c.Request, req)
+		// }
+		f.Func().Id("CodecBodyDecoder").
+			Params(
+				jen.Id("c").Op("*").Qual("github.com/gin-gonic/gin", "Context"),
+				jen.Id("req").Any(),
+			).
+			Error().
+			Block(
+				jen.Return(
+					jen.Qual(utils.FilePackage, "DecodeRequest").Call(jen.Id("c").Dot("Request"), jen.Id("req")),
+				),
+			).
+			Line()
+
+		// func CodecBodyEncoder(c *gin.Context, resp any) {
+		// 	err := jkhttp.EncodeResponse(c.Writer, c.GetHeader("Accept"), resp)
+		// 	if err != nil {
+		// 		c.AbortWithStatusJSON(500, gin.H{
+		// 			"code":    -1,
+		// 			"message": fmt.Sprintf("unable to encode response, error %v", err),
+		// 		})
+		// 	}
+		// }
+		f.Func().Id("CodecBodyEncoder").
+			Params(
+				jen.Id("c").Op("*").Qual("github.com/gin-gonic/gin", "Context"),
+				jen.Id("resp").Any(),
+			).
+			Block(
+				jen.Err().Op(":=").Qual(utils.FilePackage, "EncodeResponse").
+					Call(jen.Id("c").Dot("Writer"), jen.Id("c").Dot("GetHeader").Call(jen.Lit("Accept")), jen.Id("resp")),
+				jen.If(jen.Err().Op("!=").Nil()).Block(
+					jen.Id("c").Dot("AbortWithStatusJSON").
+						Call(
+							jen.Lit(500),
+							jen.Qual("github.com/gin-gonic/gin", "H").
+								Values(jen.Dict{
+									jen.Lit("code"):    jen.Lit(-1),
+									jen.Lit("message"): jen.Qual("fmt", "Sprintf").Call(jen.Lit("unable to encode response, error %v"), jen.Err()),
+								}),
+						),
+				),
+			).
+			Line()
+	}
+
+	if common.HasStream(service) {
+		// func StreamEncoder(c *gin.Context, resp any) {
+		// 	err := jkhttp.WriteStream(c.Writer, resp)
+		// 	if err != nil {
+		// 		c.AbortWithStatusJSON(500, gin.H{
+		// 			"code":    -1,
+		// 			"message": fmt.Sprintf("unable to write response stream, error %v", err),
+		// 		})
+		// 	}
+		// }
+		f.Func().Id("StreamEncoder").
+			Params(
+				jen.Id("c").Op("*").Qual("github.com/gin-gonic/gin", "Context"),
+				jen.Id("resp").Any(),
+			).
+			Block(
+				jen.Err().Op(":=").Qual(utils.FilePackage, "WriteStream").Call(jen.Id("c").Dot("Writer"), jen.Id("resp")),
+				jen.If(jen.Err().Op("!=").Nil()).Block(
+					jen.Id("c").Dot("AbortWithStatusJSON").
+						Call(
+							jen.Lit(500),
+							jen.Qual("github.com/gin-gonic/gin", "H").
+								Values(jen.Dict{
+									jen.Lit("code"):    jen.Lit(-1),
+									jen.Lit("message"): jen.Qual("fmt", "Sprintf").Call(jen.Lit("unable to write response stream, error %v"), jen.Err()),
+								}),
+						),
+				),
+			).
+			Line()
+	}
+
+	if common.HasEventStream(service) {
+		// func EventStreamEncoder(c *gin.Context, resp any) {
+		// 	err := jkhttp.WriteEvents(c.Request.Context(), c.Writer, resp)
+		// 	if err != nil {
+		// 		c.AbortWithStatusJSON(500, gin.H{
+		// 			"code":    -1,
+		// 			"message": fmt.Sprintf("unable to write event stream, error %v", err),
+		// 		})
+		// 	}
+		// }
+		f.Func().Id("EventStreamEncoder").
+			Params(
+				jen.Id("c").Op("*").Qual("github.com/gin-gonic/gin", "Context"),
+				jen.Id("resp").Any(),
+			).
+			Block(
+				jen.Err().Op(":=").Qual(utils.FilePackage, "WriteEvents").
+					Call(jen.Id("c").Dot("Request").Dot("Context").Call(), jen.Id("c").Dot("Writer"), jen.Id("resp")),
+				jen.If(jen.Err().Op("!=").Nil()).Block(
+					jen.Id("c").Dot("AbortWithStatusJSON").
+						Call(
+							jen.Lit(500),
+							jen.Qual("github.com/gin-gonic/gin", "H").
+								Values(jen.Dict{
+									jen.Lit("code"):    jen.Lit(-1),
+									jen.Lit("message"): jen.Qual("fmt", "Sprintf").Call(jen.Lit("unable to write event stream, error %v"), jen.Err()),
+								}),
+						),
+				),
+			).
+			Line()
+	}
+
+	if common.HasIdempotent(service) {
+		// type ginIdempotencyWriter struct {
+		// 	gin.ResponseWriter
+		// 	w http.ResponseWriter
+		// }
+		f.Comment("ginIdempotencyWriter 把 gin 写入的响应转发给 jkhttp.Idempotent 的记录器，其他方法使用原来的 gin.ResponseWriter。")
+		f.Type().Id("ginIdempotencyWriter").Struct(
+			jen.Qual("github.com/gin-gonic/gin", "ResponseWriter"),
+			jen.Id("w").Qual("net/http", "ResponseWriter"),
+		).Line()
+
+		// func (w *ginIdempotencyWriter) WriteHeader(status int) { w.w.WriteHeader(status) }
+		f.Func().Params(jen.Id("w").Op("*").Id("ginIdempotencyWriter")).
+			Id("WriteHeader").
+			Params(jen.Id("status").Int()).
+			Block(jen.Id("w").Dot("w").Dot("WriteHeader").Call(jen.Id("status"))).
+			Line()
+
+		// func (w *ginIdempotencyWriter) Write(data []byte) (int, error) { return w.w.Write(data) }
+		f.Func().Params(jen.Id("w").Op("*").Id("ginIdempotencyWriter")).
+			Id("Write").
+			Params(jen.Id("data").Index().Byte()).
+			Params(jen.Int(), jen.Error()).
+			Block(jen.Return(jen.Id("w").Dot("w").Dot("Write").Call(jen.Id("data")))).
+			Line()
+
+		// func (w *ginIdempotencyWriter) WriteString(s string) (int, error) { return w.w.Write([]byte(s)) }
+		f.Func().Params(jen.Id("w").Op("*").Id("ginIdempotencyWriter")).
+			Id("WriteString").
+			Params(jen.Id("s").String()).
+			Params(jen.Int(), jen.Error()).
+			Block(jen.Return(jen.Id("w").Dot("w").Dot("Write").Call(jen.Index().Byte().Parens(jen.Id("s"))))).
+			Line()
+
+		// func IdempotentHandler(store jkhttp.IdempotencyStore, required bool, handler gin.HandlerFunc) gin.HandlerFunc {
+		// 	return func(c *gin.Context) {
+		// 		writer := c.Writer
+		// 		defer func() { c.Writer = writer }()
+		// 		jkhttp.Idempotent(store, required, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 			c.Request = r
+		// 			c.Writer = &ginIdempotencyWriter{ResponseWriter: writer, w: w}
+		// 			handler(c)
+		// 		})).ServeHTTP(writer, c.Request)
+		// 	}
+		// }
+		f.Comment("IdempotentHandler 为 @idempotent 方法的 handler 实现幂等键，见 jkhttp.Idempotent。")
+		f.Func().Id("IdempotentHandler").
+			Params(
+				jen.Id("store").Qual(utils.FilePackage, "IdempotencyStore"),
+				jen.Id("required").Bool(),
+				jen.Id("handler").Qual("github.com/gin-gonic/gin", "HandlerFunc"),
+			).
+			Qual("github.com/gin-gonic/gin", "HandlerFunc").
+			Block(
+				jen.Return(jen.Func().Params(jen.Id("c").Op("*").Qual("github.com/gin-gonic/gin", "Context")).Block(
+					jen.Id("writer").Op(":=").Id("c").Dot("Writer"),
+					jen.Defer().Func().Params().Block(jen.Id("c").Dot("Writer").Op("=").Id("writer")).Call(),
+					jen.Qual(utils.FilePackage, "Idempotent").Call(
+						jen.Id("store"),
+						jen.Id("required"),
+						jen.Qual("net/http", "HandlerFunc").Call(
+							jen.Func().Params(
+								jen.Id("w").Qual("net/http", "ResponseWriter"),
+								jen.Id("r").Op("*").Qual("net/http", "Request"),
+							).Block(
+								jen.Id("c").Dot("Request").Op("=").Id("r"),
+								jen.Id("c").Dot("Writer").Op("=").Op("&").Id("ginIdempotencyWriter").Values(jen.Dict{
+									jen.Id("ResponseWriter"): jen.Id("writer"),
+									jen.Id("w"):              jen.Id("w"),
+								}),
+								jen.Id("handler").Call(jen.Id("c")),
+							),
+						),
+					).Dot("ServeHTTP").Call(jen.Id("writer"), jen.Id("c").Dot("Request")),
+				)),
+			).
+			Line()
+	}
+
+	if common.HasAuth(service) {
+		// func AuthHandler(auth jkhttp.Authenticator, scheme string, handler gin.HandlerFunc) gin.HandlerFunc {
+		// 	return func(c *gin.Context) {
+		// 		jkhttp.Authenticate(auth, scheme, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 			c.Request = r
+		// 			handler(c)
+		// 		})).ServeHTTP(c.Writer, c.Request)
+		// 	}
+		// }
+		f.Comment("AuthHandler 用 auth 校验请求按 scheme 携带的凭据，通过后调用 handler，见 jkhttp.Authenticate。")
+		f.Func().Id("AuthHandler").
+			Params(
+				jen.Id("auth").Qual(utils.FilePackage, "Authenticator"),
+				jen.Id("scheme").String(),
+				jen.Id("handler").Qual("github.com/gin-gonic/gin", "HandlerFunc"),
+			).
+			Qual("github.com/gin-gonic/gin", "HandlerFunc").
+			Block(
+				jen.Return(jen.Func().Params(jen.Id("c").Op("*").Qual("github.com/gin-gonic/gin", "Context")).Block(
+					jen.Qual(utils.FilePackage, "Authenticate").Call(
+						jen.Id("auth"),
+						jen.Id("scheme"),
+						jen.Qual("net/http", "HandlerFunc").Call(
+							jen.Func().Params(
+								jen.Id("w").Qual("net/http", "ResponseWriter"),
+								jen.Id("r").Op("*").Qual("net/http", "Request"),
+							).Block(
+								jen.Id("c").Dot("Request").Op("=").Id("r"),
+								jen.Id("handler").Call(jen.Id("c")),
+							),
+						),
+					).Dot("ServeHTTP").Call(jen.Id("c").Dot("Writer"), jen.Id("c").Dot("Request")),
+				)),
+			).
+			Line()
+	}
+
+	common.GenerateErrorStatus(f, service, "errorStatus")
+
 	// func Handler[Request, Response any](ep GenericEndpoint[Request, Response], decoder RequestDecoder, encoder ResponseEncoder) gin.HandlerFunc {
 	// 	return func(c *gin.Context) {
 	// 		var req = new(Request)
@@ -100,6 +390,13 @@ func generateCommonCode(f *jen.File) {
 	// 		}
 	//
 	// 		resp, err := ep(c.Request.Context(), req)
+	// 		if err != nil {
+	// 			c.AbortWithStatusJSON(errorStatus(err), gin.H{
+	// 				"code":    -1,
+	// 				"message": fmt.Sprintf("error occurred: %v", err),
+	// 			})
+	// 			return
+	// 		}
 	// 		encoder(c, resp)
 	// 	}
 	// }
@@ -135,6 +432,19 @@ func generateCommonCode(f *jen.File) {
 								jen.Id("c").Dot("Request").Dot("Context").Call(),
 								jen.Id("req"),
 							)
+						// 端点出错时响应为 nil，不能交给 encoder
+						g.If(jen.Err().Op("!=").Nil()).BlockFunc(func(g *jen.Group) {
+							g.Id("c").Dot("AbortWithStatusJSON").
+								Call(
+									jen.Id("errorStatus").Call(jen.Err()),
+									jen.Qual("github.com/gin-gonic/gin", "H").
+										Values(jen.Dict{
+											jen.Lit("code"):    jen.Lit(-1),
+											jen.Lit("message"): jen.Qual("fmt", "Sprintf").Call(jen.Lit("error occurred: %v"), jen.Err()),
+										}),
+								)
+							g.Return()
+						})
 						g.Id("encoder").Call(jen.Id("c"), jen.Id("resp"))
 					}))
 		})
@@ -145,6 +455,14 @@ func generateGinServerSet(f *jen.File, service *domain.Service) {
 		for _, method := range service.Methods {
 			g.Id(method.Func.Name()+"Handler").Qual("github.com/gin-gonic/gin", "HandlerFunc")
 		}
+		if common.HasIdempotent(service) {
+			// IdempotencyStore 保存 @idempotent 方法的响应，默认保存在内存中，多实例部署时应替换为共享存储
+			g.Id("IdempotencyStore").Qual(utils.FilePackage, "IdempotencyStore")
+		}
+		if common.HasAuth(service) {
+			// Authenticator 校验需要认证的方法的凭据，没有设置时这些方法返回 500
+			g.Id("Authenticator").Qual(utils.FilePackage, "Authenticator")
+		}
 	}).Line()
 
 	f.Func().Id("NewGinServerSet").
@@ -153,7 +471,18 @@ func generateGinServerSet(f *jen.File, service *domain.Service) {
 		BlockFunc(func(g *jen.Group) {
 			g.Return(jen.Op("&").Id("GinServerSet").
 				Values(jen.DictFunc(func(d jen.Dict) {
+					defaultEncoder := jen.Id("JSONBodyEncoder")
+					if common.HasCodecs(service) {
+						defaultEncoder = jen.Id("CodecBodyEncoder")
+					}
 					for _, method := range service.Methods {
+						encoder := defaultEncoder
+						switch {
+						case method.IsEventStream():
+							encoder = jen.Id("EventStreamEncoder")
+						case common.IsStream(method):
+							encoder = jen.Id("StreamEncoder")
+						}
 						switch method.Annotations.HTTPMethod {
 						case http.MethodGet, http.MethodDelete:
 							d[jen.Id(method.Func.Name()+"Handler")] = jen.
@@ -162,19 +491,32 @@ func generateGinServerSet(f *jen.File, service *domain.Service) {
 								Call(
 									jen.Id("eps").Dot(method.Func.Name()+"Endpoint"),
 									jen.Id("QueryStringDecoder"),
-									jen.Id("JSONBodyEncoder"),
+									encoder,
 								)
 						case http.MethodPost, http.MethodPut, http.MethodPatch:
+							decoder := jen.Id("JSONBodyDecoder")
+							switch {
+							case common.IsMultipart(method):
+								decoder = jen.Id("MultipartDecoder")
+							case common.IsForm(method):
+								decoder = jen.Id("FormDecoder")
+							case common.HasCodecs(service):
+								decoder = jen.Id("CodecBodyDecoder")
+							}
 							d[jen.Id(method.Func.Name()+"Handler")] = jen.
 								Id("Handler").
 								Types(method.RequestTypeCodeJen()).
 								Call(
 									jen.Id("eps").Dot(method.Func.Name()+"Endpoint"),
-									jen.Id("JSONBodyDecoder"),
-									jen.Id("JSONBodyEncoder"),
+									decoder,
+									encoder,
 								)
 						}
 					}
+					if common.HasIdempotent(service) {
+						d[jen.Id("IdempotencyStore")] = jen.Qual(utils.FilePackage, "NewMemoryIdempotencyStore").
+							Call(jen.Qual(utils.FilePackage, "DefaultIdempotencyTTL"))
+					}
 				})))
 		}).Line()
 
@@ -184,10 +526,18 @@ func generateGinServerSet(f *jen.File, service *domain.Service) {
 		Params(jen.Id("router").Qual("github.com/gin-gonic/gin", "IRouter")).
 		BlockFunc(func(g *jen.Group) {
 			for _, method := range service.Methods {
+				handler := jen.Id("s").Dot(method.Func.Name() + "Handler")
+				if method.Annotations.Idempotent {
+					handler = jen.Id("IdempotentHandler").Call(jen.Id("s").Dot("IdempotencyStore"), jen.Lit(method.IdempotencyKeyRequired()), handler)
+				}
+				if scheme := method.AuthScheme(); scheme != "" {
+					// 先认证再检查幂等键，未认证的请求不会重放其他人的响应
+					handler = jen.Id("AuthHandler").Call(jen.Id("s").Dot("Authenticator"), common.AuthSchemeJen(scheme), handler)
+				}
 				g.Id("router").Dot(method.Annotations.HTTPMethod).
 					Call(
 						jen.Lit(method.Annotations.HTTPPath),
-						jen.Id("s").Dot(method.Func.Name()+"Handler"),
+						handler,
 					)
 			}
 		}).Line()
@@ -236,7 +586,7 @@ func GenerateGinEmbedSwaggerUI(f *jen.File, service *domain.Service) {
 
 func GenerateGin(f *jen.File, service *domain.Service) error {
 	common.HTTPPopulateDefaultAnnotations(service)
-	generateCommonCode(f)
+	generateCommonCode(f, service)
 	generateGinServerSet(f, service)
 	return nil
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/nnnewb/battery/slices"
 	"github.com/nnnewb/jk/internal/domain"
 	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/utils"
 )
 
 func generateServerSet(f *jen.File, service *domain.Service) {
@@ -27,6 +28,14 @@ func generateServerSet(f *jen.File, service *domain.Service) {
 			// XXXServer: *http.Server,
 			g.Id(method.Name()+"Server").Op("*").Qual("github.com/go-kit/kit/transport/http", "Server")
 		}
+		if common.HasIdempotent(service) {
+			// IdempotencyStore 保存 @idempotent 方法的响应，默认保存在内存中，多实例部署时应替换为共享存储
+			g.Id("IdempotencyStore").Qual(utils.FilePackage, "IdempotencyStore")
+		}
+		if common.HasAuth(service) {
+			// Authenticator 校验需要认证的方法的凭据，没有设置时这些方法返回 500
+			g.Id("Authenticator").Qual(utils.FilePackage, "Authenticator")
+		}
 	}).Line()
 
 	// func NewHTTPServerSet(endpointSet EndpointSet, options ...http.ServerOption) HTTPServerSet {
@@ -71,6 +80,25 @@ func generateServerSet(f *jen.File, service *domain.Service) {
 					default:
 						log.Printf("unexpected http-method annotation %s for method %s, fallback to POST", strings.ToUpper(methodData.Annotations.HTTPMethod), method.Name())
 					}
+					codecs := common.HasCodecs(service)
+					switch {
+					case common.IsMultipart(methodData):
+						httpRequestDecoder = jen.Id("httpMultipartRequestDecoder")
+					case common.IsForm(methodData):
+						httpRequestDecoder = jen.Id("httpFormRequestDecoder")
+					case codecs && common.IsJSONBody(methodData):
+						httpRequestDecoder = jen.Id("httpCodecRequestDecoder")
+					}
+
+					httpResponseEncoder := jen.Qual("github.com/go-kit/kit/transport/http", "EncodeJSONResponse")
+					switch {
+					case methodData.IsEventStream():
+						httpResponseEncoder = jen.Qual(utils.FilePackage, "EncodeEventStreamResponse")
+					case common.IsStream(methodData):
+						httpResponseEncoder = jen.Qual(utils.FilePackage, "EncodeStreamResponse")
+					case codecs:
+						httpResponseEncoder = jen.Id("httpCodecResponseEncoder")
+					}
 
 					// XXXServer: khttp.NewServer(
 					//   endpointSet.XXXEndpoint,
@@ -82,9 +110,14 @@ func generateServerSet(f *jen.File, service *domain.Service) {
 						Call(
 							jen.Line().Id("endpointSet").Dot(method.Name()+"Endpoint"),
 							jen.Line().Add(httpRequestDecoder).Types(jen.Qual(reqType.Obj().Pkg().Path(), reqType.Obj().Name())),
-							jen.Line().Qual("github.com/go-kit/kit/transport/http", "EncodeJSONResponse"),
+							jen.Line().Add(httpResponseEncoder),
 							jen.Line().Id("options").Op("..."))
 				}
+				if common.HasIdempotent(service) {
+					// IdempotencyStore: jkhttp.NewMemoryIdempotencyStore(jkhttp.DefaultIdempotencyTTL),
+					d[jen.Id("IdempotencyStore")] = jen.Qual(utils.FilePackage, "NewMemoryIdempotencyStore").
+						Call(jen.Qual(utils.FilePackage, "DefaultIdempotencyTTL"))
+				}
 			})))
 		}).Line()
 }
@@ -117,9 +150,23 @@ func generateRegister(f *jen.File, service *domain.Service) {
 						g.Switch(jen.Id("req").Dot("Method")).BlockFunc(func(g *jen.Group) {
 							for _, method := range methods {
 								g.Case(method.HTTPMethodJen())
-								g.Id("s").
-									Dot(method.Func.Name()+"Server").
-									Dot("ServeHTTP").Call(jen.Id("wr"), jen.Id("req"))
+								handler := jen.Id("s").Dot(method.Func.Name() + "Server")
+								if method.Annotations.Idempotent {
+									// jkhttp.Idempotent(s.IdempotencyStore, required, s.XXServer).ServeHTTP(wr, req)
+									handler = jen.Qual(utils.FilePackage, "Idempotent").Call(
+										jen.Id("s").Dot("IdempotencyStore"),
+										jen.Lit(method.IdempotencyKeyRequired()),
+										handler)
+								}
+								if scheme := method.AuthScheme(); scheme != "" {
+									// jkhttp.Authenticate(s.Authenticator, jkhttp.AuthBearer, handler).ServeHTTP(wr, req)
+									// 先认证再检查幂等键，未认证的请求不会重放其他人的响应
+									handler = jen.Qual(utils.FilePackage, "Authenticate").Call(
+										jen.Id("s").Dot("Authenticator"),
+										common.AuthSchemeJen(scheme),
+										handler)
+								}
+								g.Add(handler).Dot("ServeHTTP").Call(jen.Id("wr"), jen.Id("req"))
 								g.Return()
 							}
 							g.Default()
@@ -159,8 +206,8 @@ func generateHTTPJSONRequestDecoder(f *jen.File) {
 			// err := json.NewDecoder(req.Body).Decode(&request)
 			g.Err().Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Id("req").Dot("Body")).Dot("Decode").
 				Call(jen.Op("&").Id("request"))
-			// if err != nil { return nil, err }
-			g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err()))
+			// if err != nil { return nil, httpRequestError{err} }
+			g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Id("httpRequestError").Values(jen.Err())))
 			// return request,nil
 			g.Return(jen.Op("&").Id("request"), jen.Nil())
 		}).Line()
@@ -183,20 +230,164 @@ func generateHTTPQueryStringRequestDecoder(f *jen.File) {
 			g.Var().Id("request").Id("T")
 			// defer req.Body.Close()
 			g.Defer().Id("req").Dot("Body").Dot("Close").Call()
-			// err := schema.NewDecoder().Decode(&request, req.URL.RawQuery)
-			g.Err().Op(":=").
-				Qual("github.com/gorilla/schema", "NewDecoder").Call().
-				Dot("Decode").Call(jen.Op("&").Id("request"), jen.Id("req").Dot("URL").Dot("Query").Call())
+			// decoder := schema.NewDecoder()
+			g.Id("decoder").Op(":=").Qual("github.com/gorilla/schema", "NewDecoder").Call()
+			// decoder.SetAliasTag("json")
+			g.Id("decoder").Dot("SetAliasTag").Call(jen.Lit("json"))
+			// 兼容按 form 或 schema 标签发送参数的旧客户端
+			// values := req.URL.Query()
+			g.Id("values").Op(":=").Id("req").Dot("URL").Dot("Query").Call()
+			// jkhttp.AliasFormTags(&request, values)
+			g.Qual(utils.FilePackage, "AliasFormTags").Call(jen.Op("&").Id("request"), jen.Id("values"))
+			// err := decoder.Decode(&request, values)
+			g.Err().Op(":=").Id("decoder").Dot("Decode").Call(jen.Op("&").Id("request"), jen.Id("values"))
 
-			// if err != nil { return nil, err }
-			g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err()))
+			// if err != nil { return nil, httpRequestError{err} }
+			g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Id("httpRequestError").Values(jen.Err())))
+			// return &request, nil
+			g.Return(jen.Op("&").Id("request"), jen.Nil())
+		}).Line()
+}
+
+func generateHTTPFormRequestDecoder(f *jen.File) {
+	// func httpFormRequestDecoder[T any](ctx context.Context, req *http.Request) (any, error) {
+	f.Func().
+		Id("httpFormRequestDecoder").
+		Types(jen.Id("T").Any()).
+		Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("req").Op("*").Qual("net/http", "Request"),
+		).
+		Params(
+			jen.Any(),
+			jen.Error()).
+		BlockFunc(func(g *jen.Group) {
+			// var request T
+			g.Var().Id("request").Id("T")
+			// err := req.ParseForm()
+			g.Err().Op(":=").Id("req").Dot("ParseForm").Call()
+			// if err != nil { return nil, httpRequestError{err} }
+			g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Id("httpRequestError").Values(jen.Err())))
+			// decoder := schema.NewDecoder()
+			g.Id("decoder").Op(":=").Qual("github.com/gorilla/schema", "NewDecoder").Call()
+			// decoder.SetAliasTag("json")
+			g.Id("decoder").Dot("SetAliasTag").Call(jen.Lit("json"))
+			// 回调通常包含请求结构体没有声明的字段
+			// decoder.IgnoreUnknownKeys(true)
+			g.Id("decoder").Dot("IgnoreUnknownKeys").Call(jen.True())
+			// err = decoder.Decode(&request, req.PostForm)
+			g.Err().Op("=").Id("decoder").Dot("Decode").Call(jen.Op("&").Id("request"), jen.Id("req").Dot("PostForm"))
+			// if err != nil { return nil, httpRequestError{err} }
+			g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Id("httpRequestError").Values(jen.Err())))
 			// return &request, nil
 			g.Return(jen.Op("&").Id("request"), jen.Nil())
 		}).Line()
 }
 
+func generateHTTPMultipartRequestDecoder(f *jen.File) {
+	// func httpMultipartRequestDecoder[T any](ctx context.Context, req *http.Request) (any, error) {
+	f.Func().
+		Id("httpMultipartRequestDecoder").
+		Types(jen.Id("T").Any()).
+		Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("req").Op("*").Qual("net/http", "Request"),
+		).
+		Params(
+			jen.Any(),
+			jen.Error()).
+		BlockFunc(func(g *jen.Group) {
+			// var request T
+			g.Var().Id("request").Id("T")
+			// err := jkhttp.DecodeMultipartRequest(req, &request)
+			g.Err().Op(":=").Qual(utils.FilePackage, "DecodeMultipartRequest").Call(jen.Id("req"), jen.Op("&").Id("request"))
+			// if err != nil { return nil, httpRequestError{err} }
+			g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Id("httpRequestError").Values(jen.Err())))
+			// return &request, nil
+			g.Return(jen.Op("&").Id("request"), jen.Nil())
+		}).Line()
+}
+
+// generateHTTPCodecHelpers 生成按 Content-Type 解码请求、按 Accept 编码响应的函数。
+func generateHTTPCodecHelpers(f *jen.File) {
+	// func httpCodecRequestDecoder[T any](ctx context.Context, req *http.Request) (any, error) {
+	f.Func().
+		Id("httpCodecRequestDecoder").
+		Types(jen.Id("T").Any()).
+		Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("req").Op("*").Qual("net/http", "Request"),
+		).
+		Params(
+			jen.Any(),
+			jen.Error()).
+		BlockFunc(func(g *jen.Group) {
+			// var request T
+			g.Var().Id("request").Id("T")
+			// err := jkhttp.DecodeRequest(req, &request)
+			g.Err().Op(":=").Qual(utils.FilePackage, "DecodeRequest").Call(jen.Id("req"), jen.Op("&").Id("request"))
+			// if err != nil { return nil, httpRequestError{err} }
+			g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Id("httpRequestError").Values(jen.Err())))
+			// return &request, nil
+			g.Return(jen.Op("&").Id("request"), jen.Nil())
+		}).Line()
+
+	// func httpCodecResponseEncoder(ctx context.Context, w http.ResponseWriter, response any) error {
+	//   accept, _ := ctx.Value(khttp.ContextKeyRequestAccept).(string)
+	//   return jkhttp.EncodeResponse(w, accept, response)
+	// }
+	f.Func().
+		Id("httpCodecResponseEncoder").
+		Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("w").Qual("net/http", "ResponseWriter"),
+			jen.Id("response").Any(),
+		).
+		Error().
+		Block(
+			jen.List(jen.Id("accept"), jen.Id("_")).Op(":=").Id("ctx").Dot("Value").
+				Call(jen.Qual("github.com/go-kit/kit/transport/http", "ContextKeyRequestAccept")).Assert(jen.String()),
+			jen.Return(jen.Qual(utils.FilePackage, "EncodeResponse").Call(jen.Id("w"), jen.Id("accept"), jen.Id("response"))),
+		).Line()
+}
+
+// generateHTTPRequestError 生成 httpRequestError，解码请求失败时返回 400。
+func generateHTTPRequestError(f *jen.File) {
+	// type httpRequestError struct { err error }
+	f.Comment("httpRequestError 是解码请求失败的错误，错误本身实现了 khttp.StatusCoder 时使用它的状态码，否则是 400。")
+	f.Type().Id("httpRequestError").Struct(jen.Id("err").Error()).Line()
+
+	// func (e httpRequestError) Error() string {
+	//   return fmt.Sprintf("unable to parse request payload, error %v", e.err)
+	// }
+	f.Func().Params(jen.Id("e").Id("httpRequestError")).Id("Error").Params().String().Block(
+		jen.Return(jen.Qual("fmt", "Sprintf").Call(jen.Lit("unable to parse request payload, error %v"), jen.Id("e").Dot("err"))),
+	).Line()
+
+	// func (e httpRequestError) Unwrap() error { return e.err }
+	f.Func().Params(jen.Id("e").Id("httpRequestError")).Id("Unwrap").Params().Error().Block(
+		jen.Return(jen.Id("e").Dot("err")),
+	).Line()
+
+	// func (e httpRequestError) StatusCode() int {
+	//   var coder khttp.StatusCoder
+	//   if errors.As(e.err, &coder) {
+	//     return coder.StatusCode()
+	//   }
+	//   return http.StatusBadRequest
+	// }
+	f.Func().Params(jen.Id("e").Id("httpRequestError")).Id("StatusCode").Params().Int().Block(
+		jen.Var().Id("coder").Qual("github.com/go-kit/kit/transport/http", "StatusCoder"),
+		jen.If(jen.Qual("errors", "As").Call(jen.Id("e").Dot("err"), jen.Op("&").Id("coder"))).Block(
+			jen.Return(jen.Id("coder").Dot("StatusCode").Call()),
+		),
+		jen.Return(jen.Qual("net/http", "StatusBadRequest")),
+	).Line()
+}
+
 func generateBeautifyErrorEncoder(f *jen.File) {
 	// func beautifyErrorEncoder(ctx context.Context, err error, w http.ResponseWriter) {
+	f.Comment("beautifyErrorEncoder 以 httpErrorStatus 返回的状态码和 {\"code\": -1, \"message\": ...} 格式返回错误。")
 	f.Func().
 		Id("beautifyErrorEncoder").
 		Params(
@@ -214,15 +405,31 @@ func generateBeautifyErrorEncoder(f *jen.File) {
 			g.Id("resp").Dot("Message").Op("=").
 				Qual("fmt", "Sprintf").Call(jen.Lit("error occurred: %v"), jen.Err())
 
+			// wr.Header().Set("Content-Type", "application/json; charset=utf-8")
+			// wr.WriteHeader(httpErrorStatus(err))
+			g.Id("wr").Dot("Header").Call().Dot("Set").Call(jen.Lit("Content-Type"), jen.Lit("application/json; charset=utf-8"))
+			g.Id("wr").Dot("WriteHeader").Call(jen.Id("httpErrorStatus").Call(jen.Err()))
 			g.Qual("encoding/json", "NewEncoder").Call(jen.Id("wr")).
 				Dot("Encode").Call(jen.Id("resp"))
 		}).Line()
 }
 
 func GenerateHTTPTransportServer(f *jen.File, svc *domain.Service) {
+	common.HTTPPopulateDefaultAnnotations(svc)
+	common.GenerateErrorStatus(f, svc, "httpErrorStatus")
+	generateHTTPRequestError(f)
 	generateBeautifyErrorEncoder(f)
 	generateHTTPJSONRequestDecoder(f)
 	generateHTTPQueryStringRequestDecoder(f)
+	if common.HasContentType(svc, common.ContentTypeForm) {
+		generateHTTPFormRequestDecoder(f)
+	}
+	if common.HasContentType(svc, common.ContentTypeMultipart) {
+		generateHTTPMultipartRequestDecoder(f)
+	}
+	if common.HasCodecs(svc) {
+		generateHTTPCodecHelpers(f)
+	}
 	generateServerSet(f, svc)
 	generateRegister(f, svc)
 }
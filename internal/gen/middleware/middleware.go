@@ -0,0 +1,352 @@
+package middleware
+
+import (
+	"go/types"
+	"sort"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/dave/jennifer/jen"
+	"github.com/iancoleman/strcase"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+const (
+	pkgSlog          = "log/slog"
+	pkgKitLog        = "github.com/go-kit/log"
+	pkgPrometheus    = "github.com/prometheus/client_golang/prometheus"
+	pkgOtelAttribute = "go.opentelemetry.io/otel/attribute"
+	pkgOtelCodes     = "go.opentelemetry.io/otel/codes"
+	pkgOtelMetric    = "go.opentelemetry.io/otel/metric"
+	pkgOtelTrace     = "go.opentelemetry.io/otel/trace"
+)
+
+// generators 是 --kind 可选的中间件生成函数。
+var generators = map[string]func(f *jen.File, service *domain.Service){
+	"slog":         generateSlogMiddleware,
+	"kitlog":       generateKitLogMiddleware,
+	"prometheus":   generatePrometheusMiddleware,
+	"otel-metrics": generateOtelMetricsMiddleware,
+	"otel-tracing": generateOtelTracingMiddleware,
+}
+
+// Kinds 返回支持的中间件种类。
+func Kinds() []string {
+	ret := make([]string, 0, len(generators))
+	for kind := range generators {
+		ret = append(ret, kind)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// serviceType 返回服务接口类型的代码。
+func serviceType(service *domain.Service) *jen.Statement {
+	return jen.Qual(service.Interface.Obj().Pkg().Path(), service.Name())
+}
+
+// generateMiddlewareStruct 生成中间件结构体 type name struct { next Service; fields... }。
+func generateMiddlewareStruct(f *jen.File, service *domain.Service, name string, fields ...jen.Code) {
+	f.Type().Id(name).StructFunc(func(g *jen.Group) {
+		g.Id("next").Add(serviceType(service))
+		for _, field := range fields {
+			g.Add(field)
+		}
+	}).Line()
+}
+
+// generateMethods 为中间件结构体生成所有接口方法，before 生成调用 next 之前的语句。
+// 方法使用具名返回值 resp 和 err，before 中可以 defer 读取它们。
+func generateMethods(f *jen.File, service *domain.Service, name string, before func(g *jen.Group, method *domain.Method)) {
+	for _, method := range service.Methods {
+		f.Func().
+			Params(jen.Id("mw").Id(name)).
+			Id(method.Func.Name()).
+			Params(
+				jen.Id("ctx").Qual("context", "Context"),
+				jen.Id("req").Op("*").Add(method.RequestTypeCodeJen())).
+			Params(
+				jen.Id("resp").Add(method.ResultTypeCodeJen()),
+				jen.Err().Error()).
+			BlockFunc(func(g *jen.Group) {
+				before(g, method)
+				// return mw.next.XXX(ctx, req)
+				g.Return(jen.Id("mw").Dot("next").Dot(method.Func.Name()).Call(jen.Id("ctx"), jen.Id("req")))
+			}).Line()
+	}
+}
+
+// generateConstructor 生成返回 ServiceMiddleware 的构造函数。
+func generateConstructor(f *jen.File, service *domain.Service, comment, funcName string, params []jen.Code, structName string, values jen.Dict) {
+	values[jen.Id("next")] = jen.Id("next")
+	f.Comment(comment)
+	f.Func().
+		Id(funcName).
+		Params(params...).
+		Id("ServiceMiddleware").
+		Block(
+			jen.Return(jen.Func().Params(jen.Id("next").Add(serviceType(service))).Add(serviceType(service)).Block(
+				jen.Return(jen.Id(structName).Values(values)),
+			)),
+		).Line()
+}
+
+// deferWithDuration 生成 defer func(begin time.Time) { ... }(time.Now())。
+func deferWithDuration(g *jen.Group, body ...jen.Code) {
+	g.Defer().Func().Params(jen.Id("begin").Qual("time", "Time")).Block(body...).Call(jen.Qual("time", "Now").Call())
+}
+
+func generateSlogMiddleware(f *jen.File, service *domain.Service) {
+	generateMiddlewareStruct(f, service, "slogMiddleware", jen.Id("logger").Op("*").Qual(pkgSlog, "Logger"))
+	generateConstructor(f, service,
+		"NewSlogMiddleware 使用 slog 记录每次调用的请求、响应、耗时和错误，出错时使用 Error 级别。",
+		"NewSlogMiddleware",
+		[]jen.Code{jen.Id("logger").Op("*").Qual(pkgSlog, "Logger")},
+		"slogMiddleware",
+		jen.Dict{jen.Id("logger"): jen.Id("logger")})
+
+	generateMethods(f, service, "slogMiddleware", func(g *jen.Group, method *domain.Method) {
+		// defer func(begin time.Time) {
+		//   level := slog.LevelInfo
+		//   if err != nil {
+		//     level = slog.LevelError
+		//   }
+		//   mw.logger.LogAttrs(ctx, level, "call", slog.String("method", "XXX"), ...)
+		// }(time.Now())
+		deferWithDuration(g,
+			jen.Id("level").Op(":=").Qual(pkgSlog, "LevelInfo"),
+			jen.If(jen.Err().Op("!=").Nil()).Block(jen.Id("level").Op("=").Qual(pkgSlog, "LevelError")),
+			jen.Id("mw").Dot("logger").Dot("LogAttrs").Call(
+				jen.Id("ctx"),
+				jen.Id("level"),
+				jen.Lit("call"),
+				jen.Qual(pkgSlog, "String").Call(jen.Lit("method"), jen.Lit(method.Func.Name())),
+				jen.Qual(pkgSlog, "Any").Call(jen.Lit("request"), jen.Id("req")),
+				jen.Qual(pkgSlog, "Any").Call(jen.Lit("response"), jen.Id("resp")),
+				jen.Qual(pkgSlog, "Duration").Call(jen.Lit("duration"), jen.Qual("time", "Since").Call(jen.Id("begin"))),
+				jen.Qual(pkgSlog, "Any").Call(jen.Lit("error"), jen.Err()),
+			),
+		)
+	})
+}
+
+func generateKitLogMiddleware(f *jen.File, service *domain.Service) {
+	generateMiddlewareStruct(f, service, "kitLogMiddleware", jen.Id("logger").Qual(pkgKitLog, "Logger"))
+	generateConstructor(f, service,
+		"NewKitLogMiddleware 使用 go-kit log 记录每次调用的请求、响应、耗时和错误。",
+		"NewKitLogMiddleware",
+		[]jen.Code{jen.Id("logger").Qual(pkgKitLog, "Logger")},
+		"kitLogMiddleware",
+		jen.Dict{jen.Id("logger"): jen.Id("logger")})
+
+	generateMethods(f, service, "kitLogMiddleware", func(g *jen.Group, method *domain.Method) {
+		// defer func(begin time.Time) {
+		//   mw.logger.Log("method", "XXX", "request", req, "response", resp, "duration", time.Since(begin), "err", err)
+		// }(time.Now())
+		deferWithDuration(g,
+			jen.Id("_").Op("=").Id("mw").Dot("logger").Dot("Log").Call(
+				jen.Lit("method"), jen.Lit(method.Func.Name()),
+				jen.Lit("request"), jen.Id("req"),
+				jen.Lit("response"), jen.Id("resp"),
+				jen.Lit("duration"), jen.Qual("time", "Since").Call(jen.Id("begin")),
+				jen.Lit("err"), jen.Err(),
+			),
+		)
+	})
+}
+
+func generatePrometheusMiddleware(f *jen.File, service *domain.Service) {
+	generateMiddlewareStruct(f, service, "prometheusMiddleware",
+		jen.Id("requests").Op("*").Qual(pkgPrometheus, "CounterVec"),
+		jen.Id("failures").Op("*").Qual(pkgPrometheus, "CounterVec"),
+		jen.Id("duration").Op("*").Qual(pkgPrometheus, "HistogramVec"))
+
+	metricName := strcase.ToSnake(service.Name())
+	// func NewPrometheusMiddleware(registerer prometheus.Registerer, namespace string) ServiceMiddleware {
+	f.Comment("NewPrometheusMiddleware 统计每个方法的调用次数、错误次数和耗时，指标按 method 标签区分，并注册到 registerer。")
+	f.Func().
+		Id("NewPrometheusMiddleware").
+		Params(
+			jen.Id("registerer").Qual(pkgPrometheus, "Registerer"),
+			jen.Id("namespace").String()).
+		Id("ServiceMiddleware").
+		BlockFunc(func(g *jen.Group) {
+			counterVec := func(name, help string) jen.Code {
+				return jen.Qual(pkgPrometheus, "NewCounterVec").Call(
+					jen.Qual(pkgPrometheus, "CounterOpts").Values(jen.Dict{
+						jen.Id("Namespace"): jen.Id("namespace"),
+						jen.Id("Subsystem"): jen.Lit(metricName),
+						jen.Id("Name"):      jen.Lit(name),
+						jen.Id("Help"):      jen.Lit(help),
+					}),
+					jen.Index().String().Values(jen.Lit("method")),
+				)
+			}
+			g.Id("requests").Op(":=").Add(counterVec("requests_total", "Number of requests received."))
+			g.Id("failures").Op(":=").Add(counterVec("request_errors_total", "Number of requests returned an error."))
+			g.Id("duration").Op(":=").Qual(pkgPrometheus, "NewHistogramVec").Call(
+				jen.Qual(pkgPrometheus, "HistogramOpts").Values(jen.Dict{
+					jen.Id("Namespace"): jen.Id("namespace"),
+					jen.Id("Subsystem"): jen.Lit(metricName),
+					jen.Id("Name"):      jen.Lit("request_duration_seconds"),
+					jen.Id("Help"):      jen.Lit("Request duration in seconds."),
+					jen.Id("Buckets"):   jen.Qual(pkgPrometheus, "DefBuckets"),
+				}),
+				jen.Index().String().Values(jen.Lit("method")),
+			)
+			g.Id("registerer").Dot("MustRegister").Call(jen.Id("requests"), jen.Id("failures"), jen.Id("duration"))
+			g.Return(jen.Func().Params(jen.Id("next").Add(serviceType(service))).Add(serviceType(service)).Block(
+				jen.Return(jen.Id("prometheusMiddleware").Values(jen.Dict{
+					jen.Id("next"):     jen.Id("next"),
+					jen.Id("requests"): jen.Id("requests"),
+					jen.Id("failures"): jen.Id("failures"),
+					jen.Id("duration"): jen.Id("duration"),
+				})),
+			))
+		}).Line()
+
+	generateMethods(f, service, "prometheusMiddleware", func(g *jen.Group, method *domain.Method) {
+		// defer func(begin time.Time) {
+		//   mw.requests.WithLabelValues("XXX").Inc()
+		//   if err != nil {
+		//     mw.failures.WithLabelValues("XXX").Inc()
+		//   }
+		//   mw.duration.WithLabelValues("XXX").Observe(time.Since(begin).Seconds())
+		// }(time.Now())
+		name := jen.Lit(method.Func.Name())
+		deferWithDuration(g,
+			jen.Id("mw").Dot("requests").Dot("WithLabelValues").Call(name).Dot("Inc").Call(),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Id("mw").Dot("failures").Dot("WithLabelValues").Call(name).Dot("Inc").Call(),
+			),
+			jen.Id("mw").Dot("duration").Dot("WithLabelValues").Call(name).Dot("Observe").Call(
+				jen.Qual("time", "Since").Call(jen.Id("begin")).Dot("Seconds").Call()),
+		)
+	})
+}
+
+func generateOtelMetricsMiddleware(f *jen.File, service *domain.Service) {
+	generateMiddlewareStruct(f, service, "otelMetricsMiddleware",
+		jen.Id("requests").Qual(pkgOtelMetric, "Int64Counter"),
+		jen.Id("failures").Qual(pkgOtelMetric, "Int64Counter"),
+		jen.Id("duration").Qual(pkgOtelMetric, "Float64Histogram"))
+
+	prefix := strcase.ToSnake(service.Name()) + "."
+	// func NewOtelMetricsMiddleware(meter metric.Meter) (ServiceMiddleware, error) {
+	f.Comment("NewOtelMetricsMiddleware 使用 OpenTelemetry 统计每个方法的调用次数、错误次数和耗时，指标带 method 属性。")
+	f.Func().
+		Id("NewOtelMetricsMiddleware").
+		Params(jen.Id("meter").Qual(pkgOtelMetric, "Meter")).
+		Params(jen.Id("ServiceMiddleware"), jen.Error()).
+		BlockFunc(func(g *jen.Group) {
+			instrument := func(id, constructor, name string, options ...jen.Code) {
+				g.List(jen.Id(id), jen.Err()).Op(":=").Id("meter").Dot(constructor).Call(
+					append([]jen.Code{jen.Lit(prefix + name)}, options...)...)
+				g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err()))
+			}
+			instrument("requests", "Int64Counter", "requests",
+				jen.Qual(pkgOtelMetric, "WithDescription").Call(jen.Lit("Number of requests received.")))
+			instrument("failures", "Int64Counter", "request.errors",
+				jen.Qual(pkgOtelMetric, "WithDescription").Call(jen.Lit("Number of requests returned an error.")))
+			instrument("duration", "Float64Histogram", "request.duration",
+				jen.Qual(pkgOtelMetric, "WithDescription").Call(jen.Lit("Request duration in seconds.")),
+				jen.Qual(pkgOtelMetric, "WithUnit").Call(jen.Lit("s")))
+			g.Return(jen.Func().Params(jen.Id("next").Add(serviceType(service))).Add(serviceType(service)).Block(
+				jen.Return(jen.Id("otelMetricsMiddleware").Values(jen.Dict{
+					jen.Id("next"):     jen.Id("next"),
+					jen.Id("requests"): jen.Id("requests"),
+					jen.Id("failures"): jen.Id("failures"),
+					jen.Id("duration"): jen.Id("duration"),
+				})),
+			), jen.Nil())
+		}).Line()
+
+	generateMethods(f, service, "otelMetricsMiddleware", func(g *jen.Group, method *domain.Method) {
+		// defer func(begin time.Time) {
+		//   attrs := metric.WithAttributes(attribute.String("method", "XXX"))
+		//   mw.requests.Add(ctx, 1, attrs)
+		//   if err != nil {
+		//     mw.failures.Add(ctx, 1, attrs)
+		//   }
+		//   mw.duration.Record(ctx, time.Since(begin).Seconds(), attrs)
+		// }(time.Now())
+		deferWithDuration(g,
+			jen.Id("attrs").Op(":=").Qual(pkgOtelMetric, "WithAttributes").Call(
+				jen.Qual(pkgOtelAttribute, "String").Call(jen.Lit("method"), jen.Lit(method.Func.Name()))),
+			jen.Id("mw").Dot("requests").Dot("Add").Call(jen.Id("ctx"), jen.Lit(1), jen.Id("attrs")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Id("mw").Dot("failures").Dot("Add").Call(jen.Id("ctx"), jen.Lit(1), jen.Id("attrs")),
+			),
+			jen.Id("mw").Dot("duration").Dot("Record").Call(
+				jen.Id("ctx"),
+				jen.Qual("time", "Since").Call(jen.Id("begin")).Dot("Seconds").Call(),
+				jen.Id("attrs")),
+		)
+	})
+}
+
+func generateOtelTracingMiddleware(f *jen.File, service *domain.Service) {
+	generateMiddlewareStruct(f, service, "otelTracingMiddleware", jen.Id("tracer").Qual(pkgOtelTrace, "Tracer"))
+	generateConstructor(f, service,
+		"NewOtelTracingMiddleware 为每次调用创建 OpenTelemetry span，出错时记录错误并设置 span 状态。",
+		"NewOtelTracingMiddleware",
+		[]jen.Code{jen.Id("tracer").Qual(pkgOtelTrace, "Tracer")},
+		"otelTracingMiddleware",
+		jen.Dict{jen.Id("tracer"): jen.Id("tracer")})
+
+	generateMethods(f, service, "otelTracingMiddleware", func(g *jen.Group, method *domain.Method) {
+		// ctx, span := mw.tracer.Start(ctx, "Service.XXX")
+		// defer func() {
+		//   if err != nil {
+		//     span.RecordError(err)
+		//     span.SetStatus(codes.Error, err.Error())
+		//   }
+		//   span.End()
+		// }()
+		g.List(jen.Id("ctx"), jen.Id("span")).Op(":=").Id("mw").Dot("tracer").Dot("Start").Call(
+			jen.Id("ctx"),
+			jen.Lit(service.Name()+"."+method.Func.Name()))
+		g.Defer().Func().Params().Block(
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Id("span").Dot("RecordError").Call(jen.Err()),
+				jen.Id("span").Dot("SetStatus").Call(jen.Qual(pkgOtelCodes, "Error"), jen.Err().Dot("Error").Call()),
+			),
+			jen.Id("span").Dot("End").Call(),
+		).Call()
+	})
+}
+
+// GenerateMiddleware 为服务接口生成 kinds 指定的中间件，每种中间件都是一个 ServiceMiddleware。
+func GenerateMiddleware(f *jen.File, service *domain.Service, kinds []string) error {
+	for _, method := range service.Methods {
+		signature := method.Func.Type().(*types.Signature)
+		if err := utils.CheckParams(signature.Params()); err != nil {
+			return errors.Wrapf(err, "check method signature: %s", method.Func.FullName())
+		}
+		if err := utils.CheckResults(signature.Results()); err != nil {
+			return errors.Wrapf(err, "check method signature: %s", method.Func.FullName())
+		}
+	}
+
+	for _, kind := range kinds {
+		if _, ok := generators[kind]; !ok {
+			return errors.Errorf("unsupported middleware kind %q, available kinds: %s", kind, strings.Join(Kinds(), ", "))
+		}
+	}
+
+	// type ServiceMiddleware func(Service) Service
+	f.Comment("ServiceMiddleware 包装服务接口，在调用前后添加日志、监控等功能。")
+	f.Type().Id("ServiceMiddleware").Func().Params(serviceType(service)).Add(serviceType(service)).Line()
+
+	generated := make(map[string]bool)
+	for _, kind := range kinds {
+		if generated[kind] {
+			continue
+		}
+		generated[kind] = true
+		generators[kind](f, service)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/nnnewb/jk/internal/domain/domaintest"
+)
+
+func TestGenerateMiddleware(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		src  string
+		path string // 生成代码所在的包
+		want []string
+	}{
+		{"Embedded", domaintest.EmbeddedSource, domaintest.PkgPath + "/middleware", []string{
+			"func (mw slogMiddleware) Create(ctx context.Context, req *order.CreateRequest) (resp *order.CreateResponse, err error) {",
+			`"duration", time.Since(begin)`,
+		}},
+		// 同名类型按包区分，服务所在的包不带包名
+		{"CollisionSamePackage", domaintest.CollisionSource, domaintest.PkgPath, []string{
+			"func (mw slogMiddleware) Open(ctx context.Context, req *multipart.FileHeader) (resp *FileHeader, err error) {",
+		}},
+		{"CollisionOtherPackage", domaintest.CollisionSource, domaintest.PkgPath + "/middleware", []string{
+			"func (mw slogMiddleware) Open(ctx context.Context, req *multipart.FileHeader) (resp *order.FileHeader, err error) {",
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := jen.NewFilePath(c.path)
+			err := GenerateMiddleware(f, domaintest.Service(t, c.src, "Service"), Kinds())
+			if err != nil {
+				t.Fatalf("generate failed: %+v", err)
+			}
+			var buf bytes.Buffer
+			if err = f.Render(&buf); err != nil {
+				t.Fatalf("render failed: %+v", err)
+			}
+			domaintest.Contains(t, buf.String(), c.want...)
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package mock
+
+import (
+	"go/types"
+
+	"emperror.dev/errors"
+	"github.com/dave/jennifer/jen"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+// names 是生成代码中与服务名相关的标识符。
+type names struct {
+	service string // Service
+	mock    string // MockService
+	fake    string // FakeService
+}
+
+func newNames(service *domain.Service) names {
+	return names{
+		service: service.Name(),
+		mock:    "Mock" + service.Name(),
+		fake:    "Fake" + service.Name(),
+	}
+}
+
+func (n names) call(method *domain.Method) string {
+	return n.mock + method.Func.Name() + "Call"
+}
+
+func (n names) expectations(method *domain.Method) string {
+	return "expect" + method.Func.Name()
+}
+
+func serviceType(service *domain.Service) *jen.Statement {
+	return jen.Qual(service.Interface.Obj().Pkg().Path(), service.Name())
+}
+
+func requestType(method *domain.Method) *jen.Statement {
+	return jen.Op("*").Add(method.RequestTypeCodeJen())
+}
+
+func responseType(method *domain.Method) *jen.Statement {
+	return method.ResultTypeCodeJen()
+}
+
+// methodFuncType 返回与方法签名相同的函数类型 func(context.Context, *REQ) (*RESP, error)。
+func methodFuncType(method *domain.Method) *jen.Statement {
+	return jen.Func().
+		Params(jen.Qual("context", "Context"), requestType(method)).
+		Params(responseType(method), jen.Error())
+}
+
+func generateMatchers(f *jen.File) {
+	// type MockMatcher interface {
+	//   Matches(x any) bool
+	//   String() string
+	// }
+	f.Comment("MockMatcher 匹配 mock 调用参数。设置期望时传入的参数如果不是 MockMatcher，使用 reflect.DeepEqual 比较。")
+	f.Type().Id("MockMatcher").Interface(
+		jen.Id("Matches").Params(jen.Id("x").Any()).Bool(),
+		jen.Id("String").Params().String(),
+	).Line()
+
+	// type mockMatcherFunc struct {
+	//   desc  string
+	//   match func(x any) bool
+	// }
+	f.Type().Id("mockMatcherFunc").Struct(
+		jen.Id("desc").String(),
+		jen.Id("match").Func().Params(jen.Id("x").Any()).Bool(),
+	).Line()
+
+	f.Func().Params(jen.Id("m").Id("mockMatcherFunc")).Id("Matches").Params(jen.Id("x").Any()).Bool().Block(
+		jen.Return(jen.Id("m").Dot("match").Call(jen.Id("x"))),
+	).Line()
+
+	f.Func().Params(jen.Id("m").Id("mockMatcherFunc")).Id("String").Params().String().Block(
+		jen.Return(jen.Id("m").Dot("desc")),
+	).Line()
+
+	// func MockAny() MockMatcher {
+	f.Comment("MockAny 匹配任意参数。")
+	f.Func().Id("MockAny").Params().Id("MockMatcher").Block(
+		jen.Return(jen.Id("mockMatcherFunc").Values(jen.Dict{
+			jen.Id("desc"):  jen.Lit("any"),
+			jen.Id("match"): jen.Func().Params(jen.Id("x").Any()).Bool().Block(jen.Return(jen.True())),
+		})),
+	).Line()
+
+	// func MockEq(expected any) MockMatcher {
+	f.Comment("MockEq 匹配与 expected 深度相等的参数。")
+	f.Func().Id("MockEq").Params(jen.Id("expected").Any()).Id("MockMatcher").Block(
+		jen.Return(jen.Id("mockMatcherFunc").Values(jen.Dict{
+			jen.Id("desc"): jen.Qual("fmt", "Sprintf").Call(jen.Lit("%+v"), jen.Id("expected")),
+			jen.Id("match"): jen.Func().Params(jen.Id("x").Any()).Bool().Block(
+				jen.Return(jen.Qual("reflect", "DeepEqual").Call(jen.Id("expected"), jen.Id("x"))),
+			),
+		})),
+	).Line()
+
+	// func MockArgThat[T any](desc string, match func(T) bool) MockMatcher {
+	f.Comment("MockArgThat 匹配类型为 T 且满足 match 的参数。")
+	f.Func().Id("MockArgThat").
+		Types(jen.Id("T").Any()).
+		Params(jen.Id("desc").String(), jen.Id("match").Func().Params(jen.Id("T")).Bool()).
+		Id("MockMatcher").
+		Block(
+			jen.Return(jen.Id("mockMatcherFunc").Values(jen.Dict{
+				jen.Id("desc"): jen.Id("desc"),
+				jen.Id("match"): jen.Func().Params(jen.Id("x").Any()).Bool().Block(
+					jen.List(jen.Id("v"), jen.Id("ok")).Op(":=").Id("x").Assert(jen.Id("T")),
+					jen.Return(jen.Id("ok").Op("&&").Id("match").Call(jen.Id("v"))),
+				),
+			})),
+		).Line()
+
+	// func mockMatcherOf(v any) MockMatcher {
+	f.Func().Id("mockMatcherOf").Params(jen.Id("v").Any()).Id("MockMatcher").Block(
+		jen.If(jen.List(jen.Id("m"), jen.Id("ok")).Op(":=").Id("v").Assert(jen.Id("MockMatcher")), jen.Id("ok")).Block(
+			jen.Return(jen.Id("m")),
+		),
+		jen.Return(jen.Id("MockEq").Call(jen.Id("v"))),
+	).Line()
+}
+
+func generateMockType(f *jen.File, service *domain.Service, n names) {
+	// type MockTestingT interface {
+	//   Helper()
+	//   Errorf(format string, args ...any)
+	// }
+	f.Comment("MockTestingT 是 *testing.T 的子集。")
+	f.Type().Id("MockTestingT").Interface(
+		jen.Id("Helper").Params(),
+		jen.Id("Errorf").Params(jen.Id("format").String(), jen.Id("args").Op("...").Any()),
+	).Line()
+
+	// type MockCall struct {
+	//   Method  string
+	//   Request any
+	// }
+	f.Comment("MockCall 记录一次对 mock 的调用。")
+	f.Type().Id("MockCall").Struct(
+		jen.Id("Method").String(),
+		jen.Id("Request").Any(),
+	).Line()
+
+	// type MockService struct {
+	f.Commentf("%s 是 %s 的 mock 实现，用 OnXXX 设置期望，未设置期望的调用会报告测试失败并返回错误。", n.mock, n.service)
+	f.Type().Id(n.mock).StructFunc(func(g *jen.Group) {
+		g.Id("t").Id("MockTestingT")
+		g.Id("mu").Qual("sync", "Mutex")
+		g.Id("calls").Index().Id("MockCall")
+		for _, method := range service.Methods {
+			g.Id(n.expectations(method)).Index().Op("*").Id(n.call(method))
+		}
+	}).Line()
+
+	// var _ Service = (*MockService)(nil)
+	f.Var().Id("_").Add(serviceType(service)).Op("=").Parens(jen.Op("*").Id(n.mock)).Call(jen.Nil()).Line()
+
+	// func NewMockService(t MockTestingT) *MockService {
+	f.Func().Id("New" + n.mock).Params(jen.Id("t").Id("MockTestingT")).Op("*").Id(n.mock).Block(
+		jen.Return(jen.Op("&").Id(n.mock).Values(jen.Dict{jen.Id("t"): jen.Id("t")})),
+	).Line()
+
+	// func NewMockEndpointSet(t MockTestingT) (*MockService, EndpointSet) {
+	f.Comment("NewMockEndpointSet 创建 mock 和基于它的 EndpointSet，可以传给生成的 HTTP 服务端做传输层测试。")
+	f.Func().Id("NewMockEndpointSet").Params(jen.Id("t").Id("MockTestingT")).Params(jen.Op("*").Id(n.mock), jen.Id("EndpointSet")).Block(
+		jen.Id("m").Op(":=").Id("New"+n.mock).Call(jen.Id("t")),
+		jen.Return(jen.Id("m"), jen.Id("NewEndpointSet").Call(jen.Id("m"))),
+	).Line()
+
+	// func (m *MockService) Calls() []MockCall {
+	f.Comment("Calls 按顺序返回所有调用记录，包括不符合期望的调用。")
+	f.Func().Params(jen.Id("m").Op("*").Id(n.mock)).Id("Calls").Params().Index().Id("MockCall").Block(
+		jen.Id("m").Dot("mu").Dot("Lock").Call(),
+		jen.Defer().Id("m").Dot("mu").Dot("Unlock").Call(),
+		jen.Return(jen.Append(jen.Index().Id("MockCall").Values(), jen.Id("m").Dot("calls").Op("..."))),
+	).Line()
+
+	// func (m *MockService) AssertExpectations(t MockTestingT) bool {
+	f.Comment("AssertExpectations 检查所有期望的调用次数，没有用 Times 指定次数的期望至少要调用一次。")
+	f.Func().Params(jen.Id("m").Op("*").Id(n.mock)).Id("AssertExpectations").Params(jen.Id("t").Id("MockTestingT")).Bool().BlockFunc(func(g *jen.Group) {
+		g.Id("t").Dot("Helper").Call()
+		g.Id("m").Dot("mu").Dot("Lock").Call()
+		g.Defer().Id("m").Dot("mu").Dot("Unlock").Call()
+		g.Line()
+		g.Id("ok").Op(":=").True()
+		for _, method := range service.Methods {
+			g.For(jen.List(jen.Id("_"), jen.Id("c")).Op(":=").Range().Id("m").Dot(n.expectations(method))).Block(
+				jen.If(jen.Op("!").Id("c").Dot("satisfied").Call()).Block(
+					jen.Id("t").Dot("Errorf").Call(
+						jen.Lit("expected call "+n.service+"."+method.Func.Name()+"(%s, %s) %s, but called %d times"),
+						jen.Id("c").Dot("ctx"),
+						jen.Id("c").Dot("req"),
+						jen.Id("c").Dot("expectedTimes").Call(),
+						jen.Id("c").Dot("called"),
+					),
+					jen.Id("ok").Op("=").False(),
+				),
+			)
+		}
+		g.Return(jen.Id("ok"))
+	}).Line()
+}
+
+func generateMockMethod(f *jen.File, n names, method *domain.Method) {
+	callType := n.call(method)
+	name := method.Func.Name()
+
+	// type MockServiceXXXCall struct {
+	f.Commentf("%s 是对 %s 的一个期望。", callType, name)
+	f.Type().Id(callType).Struct(
+		jen.Id("ctx").Id("MockMatcher"),
+		jen.Id("req").Id("MockMatcher"),
+		jen.Id("resp").Add(responseType(method)),
+		jen.Id("err").Error(),
+		jen.Id("do").Add(methodFuncType(method)),
+		jen.Id("times").Int(),
+		jen.Id("called").Int(),
+	).Line()
+
+	// func (m *MockService) OnXXX(ctx, req any) *MockServiceXXXCall {
+	f.Commentf("On%s 添加一个期望，ctx 和 req 可以是 MockMatcher 或者用于深度比较的值。", name)
+	f.Commentf("多个期望都能匹配时，使用最先添加且调用次数未达到上限的期望。")
+	f.Func().Params(jen.Id("m").Op("*").Id(n.mock)).Id("On"+name).
+		Params(jen.List(jen.Id("ctx"), jen.Id("req")).Any()).
+		Op("*").Id(callType).
+		Block(
+			jen.Id("c").Op(":=").Op("&").Id(callType).Values(jen.Dict{
+				jen.Id("ctx"): jen.Id("mockMatcherOf").Call(jen.Id("ctx")),
+				jen.Id("req"): jen.Id("mockMatcherOf").Call(jen.Id("req")),
+			}),
+			jen.Id("m").Dot("mu").Dot("Lock").Call(),
+			jen.Id("m").Dot(n.expectations(method)).Op("=").Append(jen.Id("m").Dot(n.expectations(method)), jen.Id("c")),
+			jen.Id("m").Dot("mu").Dot("Unlock").Call(),
+			jen.Return(jen.Id("c")),
+		).Line()
+
+	// func (c *MockServiceXXXCall) Return(resp *RESP, err error) *MockServiceXXXCall {
+	f.Comment("Return 设置调用的返回值。")
+	f.Func().Params(jen.Id("c").Op("*").Id(callType)).Id("Return").
+		Params(jen.Id("resp").Add(responseType(method)), jen.Err().Error()).
+		Op("*").Id(callType).
+		Block(
+			jen.Id("c").Dot("resp").Op(",").Id("c").Dot("err").Op("=").Id("resp").Op(",").Err(),
+			jen.Return(jen.Id("c")),
+		).Line()
+
+	// func (c *MockServiceXXXCall) Do(fn func(context.Context, *REQ) (*RESP, error)) *MockServiceXXXCall {
+	f.Comment("Do 设置调用时执行的函数，函数的返回值优先于 Return 设置的返回值。")
+	f.Func().Params(jen.Id("c").Op("*").Id(callType)).Id("Do").
+		Params(jen.Id("fn").Add(methodFuncType(method))).
+		Op("*").Id(callType).
+		Block(
+			jen.Id("c").Dot("do").Op("=").Id("fn"),
+			jen.Return(jen.Id("c")),
+		).Line()
+
+	// func (c *MockServiceXXXCall) Times(n int) *MockServiceXXXCall {
+	f.Comment("Times 设置期望的调用次数，超过次数的调用不再匹配这个期望。")
+	f.Func().Params(jen.Id("c").Op("*").Id(callType)).Id("Times").
+		Params(jen.Id("n").Int()).
+		Op("*").Id(callType).
+		Block(
+			jen.Id("c").Dot("times").Op("=").Id("n"),
+			jen.Return(jen.Id("c")),
+		).Line()
+
+	// func (c *MockServiceXXXCall) Once() *MockServiceXXXCall {
+	f.Func().Params(jen.Id("c").Op("*").Id(callType)).Id("Once").Params().Op("*").Id(callType).Block(
+		jen.Return(jen.Id("c").Dot("Times").Call(jen.Lit(1))),
+	).Line()
+
+	// func (c *MockServiceXXXCall) matches(ctx context.Context, req *REQ) bool {
+	f.Func().Params(jen.Id("c").Op("*").Id(callType)).Id("matches").
+		Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("req").Add(requestType(method))).
+		Bool().
+		Block(
+			jen.If(jen.Id("c").Dot("times").Op(">").Lit(0).Op("&&").Id("c").Dot("called").Op(">=").Id("c").Dot("times")).Block(
+				jen.Return(jen.False()),
+			),
+			jen.Return(jen.Id("c").Dot("ctx").Dot("Matches").Call(jen.Id("ctx")).Op("&&").
+				Id("c").Dot("req").Dot("Matches").Call(jen.Id("req"))),
+		).Line()
+
+	// func (c *MockServiceXXXCall) satisfied() bool {
+	f.Func().Params(jen.Id("c").Op("*").Id(callType)).Id("satisfied").Params().Bool().Block(
+		jen.If(jen.Id("c").Dot("times").Op(">").Lit(0)).Block(
+			jen.Return(jen.Id("c").Dot("called").Op("==").Id("c").Dot("times")),
+		),
+		jen.Return(jen.Id("c").Dot("called").Op(">").Lit(0)),
+	).Line()
+
+	// func (c *MockServiceXXXCall) expectedTimes() string {
+	f.Func().Params(jen.Id("c").Op("*").Id(callType)).Id("expectedTimes").Params().String().Block(
+		jen.If(jen.Id("c").Dot("times").Op(">").Lit(0)).Block(
+			jen.Return(jen.Qual("fmt", "Sprintf").Call(jen.Lit("%d times"), jen.Id("c").Dot("times"))),
+		),
+		jen.Return(jen.Lit("at least once")),
+	).Line()
+
+	// func (m *MockService) XXX(ctx context.Context, req *REQ) (*RESP, error) {
+	f.Func().Params(jen.Id("m").Op("*").Id(n.mock)).Id(name).
+		Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("req").Add(requestType(method))).
+		Params(responseType(method), jen.Error()).
+		Block(
+			jen.Id("m").Dot("mu").Dot("Lock").Call(),
+			jen.Id("m").Dot("calls").Op("=").Append(jen.Id("m").Dot("calls"), jen.Id("MockCall").Values(jen.Dict{
+				jen.Id("Method"):  jen.Lit(name),
+				jen.Id("Request"): jen.Id("req"),
+			})),
+			jen.Var().Id("matched").Op("*").Id(callType),
+			jen.For(jen.List(jen.Id("_"), jen.Id("c")).Op(":=").Range().Id("m").Dot(n.expectations(method))).Block(
+				jen.If(jen.Id("c").Dot("matches").Call(jen.Id("ctx"), jen.Id("req"))).Block(
+					jen.Id("matched").Op("=").Id("c"),
+					jen.Id("matched").Dot("called").Op("++"),
+					jen.Break(),
+				),
+			),
+			jen.Id("m").Dot("mu").Dot("Unlock").Call(),
+			jen.Line(),
+			jen.If(jen.Id("matched").Op("==").Nil()).Block(
+				jen.Id("m").Dot("t").Dot("Helper").Call(),
+				jen.Id("m").Dot("t").Dot("Errorf").Call(jen.Lit("unexpected call "+n.service+"."+name+"(%+v)"), jen.Id("req")),
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("mock: unexpected call "+n.service+"."+name+"(%+v)"), jen.Id("req"))),
+			),
+			jen.If(jen.Id("matched").Dot("do").Op("!=").Nil()).Block(
+				jen.Return(jen.Id("matched").Dot("do").Call(jen.Id("ctx"), jen.Id("req"))),
+			),
+			jen.Return(jen.Id("matched").Dot("resp"), jen.Id("matched").Dot("err")),
+		).Line()
+}
+
+func generateFake(f *jen.File, service *domain.Service, n names) {
+	// type FakeService struct {
+	//   XXXFunc func(context.Context, *REQ) (*RESP, error)
+	// }
+	f.Commentf("%s 是 %s 的内存实现，方法调用对应的 XXXFunc 字段，字段为 nil 时返回零值响应。", n.fake, n.service)
+	f.Type().Id(n.fake).StructFunc(func(g *jen.Group) {
+		for _, method := range service.Methods {
+			g.Id(method.Func.Name() + "Func").Add(methodFuncType(method))
+		}
+	}).Line()
+
+	// var _ Service = (*FakeService)(nil)
+	f.Var().Id("_").Add(serviceType(service)).Op("=").Parens(jen.Op("*").Id(n.fake)).Call(jen.Nil()).Line()
+
+	for _, method := range service.Methods {
+		name := method.Func.Name()
+		f.Func().Params(jen.Id("f").Op("*").Id(n.fake)).Id(name).
+			Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("req").Add(requestType(method))).
+			Params(responseType(method), jen.Error()).
+			BlockFunc(func(g *jen.Group) {
+				g.If(jen.Id("f").Dot(name + "Func").Op("!=").Nil()).Block(
+					jen.Return(jen.Id("f").Dot(name+"Func").Call(jen.Id("ctx"), jen.Id("req"))),
+				)
+				if method.IsEventStream() {
+					// 没有设置函数时返回已关闭的通道，调用方立即读到结束
+					g.Id("events").Op(":=").Make(jen.Chan().Op("*").Add(method.ResponseTypeCodeJen()))
+					g.Close(jen.Id("events"))
+					g.Return(jen.Id("events"), jen.Nil())
+					return
+				}
+				g.Return(jen.Op("&").Add(method.ResponseTypeCodeJen()).Values(), jen.Nil())
+			}).Line()
+	}
+}
+
+// GenerateMock 为服务接口生成基于期望的 MockXXX、内存实现 FakeXXX 和 NewMockEndpointSet。
+// 生成的代码只依赖标准库和 endpoints 子命令生成的 EndpointSet。
+func GenerateMock(f *jen.File, service *domain.Service) error {
+	for _, method := range service.Methods {
+		signature := method.Func.Type().(*types.Signature)
+		if err := utils.CheckParams(signature.Params()); err != nil {
+			return errors.Wrapf(err, "check method signature: %s", method.Func.FullName())
+		}
+		if err := utils.CheckResults(signature.Results()); err != nil {
+			return errors.Wrapf(err, "check method signature: %s", method.Func.FullName())
+		}
+	}
+
+	n := newNames(service)
+	generateMatchers(f)
+	generateMockType(f, service, n)
+	for _, method := range service.Methods {
+		generateMockMethod(f, n, method)
+	}
+	generateFake(f, service, n)
+	return nil
+}
@@ -0,0 +1,46 @@
+package mock
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/nnnewb/jk/internal/domain/domaintest"
+)
+
+func TestGenerateMock(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		src  string
+		path string // 生成代码所在的包
+		want []string
+	}{
+		{"Embedded", domaintest.EmbeddedSource, domaintest.PkgPath + "/mock", []string{
+			"func (m *MockService) Create(ctx context.Context, req *order.CreateRequest) (*order.CreateResponse, error) {",
+			"return &order.CreateResponse{}, nil",
+		}},
+		// 同名类型按包区分，服务所在的包不带包名
+		{"CollisionSamePackage", domaintest.CollisionSource, domaintest.PkgPath, []string{
+			"func (m *MockService) Open(ctx context.Context, req *multipart.FileHeader) (*FileHeader, error) {",
+			"func (c *MockServiceOpenCall) Return(resp *FileHeader, err error) *MockServiceOpenCall {",
+			"return &FileHeader{}, nil",
+		}},
+		{"CollisionOtherPackage", domaintest.CollisionSource, domaintest.PkgPath + "/mock", []string{
+			"func (m *MockService) Open(ctx context.Context, req *multipart.FileHeader) (*order.FileHeader, error) {",
+			"return &order.FileHeader{}, nil",
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := jen.NewFilePath(c.path)
+			err := GenerateMock(f, domaintest.Service(t, c.src, "Service"))
+			if err != nil {
+				t.Fatalf("generate failed: %+v", err)
+			}
+			var buf bytes.Buffer
+			if err = f.Render(&buf); err != nil {
+				t.Fatalf("render failed: %+v", err)
+			}
+			domaintest.Contains(t, buf.String(), c.want...)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+// Package tmpl 用 text/template 在服务模型上渲染用户自定义的模板。
+package tmpl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"emperror.dev/errors"
+	"github.com/iancoleman/strcase"
+	"github.com/nnnewb/jk/internal/model"
+)
+
+// funcs 是模板中可用的辅助函数。
+type funcs struct {
+	service *model.Service
+}
+
+func (f *funcs) funcMap() template.FuncMap {
+	return template.FuncMap{
+		"camel":          strcase.ToCamel,
+		"lowerCamel":     strcase.ToLowerCamel,
+		"snake":          strcase.ToSnake,
+		"screamingSnake": strcase.ToScreamingSnake,
+		"kebab":          strcase.ToKebab,
+		"lower":          strings.ToLower,
+		"upper":          strings.ToUpper,
+		"join":           strings.Join,
+		"replace":        strings.ReplaceAll,
+		"json":           toJSON,
+		"lookup":         f.lookup,
+		"fields":         f.fields,
+		"jsonName":       f.jsonName,
+		"goType":         f.goType,
+		"tsType":         f.tsType,
+	}
+}
+
+func toJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// lookup 返回类型引用的命名类型定义，会跳过指针，不是命名类型或没有定义时返回 nil。
+func (f *funcs) lookup(t *model.Type) *model.Named {
+	for t != nil && t.Kind == model.KindPointer {
+		t = t.Elem
+	}
+	if t == nil || t.Kind != model.KindNamed {
+		return nil
+	}
+	for i := range f.service.Types {
+		if f.service.Types[i].Name == t.Name && f.service.Types[i].Package == t.Package {
+			return &f.service.Types[i]
+		}
+	}
+	return nil
+}
+
+// fields 返回结构体的字段，t 可以是命名类型、匿名结构体或它们的指针。
+func (f *funcs) fields(t *model.Type) []model.Field {
+	if named := f.lookup(t); named != nil {
+		t = named.Underlying
+	}
+	for t != nil && t.Kind == model.KindPointer {
+		t = t.Elem
+	}
+	if t == nil || t.Kind != model.KindStruct {
+		return nil
+	}
+	return t.Fields
+}
+
+// jsonName 返回结构体字段 name 序列化后的名称，嵌入结构体的字段和 encoding/json 一样提升到外层，
+// 外层的同名字段优先。字段不存在时返回错误。
+func (f *funcs) jsonName(t *model.Type, name string) (string, error) {
+	if jsonName, ok := f.findJSONName(t, name, make(map[*model.Named]bool)); ok {
+		return jsonName, nil
+	}
+	return "", errors.Errorf("field %s not found", name)
+}
+
+func (f *funcs) findJSONName(t *model.Type, name string, seen map[*model.Named]bool) (string, bool) {
+	if named := f.lookup(t); named != nil {
+		if seen[named] {
+			return "", false
+		}
+		seen[named] = true
+	}
+
+	fields := f.fields(t)
+	for _, field := range fields {
+		if field.Name == name && !isPromoted(field) {
+			return field.JSONName, true
+		}
+	}
+	for _, field := range fields {
+		if isPromoted(field) {
+			if jsonName, ok := f.findJSONName(field.Type, name, seen); ok {
+				return jsonName, true
+			}
+		}
+	}
+	return "", false
+}
+
+// isPromoted 判断字段是否是 encoding/json 展开到外层的嵌入字段，即没有 json 名称的嵌入字段。
+func isPromoted(field model.Field) bool {
+	return field.Embedded && strings.Split(reflect.StructTag(field.Tag).Get("json"), ",")[0] == ""
+}
+
+// goType 返回 Go 类型表达式，服务所在包的类型不带包名。
+func (f *funcs) goType(t *model.Type) (string, error) {
+	switch t.Kind {
+	case model.KindBasic:
+		return t.Name, nil
+	case model.KindNamed:
+		if t.Package == "" || t.Package == f.service.Package {
+			return t.Name, nil
+		}
+		return path.Base(t.Package) + "." + t.Name, nil
+	case model.KindPointer:
+		elem, err := f.goType(t.Elem)
+		return "*" + elem, err
+	case model.KindSlice:
+		elem, err := f.goType(t.Elem)
+		return "[]" + elem, err
+	case model.KindArray:
+		elem, err := f.goType(t.Elem)
+		return fmt.Sprintf("[%d]%s", t.Len, elem), err
+	case model.KindMap:
+		key, err := f.goType(t.Key)
+		if err != nil {
+			return "", err
+		}
+		elem, err := f.goType(t.Elem)
+		return fmt.Sprintf("map[%s]%s", key, elem), err
+	case model.KindStruct:
+		fields := make([]string, 0, len(t.Fields))
+		for _, field := range t.Fields {
+			typ, err := f.goType(field.Type)
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, fmt.Sprintf("%s %s `%s`", field.Name, typ, field.Tag))
+		}
+		return "struct{" + strings.Join(fields, "; ") + "}", nil
+	case model.KindChan:
+		elem, err := f.goType(t.Elem)
+		return "<-chan " + elem, err
+	case model.KindInterface:
+		return "any", nil
+	default:
+		return "", errors.Errorf("unknown type kind %s", t.Kind)
+	}
+}
+
+// tsType 返回 TypeScript 类型表达式，命名类型使用类型名，指针取元素类型。
+func (f *funcs) tsType(t *model.Type) (string, error) {
+	switch t.Kind {
+	case model.KindBasic:
+		switch t.Name {
+		case "bool":
+			return "boolean", nil
+		case "string":
+			return "string", nil
+		case "int", "int8", "int16", "int32", "int64",
+			"uint", "uint8", "uint16", "uint32", "uint64",
+			"float32", "float64":
+			return "number", nil
+		default:
+			return "", errors.Errorf("unserializable basic type %s", t.Name)
+		}
+	case model.KindNamed:
+		if t.Package == "time" && t.Name == "Time" {
+			// time.Time 编码为 RFC 3339 字符串
+			return "string", nil
+		}
+		return t.Name, nil
+	case model.KindPointer:
+		return f.tsType(t.Elem)
+	case model.KindSlice, model.KindArray:
+		elem, err := f.tsType(t.Elem)
+		return "Array<" + elem + ">", err
+	case model.KindMap:
+		key, err := f.tsType(t.Key)
+		if err != nil {
+			return "", err
+		}
+		elem, err := f.tsType(t.Elem)
+		return fmt.Sprintf("Record<%s, %s>", key, elem), err
+	case model.KindStruct:
+		fields := make([]string, 0, len(t.Fields))
+		for _, field := range t.Fields {
+			typ, err := f.tsType(field.Type)
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, fmt.Sprintf("%s: %s", field.JSONName, typ))
+		}
+		return "{ " + strings.Join(fields, "; ") + " }", nil
+	case model.KindChan:
+		elem, err := f.tsType(t.Elem)
+		return "AsyncIterable<" + elem + ">", err
+	case model.KindInterface:
+		return "unknown", nil
+	default:
+		return "", errors.Errorf("unknown type kind %s", t.Kind)
+	}
+}
+
+// Execute 以服务模型 *model.Service 为数据执行模板文件 filename，结果写入 wr。
+func Execute(wr io.Writer, filename string, service *model.Service) error {
+	f := &funcs{service: service}
+	tmpl, err := template.New(filepath.Base(filename)).
+		Option("missingkey=error").
+		Funcs(f.funcMap()).
+		ParseFiles(filename)
+	if err != nil {
+		return errors.Wrapf(err, "parse template %s failed", filename)
+	}
+
+	err = tmpl.Execute(wr, service)
+	if err != nil {
+		return errors.Wrapf(err, "execute template %s failed", filename)
+	}
+	return nil
+}
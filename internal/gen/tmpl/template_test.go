@@ -0,0 +1,61 @@
+package tmpl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nnnewb/jk/internal/domain/domaintest"
+	"github.com/nnnewb/jk/internal/model"
+)
+
+func TestExecute(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		src  string
+		tmpl string
+		want string
+		err  string
+	}{
+		// 嵌入结构体的字段可以直接按字段名查找，包括嵌入指针中的字段
+		{"PromotedJSONName", domaintest.EmbeddedSource,
+			`{{with (index .Methods 0).Request}}{{jsonName . "Tenant"}} {{jsonName . "Source"}} {{jsonName . "UserId"}}{{end}}`,
+			"tenant source user_id", ""},
+		// time.Time 在 TypeScript 中是字符串
+		{"TimeType", domaintest.EmbeddedSource,
+			`{{range fields (index .Methods 0).Request}}{{if not .Embedded}}{{.JSONName}}: {{tsType .Type}}; {{end}}{{end}}`,
+			"userID: string; user_id: string; deadline: string; ", ""},
+		// 同名类型按包区分，服务所在的包不带包名
+		{"Collision", domaintest.CollisionSource,
+			`{{with index .Methods 0}}{{goType .Request}} {{goType .Response}}{{end}}`,
+			"*multipart.FileHeader *FileHeader", ""},
+		{"MissingField", domaintest.EmbeddedSource,
+			`{{jsonName (index .Methods 0).Request "Missing"}}`,
+			"", "field Missing not found"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			service, err := model.FromService(domaintest.Service(t, c.src, "Service"))
+			if err != nil {
+				t.Fatalf("convert service failed: %+v", err)
+			}
+			filename := filepath.Join(t.TempDir(), "test.tmpl")
+			if err = os.WriteFile(filename, []byte(c.tmpl), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			err = Execute(&buf, filename, service)
+			if c.err != "" {
+				domaintest.ErrorContains(t, err, c.err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("execute failed: %+v", err)
+			}
+			if buf.String() != c.want {
+				t.Errorf("got %q, want %q", buf.String(), c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/dave/jennifer/jen"
+	stdcli "github.com/nnnewb/jk/internal/gen/http/client/go/std"
+	"github.com/nnnewb/jk/internal/gen/http/client/python/httpx"
+	"github.com/nnnewb/jk/internal/gen/http/client/rust/reqwest"
+	"github.com/nnnewb/jk/internal/gen/http/client/typescript/fetch"
+	"github.com/nnnewb/jk/internal/gen/http/doc"
+	"github.com/nnnewb/jk/internal/gen/http/roundtrip"
+	"github.com/nnnewb/jk/internal/gen/http/server/go/gin"
+	stdsvr "github.com/nnnewb/jk/internal/gen/http/server/go/std"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+// 内置的传输层生成器，名称为 <language>-<framework>-<server|client>。
+func init() {
+	Register("go-http-server", GeneratorFunc(genHTTPServer))
+	Register("go-gin-server", GeneratorFunc(genGinServer))
+	Register("go-http-client", GeneratorFunc(genHTTPClient))
+	Register("ts-fetch-client", GeneratorFunc(genTypeScriptClient))
+	Register("python-httpx-client", GeneratorFunc(genPythonClient))
+	Register("rust-reqwest-client", GeneratorFunc(genRustClient))
+	Register("swagger", GeneratorFunc(genSwagger))
+}
+
+// generatedHeader 返回生成代码的头部注释内容。
+func generatedHeader() string {
+	return fmt.Sprintf("Code generated by jk %s; DO NOT EDIT.", strings.Join(os.Args[1:], " "))
+}
+
+// newGoFile 创建 Go 代码文件。
+func newGoFile(req *Request) *jen.File {
+	f := jen.NewFilePath(req.Package)
+	f.HeaderComment(generatedHeader())
+	utils.InitializeFileCommon(f)
+	return f
+}
+
+// renderGoFile 渲染 Go 代码文件。
+func renderGoFile(f *jen.File, filename string) (File, error) {
+	var buf bytes.Buffer
+	err := f.Render(&buf)
+	if err != nil {
+		return File{}, errors.Wrapf(err, "render generated code %s failed", filename)
+	}
+	return File{Name: filename, Content: buf.Bytes()}, nil
+}
+
+// genRoundTripTest 生成服务端和 Go 客户端的往返测试代码。
+func genRoundTripTest(req *Request, filename, framework string) (File, error) {
+	f := newGoFile(req)
+	err := roundtrip.GenerateRoundTripTest(f, req.Service, framework)
+	if err != nil {
+		return File{}, errors.Wrap(err, "generate round trip test code failed")
+	}
+	return renderGoFile(f, filename)
+}
+
+// genHTTPServer 生成HTTP服务器代码，参数 embed-swagger 嵌入 swagger 文档，with-tests 生成往返测试。
+func genHTTPServer(req *Request) ([]File, error) {
+	f := newGoFile(req)
+	stdsvr.GenerateHTTPTransportServer(f, req.Service)
+	if req.Bool("embed-swagger") {
+		stdsvr.GenerateEmbedSwaggerJSON(f, req.Service)
+	}
+
+	file, err := renderGoFile(f, "transport_http_server.go")
+	if err != nil {
+		return nil, err
+	}
+	files := []File{file}
+
+	if req.Bool("with-tests") {
+		file, err = genRoundTripTest(req, "transport_http_server_test.go", "http")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// genGinServer 生成 gin 服务器代码，参数同 genHTTPServer。
+func genGinServer(req *Request) ([]File, error) {
+	f := newGoFile(req)
+	err := gin.GenerateGin(f, req.Service)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate gin server code failed")
+	}
+	if req.Bool("embed-swagger") {
+		gin.GenerateGinEmbedSwaggerUI(f, req.Service)
+	}
+
+	file, err := renderGoFile(f, "transport_gin_server.go")
+	if err != nil {
+		return nil, err
+	}
+	files := []File{file}
+
+	if req.Bool("with-tests") {
+		file, err = genRoundTripTest(req, "transport_gin_server_test.go", "gin")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// genHTTPClient 生成HTTP客户端代码。
+func genHTTPClient(req *Request) ([]File, error) {
+	f := newGoFile(req)
+	stdcli.GenerateHTTPTransportClient(f, req.Service)
+
+	file, err := renderGoFile(f, "transport_http_client.go")
+	if err != nil {
+		return nil, err
+	}
+	return []File{file}, nil
+}
+
+// genSwagger 生成Swagger文档。
+func genSwagger(req *Request) ([]File, error) {
+	var buf bytes.Buffer
+	err := doc.GenerateSwagger(&buf, req.Service)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate swagger failed")
+	}
+	return []File{{Name: "swagger.json", Content: buf.Bytes()}}, nil
+}
+
+// genTypeScriptClient 生成 typescript 客户端代码，参数 zod 生成 zod schema 和响应校验。
+// 工作目录没有 tsconfig.json 时同时生成一份。
+func genTypeScriptClient(req *Request) ([]File, error) {
+	const filename = "client.ts"
+
+	tsconfig := fmt.Sprintf(`{
+  "compilerOptions": {
+    "target": "ES2015",
+    "module": "ES6",
+    "moduleResolution": "node",
+    "sourceMap": true,
+    "declaration": true,
+    "declarationMap": true,
+    "preserveConstEnums": true,
+    "lib": [
+      "DOM",
+      "ES2015",
+      "ES2018.AsyncGenerator",
+      "ES2018.AsyncIterable"
+    ]
+  },
+  "files": [
+    "%s"
+  ]
+}`, filename)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// %s\n", generatedHeader())
+	err := fetch.GenerateTypeScriptClient(&buf, req.Service, req.Bool("zod"))
+	if err != nil {
+		return nil, errors.Wrap(err, "generate typescript api client failed")
+	}
+
+	return []File{
+		{Name: "tsconfig.json", Content: []byte(tsconfig), KeepExisting: true},
+		{Name: filename, Content: buf.Bytes()},
+	}, nil
+}
+
+// genPythonClient 生成 python 客户端代码。
+func genPythonClient(req *Request) ([]File, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n", generatedHeader())
+	err := httpx.GeneratePythonClient(&buf, req.Service)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate python api client failed")
+	}
+	return []File{{Name: "client.py", Content: buf.Bytes()}}, nil
+}
+
+// genRustClient 生成 rust 客户端代码。
+func genRustClient(req *Request) ([]File, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// %s\n", generatedHeader())
+	err := reqwest.GenerateRustClient(&buf, req.Service)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate rust api client failed")
+	}
+	return []File{{Name: "client.rs", Content: buf.Bytes()}}, nil
+}
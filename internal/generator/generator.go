@@ -0,0 +1,110 @@
+// Package generator 定义代码生成器接口和注册表。内置生成器和 PATH 中的 jk-gen-<name> 插件
+// 都通过名称查找，调用方式相同。
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/nnnewb/jk/internal/domain"
+)
+
+// PluginPrefix 是插件可执行文件名的前缀，名为 foo 的生成器对应 PATH 中的 jk-gen-foo。
+const PluginPrefix = "jk-gen-"
+
+// Request 是一次生成的输入。
+type Request struct {
+	Package   string            // 服务接口所在包的导入路径
+	Service   *domain.Service   // 解析得到的服务
+	Parameter map[string]string // 生成器参数，如 embed-swagger=true
+}
+
+// Bool 返回布尔类型的参数，未设置时返回 false。
+func (r *Request) Bool(name string) bool {
+	return r.Parameter[name] == "true"
+}
+
+// File 是生成的文件。
+type File struct {
+	Name         string // 相对于工作目录的路径
+	Content      []byte
+	KeepExisting bool // 文件已存在时不覆盖
+}
+
+// Generator 是代码生成器。
+type Generator interface {
+	Generate(req *Request) ([]File, error)
+}
+
+// GeneratorFunc 把函数转换为 Generator。
+type GeneratorFunc func(req *Request) ([]File, error)
+
+func (f GeneratorFunc) Generate(req *Request) ([]File, error) {
+	return f(req)
+}
+
+var registry = make(map[string]Generator)
+
+// Register 注册内置生成器，名称重复时 panic。
+func Register(name string, g Generator) {
+	if _, ok := registry[name]; ok {
+		panic("generator " + name + " already registered")
+	}
+	registry[name] = g
+}
+
+// Names 返回全部内置生成器的名称。
+func Names() []string {
+	ret := make([]string, 0, len(registry))
+	for name := range registry {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Lookup 按名称查找生成器，内置生成器优先，其次是 PATH 中的 jk-gen-<name> 插件。
+func Lookup(name string) (Generator, error) {
+	if g, ok := registry[name]; ok {
+		return g, nil
+	}
+
+	path, err := exec.LookPath(PluginPrefix + name)
+	if err != nil {
+		return nil, errors.Errorf("generator %s not found, neither built-in nor %s%s in PATH", name, PluginPrefix, name)
+	}
+	return &Plugin{Name: name, Path: path}, nil
+}
+
+// WriteFiles 把生成的文件写入工作目录，拒绝写到工作目录以外的路径。
+func WriteFiles(files []File) error {
+	for _, file := range files {
+		name := filepath.Clean(filepath.FromSlash(file.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return errors.Errorf("generated file %s is outside of working directory", file.Name)
+		}
+
+		if file.KeepExisting {
+			if _, err := os.Stat(name); err == nil {
+				continue
+			}
+		}
+
+		if dir := filepath.Dir(name); dir != "." {
+			err := os.MkdirAll(dir, 0o755)
+			if err != nil {
+				return errors.Wrapf(err, "create directory of %s failed", name)
+			}
+		}
+
+		err := os.WriteFile(name, file.Content, 0o644)
+		if err != nil {
+			return errors.Wrapf(err, "write %s failed", name)
+		}
+	}
+	return nil
+}
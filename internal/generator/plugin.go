@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+
+	"emperror.dev/errors"
+	"github.com/nnnewb/jk/internal/model"
+)
+
+// PluginRequest 是写到插件标准输入的 JSON。
+type PluginRequest struct {
+	Version   int               `json:"version"` // model.Version
+	Parameter map[string]string `json:"parameter"`
+	Service   *model.Service    `json:"service"`
+}
+
+// PluginFile 是插件生成的文件。
+type PluginFile struct {
+	Name         string `json:"name"`
+	Content      string `json:"content"`
+	KeepExisting bool   `json:"keep_existing"`
+}
+
+// PluginResponse 是插件写到标准输出的 JSON，Error 不为空表示生成失败。
+type PluginResponse struct {
+	Files []PluginFile `json:"files"`
+	Error string       `json:"error"`
+}
+
+// Plugin 是以外部可执行文件实现的生成器。
+// jk 把 PluginRequest 写到插件的标准输入，从标准输出读取 PluginResponse，插件的标准错误直接输出到终端。
+type Plugin struct {
+	Name string
+	Path string
+}
+
+func (p *Plugin) Generate(req *Request) ([]File, error) {
+	service, err := model.FromService(req.Service)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := json.Marshal(&PluginRequest{
+		Version:   model.Version,
+		Parameter: req.Parameter,
+		Service:   service,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal plugin request failed")
+	}
+
+	var output bytes.Buffer
+	cmd := exec.Command(p.Path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &output
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, errors.Wrapf(err, "run plugin %s failed", p.Path)
+	}
+
+	var resp PluginResponse
+	err = json.Unmarshal(output.Bytes(), &resp)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unmarshal response of plugin %s failed", p.Path)
+	}
+	if resp.Error != "" {
+		return nil, errors.Errorf("plugin %s: %s", p.Name, resp.Error)
+	}
+
+	files := make([]File, 0, len(resp.Files))
+	for _, file := range resp.Files {
+		files = append(files, File{Name: file.Name, Content: []byte(file.Content), KeepExisting: file.KeepExisting})
+	}
+	return files, nil
+}
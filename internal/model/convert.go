@@ -0,0 +1,123 @@
+package model
+
+import (
+	"go/types"
+
+	"emperror.dev/errors"
+	"github.com/nnnewb/jk/internal/domain"
+	"github.com/nnnewb/jk/internal/gen/http/common"
+	"github.com/nnnewb/jk/internal/utils"
+)
+
+type converter struct {
+	service *domain.Service
+	named   []Named
+	seen    map[*types.TypeName]bool
+}
+
+// FromService 把解析得到的服务转换为可以序列化的模型，未导出的方法会被忽略。
+func FromService(service *domain.Service) (*Service, error) {
+	for _, method := range service.Methods {
+		signature := method.Func.Type().(*types.Signature)
+		if err := utils.CheckParams(signature.Params()); err != nil {
+			return nil, errors.Wrapf(err, "check method signature: %s", method.Func.FullName())
+		}
+		if err := utils.CheckResults(signature.Results()); err != nil {
+			return nil, errors.Wrapf(err, "check method signature: %s", method.Func.FullName())
+		}
+	}
+
+	common.HTTPPopulateDefaultAnnotations(service)
+
+	c := &converter{service: service, seen: make(map[*types.TypeName]bool)}
+	ret := &Service{
+		Package:      service.Interface.Obj().Pkg().Path(),
+		PackageName:  service.Interface.Obj().Pkg().Name(),
+		Name:         service.Name(),
+		Doc:          service.Doc(),
+		Annotations:  service.RawAnnotations,
+		HTTPBasePath: service.Annotations.HTTPBasePath,
+		Methods:      make([]Method, 0, len(service.Methods)),
+	}
+
+	for _, method := range service.Methods {
+		if !method.Func.Exported() {
+			continue
+		}
+
+		ret.Methods = append(ret.Methods, Method{
+			Name:        method.Func.Name(),
+			Doc:         method.Doc(),
+			Annotations: method.RawAnnotations,
+			HTTPMethod:  method.Annotations.HTTPMethod,
+			HTTPPath:    method.Annotations.HTTPPath,
+			Request:     c.typeOf(method.RequestType()),
+			Response:    c.typeOf(method.ResponseType()),
+		})
+	}
+
+	ret.Types = c.named
+	return ret, nil
+}
+
+func (c *converter) typeOf(typ types.Type) *Type {
+	switch t := typ.(type) {
+	case *types.Named:
+		c.define(t)
+		ret := &Type{Kind: KindNamed, Name: t.Obj().Name()}
+		if t.Obj().Pkg() != nil {
+			ret.Package = t.Obj().Pkg().Path()
+		}
+		return ret
+	case *types.Basic:
+		return &Type{Kind: KindBasic, Name: t.Name()}
+	case *types.Pointer:
+		return &Type{Kind: KindPointer, Elem: c.typeOf(t.Elem())}
+	case *types.Slice:
+		return &Type{Kind: KindSlice, Elem: c.typeOf(t.Elem())}
+	case *types.Array:
+		return &Type{Kind: KindArray, Elem: c.typeOf(t.Elem()), Len: t.Len()}
+	case *types.Map:
+		return &Type{Kind: KindMap, Key: c.typeOf(t.Key()), Elem: c.typeOf(t.Elem())}
+	case *types.Struct:
+		return &Type{Kind: KindStruct, Fields: c.fields(t)}
+	case *types.Chan:
+		return &Type{Kind: KindChan, Elem: c.typeOf(t.Elem())}
+	default:
+		return &Type{Kind: KindInterface}
+	}
+}
+
+// define 记录命名类型的定义，先标记再展开，递归类型只定义一次。
+func (c *converter) define(t *types.Named) {
+	if c.seen[t.Obj()] || t.Obj().Pkg() == nil {
+		return
+	}
+	c.seen[t.Obj()] = true
+
+	i := len(c.named)
+	c.named = append(c.named, Named{
+		Name:    t.Obj().Name(),
+		Package: t.Obj().Pkg().Path(),
+		Doc:     c.service.ObjectDoc(t.Obj()),
+	})
+	underlying := c.typeOf(t.Underlying())
+	c.named[i].Underlying = underlying
+}
+
+func (c *converter) fields(t *types.Struct) []Field {
+	jsonFields := common.DeclaredJSONFields(t)
+	ret := make([]Field, 0, len(jsonFields))
+	for _, field := range jsonFields {
+		ret = append(ret, Field{
+			Name:      field.Var.Name(),
+			JSONName:  field.Name,
+			Doc:       c.service.ObjectDoc(field.Var),
+			Tag:       field.Tag,
+			OmitEmpty: field.OmitEmpty,
+			Embedded:  field.Var.Anonymous(),
+			Type:      c.typeOf(field.Var.Type()),
+		})
+	}
+	return ret
+}
@@ -0,0 +1,79 @@
+// Package model 定义服务描述的 JSON 格式，是 jk 和外部生成器插件之间的协议。
+package model
+
+// Version 是模型格式的版本，不兼容的修改需要递增。
+const Version = 1
+
+// Description 是 jk describe 输出的文档。
+type Description struct {
+	Version int      `json:"version"` // 等于 Version
+	Service *Service `json:"service"`
+}
+
+// Service 是服务接口的描述。
+type Service struct {
+	Package      string            `json:"package"`        // 服务接口所在包的导入路径
+	PackageName  string            `json:"package_name"`   // 服务接口所在包的包名
+	Name         string            `json:"name"`           // 服务接口名
+	Doc          string            `json:"doc"`            // 文档注释，不含注解行
+	Annotations  map[string]string `json:"annotations"`    // 服务注解
+	HTTPBasePath string            `json:"http_base_path"` // 补全默认值后的 HTTP 路径前缀
+	Methods      []Method          `json:"methods"`
+	Types        []Named           `json:"types"` // 请求和响应引用的全部命名类型
+}
+
+// Method 是服务方法的描述。
+type Method struct {
+	Name        string            `json:"name"`
+	Doc         string            `json:"doc"`
+	Annotations map[string]string `json:"annotations"`
+	HTTPMethod  string            `json:"http_method"` // 补全默认值后的 HTTP 方法
+	HTTPPath    string            `json:"http_path"`   // 补全默认值后的 HTTP 路径
+	Request     *Type             `json:"request"`
+	Response    *Type             `json:"response"`
+}
+
+// Kind 是类型的种类。
+type Kind string
+
+const (
+	KindBasic     Kind = "basic"
+	KindNamed     Kind = "named"
+	KindPointer   Kind = "pointer"
+	KindSlice     Kind = "slice"
+	KindArray     Kind = "array"
+	KindMap       Kind = "map"
+	KindStruct    Kind = "struct"
+	KindInterface Kind = "interface"
+	KindChan      Kind = "chan" // SSE 方法返回的只读通道
+)
+
+// Type 是类型表达式。命名类型只记录名称和包路径，定义在 Service.Types 中。
+type Type struct {
+	Kind    Kind    `json:"kind"`
+	Name    string  `json:"name,omitempty"`    // basic 和 named 的类型名
+	Package string  `json:"package,omitempty"` // named 所在包的导入路径
+	Elem    *Type   `json:"elem,omitempty"`    // pointer、slice、array、map、chan 的元素类型
+	Key     *Type   `json:"key,omitempty"`     // map 的键类型
+	Len     int64   `json:"len,omitempty"`     // array 的长度
+	Fields  []Field `json:"fields,omitempty"`  // 匿名 struct 的字段
+}
+
+// Named 是命名类型的定义。
+type Named struct {
+	Name       string `json:"name"`
+	Package    string `json:"package"`
+	Doc        string `json:"doc"`
+	Underlying *Type  `json:"underlying"`
+}
+
+// Field 是结构体中会被 encoding/json 序列化的字段。
+type Field struct {
+	Name      string `json:"name"`      // Go 字段名
+	JSONName  string `json:"json_name"` // 序列化后的字段名
+	Doc       string `json:"doc"`
+	Tag       string `json:"tag"`
+	OmitEmpty bool   `json:"omit_empty"`
+	Embedded  bool   `json:"embedded"`
+	Type      *Type  `json:"type"`
+}
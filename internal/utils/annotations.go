@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"emperror.dev/errors"
 )
@@ -184,6 +185,11 @@ func unmarshal(dest interface{}, values map[string]string) error {
 	return unmarshalStruct(destElem, values)
 }
 
+// ParseAnnotations 返回注释中的全部注解，没有参数的注解值为空字符串。
+func ParseAnnotations(cg *ast.CommentGroup) map[string]string {
+	return parseCommentAnnotations(cg)
+}
+
 func UnmarshalAnnotations(cg *ast.CommentGroup, dest any) error {
 	values := parseCommentAnnotations(cg)
 	err := unmarshal(dest, values)
@@ -192,3 +198,28 @@ func UnmarshalAnnotations(cg *ast.CommentGroup, dest any) error {
 	}
 	return nil
 }
+
+// ParseRate 解析形如 100/s、10/m、5/100ms 的速率注解，返回每个时间窗口 per 内允许的次数 n。
+// 时间窗口可以是 s、m、h 这样的单位，也可以是 time.ParseDuration 支持的时长。
+func ParseRate(value string) (n int, per time.Duration, err error) {
+	parts := strings.SplitN(strings.TrimSpace(value), "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, errors.Errorf("invalid rate %q, expect format like 100/s", value)
+	}
+
+	n, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || n <= 0 {
+		return 0, 0, errors.Errorf("invalid rate %q, count must be a positive integer", value)
+	}
+
+	unit := strings.TrimSpace(parts[1])
+	if unit != "" && (unit[0] < '0' || unit[0] > '9') {
+		unit = "1" + unit
+	}
+	per, err = time.ParseDuration(unit)
+	if err != nil || per <= 0 {
+		return 0, 0, errors.Errorf("invalid rate %q, unknown time unit %q", value, parts[1])
+	}
+
+	return n, per, nil
+}
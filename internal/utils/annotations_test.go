@@ -4,6 +4,7 @@ import (
 	"go/ast"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // 测试parseCommentAnnotations函数
@@ -285,3 +286,33 @@ func TestUnmarshalAnnotations(t *testing.T) {
 		t.Errorf("Expected true, but got %v", ts.Deprecated)
 	}
 }
+
+// 测试ParseRate函数
+func TestParseRate(t *testing.T) {
+	cases := []struct {
+		value string
+		n     int
+		per   time.Duration
+	}{
+		{"100/s", 100, time.Second},
+		{"10/m", 10, time.Minute},
+		{" 1000 / h ", 1000, time.Hour},
+		{"5/100ms", 5, 100 * time.Millisecond},
+	}
+	for _, c := range cases {
+		n, per, err := ParseRate(c.value)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if n != c.n || per != c.per {
+			t.Errorf("Expected %d/%v, but got %d/%v", c.n, c.per, n, per)
+		}
+	}
+
+	for _, value := range []string{"", "100", "0/s", "-1/s", "x/s", "10/parsec", "10/0s"} {
+		_, _, err := ParseRate(value)
+		if err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"go/ast"
+	"regexp"
+)
+
+var generatedPattern = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// IsGeneratedFile 判断文件是否为生成的代码，即 package 子句之前有一行
+// "// Code generated ... DO NOT EDIT." 注释。
+func IsGeneratedFile(file *ast.File) bool {
+	for _, cg := range file.Comments {
+		if cg.Pos() > file.Package {
+			return false
+		}
+		for _, comment := range cg.List {
+			if generatedPattern.MatchString(comment.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
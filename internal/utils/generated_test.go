@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestIsGeneratedFile(t *testing.T) {
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"// Code generated by jk generate endpoints; DO NOT EDIT.\n\npackage order\n", true},
+		{"//go:build linux\n\n// Code generated by stringer; DO NOT EDIT.\n\npackage order\n", true},
+		{"// Package order 订单服务\npackage order\n", false},
+		{"package order\n\n// Code generated by jk; DO NOT EDIT.\nvar x int\n", false},
+		{"// Code generated by jk\npackage order\n", false},
+	}
+
+	for _, c := range cases {
+		file, err := parser.ParseFile(token.NewFileSet(), "x.go", c.src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := IsGeneratedFile(file); got != c.want {
+			t.Errorf("IsGeneratedFile(%q) = %v, want %v", c.src, got, c.want)
+		}
+	}
+}
@@ -68,6 +68,8 @@ func CheckParams(params *types.Tuple) error {
 // CheckResults checks if the function signature meets the following requirements:
 //   - The first return value must be exported serializable struct.
 //     response struct must have Code (int) and Message (string) field.
+//     streaming response embeds jkhttp.Stream (or implements jkhttp.Streamer) instead.
+//     server-sent events method returns <-chan *T instead, T must be exported serializable struct.
 //   - The second return value must be of type error.
 func CheckResults(results *types.Tuple) error {
 	// Check first return value.
@@ -83,6 +85,13 @@ func CheckResults(results *types.Tuple) error {
 		errType  = results.At(1).Type()
 	)
 
+	if _, isChan := respType.(*types.Chan); isChan {
+		if err := checkEventResult(respType); err != nil {
+			return err
+		}
+		return checkErrorResult(errType)
+	}
+
 	// Check if the first return value is an exported struct or slice of structs
 	if ptr, ok = respType.(*types.Pointer); !ok {
 		return fmt.Errorf("the first return value must be a pointer to an exported and serializable struct, but got %s", respType)
@@ -96,6 +105,11 @@ func CheckResults(results *types.Tuple) error {
 		return fmt.Errorf("the first return value must be a pointer to an exported and serializable struct, but got %s", respType)
 	}
 
+	if IsStreamType(named) {
+		// 流式响应不编码为 JSON，不需要可序列化，也没有 Code 和 Message 字段
+		return checkErrorResult(errType)
+	}
+
 	if !IsSerializable(named.Underlying()) {
 		return fmt.Errorf("the first return value must be a pointer to an exported and serializable struct, but got %s", respType)
 	}
@@ -108,7 +122,26 @@ func CheckResults(results *types.Tuple) error {
 		return err
 	}
 
-	if named, ok = errType.(*types.Named); !ok {
+	return checkErrorResult(errType)
+}
+
+// checkEventResult 检查 SSE 方法的返回值，事件类型不需要 Code 和 Message 字段。
+func checkEventResult(respType types.Type) error {
+	named, ok := EventType(respType)
+	if !ok || !named.Obj().Exported() {
+		return fmt.Errorf("the first return value of server-sent events method must be a receive-only channel of pointer to exported and serializable struct, but got %s", respType)
+	}
+
+	if _, ok = named.Underlying().(*types.Struct); !ok || !IsSerializable(named.Underlying()) {
+		return fmt.Errorf("the first return value of server-sent events method must be a receive-only channel of pointer to exported and serializable struct, but got %s", respType)
+	}
+
+	return nil
+}
+
+func checkErrorResult(errType types.Type) error {
+	named, ok := errType.(*types.Named)
+	if !ok {
 		return fmt.Errorf("the type of the second return value must be error, but got %s", errType)
 	}
 
@@ -27,7 +27,8 @@ func IsSerializable(t types.Type) bool {
 	case *types.Struct:
 		return isSerializableStructureType(t)
 	case *types.Named:
-		return IsSerializable(t.Underlying())
+		// jkhttp.File 的 Reader 字段不会被序列化，由生成的代码作为文件上传
+		return IsFileType(t) || IsSerializable(t.Underlying())
 	default:
 		return false
 	}
@@ -91,3 +92,76 @@ func isBasicSerializableType(t types.Type) bool {
 	}
 	return false
 }
+
+// FilePackage 是 jk 提供的运行时包，其中的 File 类型表示 multipart/form-data 请求中的文件。
+const FilePackage = "github.com/nnnewb/jk/pkg/jkhttp"
+
+// IsFileType 判断类型是否是 jkhttp.File。
+func IsFileType(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == FilePackage && named.Obj().Name() == "File"
+}
+
+// IsTimeType 判断 t 是否是 time.Time，encoding/json 把它编码为 RFC 3339 格式的字符串。
+func IsTimeType(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "time" && named.Obj().Name() == "Time"
+}
+
+// hasMarshalMethod 判断 t 或 *t 是否有 encoding/json 使用的 name() ([]byte, error) 方法。
+func hasMarshalMethod(t types.Type, name string) bool {
+	if _, ok := t.(*types.Named); !ok {
+		return false
+	}
+	sel := types.NewMethodSet(types.NewPointer(t)).Lookup(nil, name)
+	if sel == nil {
+		return false
+	}
+	signature := sel.Type().(*types.Signature)
+	return signature.Params().Len() == 0 && signature.Results().Len() == 2
+}
+
+// IsJSONMarshaler 判断 t 或 *t 是否实现 json.Marshaler，这种类型的 JSON 格式由 MarshalJSON 决定，生成器无法得知。
+func IsJSONMarshaler(t types.Type) bool {
+	return hasMarshalMethod(t, "MarshalJSON")
+}
+
+// IsTextMarshaler 判断 t 或 *t 是否实现 encoding.TextMarshaler，没有实现 json.Marshaler 时 encoding/json 把它编码为字符串。
+func IsTextMarshaler(t types.Type) bool {
+	return hasMarshalMethod(t, "MarshalText")
+}
+
+// IsStreamType 判断 *T 是否实现 jkhttp.Streamer，即 T 嵌入了 jkhttp.Stream 或者 *T 有返回 *jkhttp.Stream 的 ContentStream 方法。
+func IsStreamType(t types.Type) bool {
+	if _, ok := t.(*types.Pointer); !ok {
+		t = types.NewPointer(t)
+	}
+	sel := types.NewMethodSet(t).Lookup(nil, "ContentStream")
+	if sel == nil {
+		return false
+	}
+	signature := sel.Type().(*types.Signature)
+	if signature.Params().Len() != 0 || signature.Results().Len() != 1 {
+		return false
+	}
+	ptr, ok := signature.Results().At(0).Type().(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == FilePackage && named.Obj().Name() == "Stream"
+}
+
+// EventType 返回 SSE 方法返回值 <-chan *T 中的 T，t 不是只读通道或元素不是具名类型的指针时返回 false。
+func EventType(t types.Type) (*types.Named, bool) {
+	ch, ok := t.(*types.Chan)
+	if !ok || ch.Dir() != types.RecvOnly {
+		return nil, false
+	}
+	ptr, ok := ch.Elem().(*types.Pointer)
+	if !ok {
+		return nil, false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	return named, ok
+}
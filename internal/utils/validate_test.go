@@ -199,3 +199,21 @@ func TestIsBasicSerializableType(t *testing.T) {
 		t.Errorf("Expected false for Uintptr type")
 	}
 }
+
+func TestEventType(t *testing.T) {
+	pkg := types.NewPackage("example/api", "api")
+	event := types.NewNamed(types.NewTypeName(token.NoPos, pkg, "Event", nil), types.NewStruct(nil, nil), nil)
+
+	if named, ok := EventType(types.NewChan(types.RecvOnly, types.NewPointer(event))); !ok || named != event {
+		t.Errorf("Expected <-chan *Event to be event stream of Event")
+	}
+	if _, ok := EventType(types.NewChan(types.SendRecv, types.NewPointer(event))); ok {
+		t.Errorf("Expected bidirectional channel not to be event stream")
+	}
+	if _, ok := EventType(types.NewChan(types.RecvOnly, event)); ok {
+		t.Errorf("Expected channel of non-pointer not to be event stream")
+	}
+	if _, ok := EventType(types.NewPointer(event)); ok {
+		t.Errorf("Expected pointer not to be event stream")
+	}
+}
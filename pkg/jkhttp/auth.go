@@ -0,0 +1,187 @@
+package jkhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 认证方式，对应 @http-auth 注解的值。
+const (
+	AuthBearer = "bearer" // Authorization: Bearer <token>
+	AuthBasic  = "basic"  // Authorization: Basic <base64(username:password)>
+	AuthAPIKey = "apikey" // X-API-Key: <token>
+)
+
+// APIKeyHeader 是 apikey 认证方式携带密钥的请求头。
+const APIKeyHeader = "X-API-Key"
+
+var (
+	// ErrMissingCredentials 表示请求没有携带认证方式要求的凭据。
+	ErrMissingCredentials = errors.New("jkhttp: missing credentials")
+	// ErrNoAuthenticator 表示服务端没有设置 Authenticator，需要认证的方法拒绝所有请求。
+	ErrNoAuthenticator = errors.New("jkhttp: authenticator is not configured")
+)
+
+// Credentials 是请求携带的凭据。
+type Credentials struct {
+	Scheme   string // 认证方式，AuthBearer、AuthBasic 或 AuthAPIKey
+	Token    string // bearer 令牌或 API 密钥
+	Username string // basic 认证的用户名
+	Password string // basic 认证的密码
+}
+
+// CredentialsFromRequest 按认证方式 scheme 从请求头读取凭据，请求没有携带凭据时返回 false。
+func CredentialsFromRequest(r *http.Request, scheme string) (*Credentials, bool) {
+	switch scheme {
+	case AuthBearer:
+		prefix, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(prefix, "Bearer") || strings.TrimSpace(token) == "" {
+			return nil, false
+		}
+		return &Credentials{Scheme: AuthBearer, Token: strings.TrimSpace(token)}, true
+	case AuthBasic:
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			return nil, false
+		}
+		return &Credentials{Scheme: AuthBasic, Username: username, Password: password}, true
+	case AuthAPIKey:
+		token := r.Header.Get(APIKeyHeader)
+		if token == "" {
+			return nil, false
+		}
+		return &Credentials{Scheme: AuthAPIKey, Token: token}, true
+	default:
+		return nil, false
+	}
+}
+
+// SetRequestCredentials 按 cred.Scheme 把凭据设置为请求头。
+func SetRequestCredentials(r *http.Request, cred *Credentials) {
+	switch cred.Scheme {
+	case AuthBearer:
+		r.Header.Set("Authorization", "Bearer "+cred.Token)
+	case AuthBasic:
+		r.SetBasicAuth(cred.Username, cred.Password)
+	case AuthAPIKey:
+		r.Header.Set(APIKeyHeader, cred.Token)
+	}
+}
+
+type credentialsContextKey struct{}
+
+// WithCredentials 返回携带客户端凭据的 ctx，客户端用这个 ctx 调用需要认证的方法时，
+// 直接使用这个凭据而不是从 TokenSource 获取。
+func WithCredentials(ctx context.Context, cred *Credentials) context.Context {
+	return context.WithValue(ctx, credentialsContextKey{}, cred)
+}
+
+// CredentialsFromContext 返回 WithCredentials 设置的凭据。
+func CredentialsFromContext(ctx context.Context) (*Credentials, bool) {
+	cred, ok := ctx.Value(credentialsContextKey{}).(*Credentials)
+	return cred, ok && cred != nil
+}
+
+type authenticatedCredentialsContextKey struct{}
+
+// WithAuthenticatedCredentials 返回携带服务端认证通过的凭据的 ctx，由 Authenticate 调用。
+//
+// 认证通过的凭据和 WithCredentials 使用不同的键，方法实现用请求的 ctx 调用其他服务时
+// 不会转发调用方的凭据，仍然从 TokenSource 获取。
+func WithAuthenticatedCredentials(ctx context.Context, cred *Credentials) context.Context {
+	return context.WithValue(ctx, authenticatedCredentialsContextKey{}, cred)
+}
+
+// AuthenticatedCredentials 返回服务端认证通过的请求的凭据。
+func AuthenticatedCredentials(ctx context.Context) (*Credentials, bool) {
+	cred, ok := ctx.Value(authenticatedCredentialsContextKey{}).(*Credentials)
+	return cred, ok && cred != nil
+}
+
+// Authenticator 校验请求携带的凭据，校验失败返回错误，服务端以 401 拒绝请求。
+//
+// 返回的 ctx 会传递给方法实现，可以在其中放入认证得到的用户等信息。
+type Authenticator interface {
+	Authenticate(ctx context.Context, cred *Credentials) (context.Context, error)
+}
+
+// AuthenticatorFunc 把函数转换为 Authenticator。
+type AuthenticatorFunc func(ctx context.Context, cred *Credentials) (context.Context, error)
+
+// Authenticate 实现 Authenticator。
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, cred *Credentials) (context.Context, error) {
+	return f(ctx, cred)
+}
+
+// Authenticate 用 auth 校验请求按 scheme 携带的凭据，通过后调用 next，
+// 请求的 ctx 中带有凭据，可以用 AuthenticatedCredentials 取得。
+//
+// 没有凭据或校验失败时返回 401，auth 为 nil 时返回 500，响应体是生成的服务端的错误格式。
+func Authenticate(auth Authenticator, scheme string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth == nil {
+			writeErrorResponse(w, http.StatusInternalServerError, ErrNoAuthenticator.Error())
+			return
+		}
+
+		cred, ok := CredentialsFromRequest(r, scheme)
+		if !ok {
+			unauthorized(w, scheme, ErrMissingCredentials)
+			return
+		}
+		ctx, err := auth.Authenticate(WithAuthenticatedCredentials(r.Context(), cred), cred)
+		if err != nil {
+			unauthorized(w, scheme, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// unauthorized 返回 401，bearer 和 basic 认证方式带有 WWW-Authenticate 响应头。
+func unauthorized(w http.ResponseWriter, scheme string, err error) {
+	switch scheme {
+	case AuthBearer:
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	case AuthBasic:
+		w.Header().Set("WWW-Authenticate", `Basic realm="api"`)
+	}
+	writeErrorResponse(w, http.StatusUnauthorized, fmt.Sprintf("unauthenticated, error %v", err))
+}
+
+// TokenSource 为客户端提供认证方式 scheme 的凭据，每次调用需要认证的方法时调用一次。
+// 实现可以缓存和刷新令牌，需要支持并发调用。
+type TokenSource interface {
+	Credentials(ctx context.Context, scheme string) (*Credentials, error)
+}
+
+// TokenSourceFunc 把函数转换为 TokenSource。
+type TokenSourceFunc func(ctx context.Context, scheme string) (*Credentials, error)
+
+// Credentials 实现 TokenSource。
+func (f TokenSourceFunc) Credentials(ctx context.Context, scheme string) (*Credentials, error) {
+	return f(ctx, scheme)
+}
+
+// StaticCredentials 返回固定凭据的 TokenSource，按认证方式选择 creds 中 Scheme 相同的凭据。
+func StaticCredentials(creds ...*Credentials) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context, scheme string) (*Credentials, error) {
+		for _, cred := range creds {
+			if cred.Scheme == scheme {
+				return cred, nil
+			}
+		}
+		return nil, fmt.Errorf("jkhttp: no %s credentials", scheme)
+	})
+}
+
+// SetCredentials 把 ctx 中的凭据设置为请求头，可以用作 go-kit 的 ClientBefore。
+func SetCredentials(ctx context.Context, r *http.Request) context.Context {
+	if cred, ok := CredentialsFromContext(ctx); ok {
+		SetRequestCredentials(r, cred)
+	}
+	return ctx
+}
@@ -0,0 +1,88 @@
+package jkhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type authUserKey struct{}
+
+func TestAuthenticate(t *testing.T) {
+	creds := []*Credentials{
+		{Scheme: AuthBearer, Token: "t0ken"},
+		{Scheme: AuthBasic, Username: "alice", Password: "p:ss"},
+		{Scheme: AuthAPIKey, Token: "k3y"},
+	}
+	auth := AuthenticatorFunc(func(ctx context.Context, cred *Credentials) (context.Context, error) {
+		for _, want := range creds {
+			if *want == *cred {
+				return context.WithValue(ctx, authUserKey{}, cred.Scheme), nil
+			}
+		}
+		return nil, errors.New("invalid credentials")
+	})
+
+	for _, cred := range creds {
+		handler := Authenticate(auth, cred.Scheme, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok := AuthenticatedCredentials(r.Context())
+			if !ok || *got != *cred || r.Context().Value(authUserKey{}) != cred.Scheme {
+				t.Errorf("%s: context credentials %+v", cred.Scheme, got)
+			}
+			// 认证通过的凭据不能被客户端当作调用下游服务的凭据
+			if _, ok := CredentialsFromContext(r.Context()); ok {
+				t.Errorf("%s: authenticated credentials leaked to client credentials", cred.Scheme)
+			}
+		}))
+
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		SetCredentials(WithCredentials(context.Background(), cred), r)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status %d, body %s", cred.Scheme, w.Code, w.Body)
+		}
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: missing credentials got status %d", cred.Scheme, w.Code)
+		}
+		if cred.Scheme != AuthAPIKey && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expect WWW-Authenticate header", cred.Scheme)
+		}
+
+		r = httptest.NewRequest(http.MethodPost, "/", nil)
+		SetRequestCredentials(r, &Credentials{Scheme: cred.Scheme, Token: "wrong", Username: "wrong"})
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: wrong credentials got status %d", cred.Scheme, w.Code)
+		}
+	}
+}
+
+func TestAuthenticateWithoutAuthenticator(t *testing.T) {
+	handler := Authenticate(nil, AuthBearer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called without authenticator")
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Authorization", "Bearer t0ken")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status %d", w.Code)
+	}
+}
+
+func TestStaticCredentials(t *testing.T) {
+	src := StaticCredentials(&Credentials{Scheme: AuthAPIKey, Token: "k3y"})
+	if cred, err := src.Credentials(context.Background(), AuthAPIKey); err != nil || cred.Token != "k3y" {
+		t.Errorf("got %+v, %v", cred, err)
+	}
+	if _, err := src.Credentials(context.Background(), AuthBearer); err == nil {
+		t.Error("expect error for scheme without credentials")
+	}
+}
@@ -0,0 +1,207 @@
+package jkhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Codec 编解码请求体和响应体，如 JSON、msgpack、CBOR。
+type Codec interface {
+	ContentType() string // 媒体类型，如 application/msgpack
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+type funcCodec struct {
+	contentType string
+	marshal     func(v any) ([]byte, error)
+	unmarshal   func(data []byte, v any) error
+}
+
+func (c *funcCodec) ContentType() string                { return c.contentType }
+func (c *funcCodec) Marshal(v any) ([]byte, error)      { return c.marshal(v) }
+func (c *funcCodec) Unmarshal(data []byte, v any) error { return c.unmarshal(data, v) }
+
+// NewCodec 用序列化函数创建 Codec，例如 NewCodec("application/msgpack", msgpack.Marshal, msgpack.Unmarshal)。
+func NewCodec(contentType string, marshal func(v any) ([]byte, error), unmarshal func(data []byte, v any) error) Codec {
+	return &funcCodec{contentType: contentType, marshal: marshal, unmarshal: unmarshal}
+}
+
+// JSON 是默认的 Codec。
+var JSON = NewCodec("application/json", json.Marshal, json.Unmarshal)
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{JSON.ContentType(): JSON}
+)
+
+// RegisterCodec 注册 Codec，媒体类型相同时覆盖已注册的 Codec。生成的服务端和客户端只使用注册过的 Codec。
+func RegisterCodec(codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[strings.ToLower(codec.ContentType())] = codec
+}
+
+// LookupCodec 按媒体类型查找 Codec，忽略 charset 等参数。
+func LookupCodec(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[mediaType]
+	return codec, ok
+}
+
+// acceptRange 是 Accept 请求头中的一项。
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ret []acceptRange
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ret = append(ret, acceptRange{mediaType: mediaType, q: q})
+		}
+	}
+	// 权重相同时保持请求头中的顺序
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].q > ret[j].q })
+	return ret
+}
+
+// NegotiateCodec 按 Accept 请求头选择响应的 Codec。Accept 为空、只接受通配符或没有可用的 Codec 时返回 JSON。
+func NegotiateCodec(accept string) Codec {
+	for _, r := range parseAccept(accept) {
+		if strings.HasSuffix(r.mediaType, "/*") {
+			// */* 和 application/* 都包含 JSON
+			return JSON
+		}
+		if codec, ok := LookupCodec(r.mediaType); ok {
+			return codec
+		}
+	}
+	return JSON
+}
+
+// UnsupportedMediaTypeError 表示请求体的媒体类型没有注册 Codec。
+type UnsupportedMediaTypeError struct {
+	ContentType string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("jkhttp: unsupported media type %q", e.ContentType)
+}
+
+// StatusCode 实现 go-kit 的 StatusCoder。
+func (e *UnsupportedMediaTypeError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+// bodyCodec 按 Content-Type 选择请求体或响应体的 Codec，没有 Content-Type 时使用 JSON。
+func bodyCodec(contentType string) (Codec, error) {
+	if contentType == "" {
+		return JSON, nil
+	}
+	codec, ok := LookupCodec(contentType)
+	if !ok {
+		return nil, &UnsupportedMediaTypeError{ContentType: contentType}
+	}
+	return codec, nil
+}
+
+// DecodeRequest 按 Content-Type 请求头选择 Codec 解码请求体，媒体类型没有注册时返回 *UnsupportedMediaTypeError。
+func DecodeRequest(r *http.Request, dst any) error {
+	codec, err := bodyCodec(r.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(data, dst)
+}
+
+// EncodeResponse 按 Accept 请求头选择 Codec 编码响应。
+func EncodeResponse(w http.ResponseWriter, accept string, response any) error {
+	codec := NegotiateCodec(accept)
+	data, err := codec.Marshal(response)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", codec.ContentType())
+	w.Header().Add("Vary", "Accept")
+	_, err = w.Write(data)
+	return err
+}
+
+type codecContextKey struct{}
+
+// WithCodec 指定客户端使用的 Codec，用 ctx 发起的请求以 contentType 编码请求体，并期望同样格式的响应。
+func WithCodec(ctx context.Context, contentType string) context.Context {
+	return context.WithValue(ctx, codecContextKey{}, contentType)
+}
+
+// ContextCodec 返回 WithCodec 指定的 Codec，没有指定时返回 JSON，指定的媒体类型没有注册时返回错误。
+func ContextCodec(ctx context.Context) (Codec, error) {
+	contentType, _ := ctx.Value(codecContextKey{}).(string)
+	return bodyCodec(contentType)
+}
+
+// EncodeRequest 用 ContextCodec 编码请求体，同时设置 Accept 请求头。
+func EncodeRequest(ctx context.Context, r *http.Request, request any) error {
+	codec, err := ContextCodec(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := codec.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set("Content-Type", codec.ContentType())
+	r.Header.Set("Accept", codec.ContentType())
+	r.ContentLength = int64(len(data))
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return nil
+}
+
+// DecodeResponse 按 Content-Type 响应头选择 Codec 解码响应体。
+func DecodeResponse(resp *http.Response, dst any) error {
+	codec, err := bodyCodec(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(data, dst)
+}
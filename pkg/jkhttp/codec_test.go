@@ -0,0 +1,84 @@
+package jkhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testCodec 是测试用的 Codec，用可区分的媒体类型编码 JSON。
+var testCodec = NewCodec("application/x-test", json.Marshal, json.Unmarshal)
+
+func TestNegotiateCodec(t *testing.T) {
+	RegisterCodec(testCodec)
+
+	cases := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"text/html", "application/json"},
+		{"application/x-test", "application/x-test"},
+		{"application/json;q=0.5, application/x-test", "application/x-test"},
+		{"application/x-test;q=0.1, application/*;q=0.2", "application/json"},
+		{"application/x-test;q=0, application/json", "application/json"},
+	}
+	for _, c := range cases {
+		if got := NegotiateCodec(c.accept).ContentType(); got != c.want {
+			t.Errorf("NegotiateCodec(%q) = %s, want %s", c.accept, got, c.want)
+		}
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	RegisterCodec(testCodec)
+	type message struct {
+		Text string `json:"text"`
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	err := EncodeRequest(WithCodec(context.Background(), "application/x-test"), r, &message{Text: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Header.Get("Content-Type") != "application/x-test" || r.Header.Get("Accept") != "application/x-test" {
+		t.Errorf("unexpected request headers %v", r.Header)
+	}
+
+	var req message
+	err = DecodeRequest(r, &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	err = EncodeResponse(w, r.Header.Get("Accept"), &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resp message
+	err = DecodeResponse(w.Result(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "hello" || w.Header().Get("Content-Type") != "application/x-test" {
+		t.Errorf("response = %+v, headers %v", resp, w.Header())
+	}
+}
+
+func TestDecodeRequestUnsupportedMediaType(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("<xml/>"))
+	r.Header.Set("Content-Type", "application/xml")
+
+	var dst struct{}
+	var unsupported *UnsupportedMediaTypeError
+	if err := DecodeRequest(r, &dst); !errors.As(err, &unsupported) {
+		t.Errorf("expect *UnsupportedMediaTypeError, got %v", err)
+	}
+}
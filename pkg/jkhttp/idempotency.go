@@ -0,0 +1,300 @@
+package jkhttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader 是客户端为同一次调用的所有重试携带的幂等键请求头。
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader 出现在重放的响应中，值为 true。
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// DefaultIdempotencyTTL 是生成的服务端默认的内存存储保存响应的时长。
+	DefaultIdempotencyTTL = 24 * time.Hour
+)
+
+var (
+	// ErrIdempotencyKeyInUse 表示使用同一个幂等键的请求正在处理。
+	ErrIdempotencyKeyInUse = errors.New("jkhttp: idempotency key is in use")
+	// IdempotencyMaxBodyBytes 是 Idempotent 读取的请求体的最大字节数，请求体需要保存在内存中计算摘要，
+	// 超出时返回 413，小于等于 0 时不限制。
+	//
+	// Idempotent 在解码请求之前读取请求体，这个值小于 MaxMultipartSize 时，大小在两者之间的 multipart/form-data
+	// 请求会被 Idempotent 拒绝，所以默认值和 MaxMultipartSize 相同，调大 MaxMultipartSize 时需要一起调整。
+	IdempotencyMaxBodyBytes int64 = 64 << 20
+)
+
+// IdempotentResponse 是保存的响应，同一个幂等键的后续请求原样重放。
+type IdempotentResponse struct {
+	RequestHash string      // 首次请求的摘要，用于拒绝复用幂等键的不同请求
+	StatusCode  int         // 响应状态码
+	Header      http.Header // 响应头
+	Body        []byte      // 响应体
+}
+
+// IdempotencyStore 保存幂等键对应的响应，实现需要支持并发调用，多实例部署时应当使用共享存储。
+//
+// 一个键的生命周期是 Reserve、处理请求，然后 Save 保存响应或 Release 放弃，放弃的键可以重试。
+type IdempotencyStore interface {
+	// Reserve 占用幂等键。键已经保存响应时返回响应，键被占用但还没有响应时返回 ErrIdempotencyKeyInUse，
+	// 否则占用键并返回 nil。
+	Reserve(ctx context.Context, key string) (*IdempotentResponse, error)
+	// Save 保存 Reserve 占用的键的响应。
+	Save(ctx context.Context, key string, resp *IdempotentResponse) error
+	// Release 释放 Reserve 占用的键，不保存响应。
+	Release(ctx context.Context, key string) error
+}
+
+type memoryIdempotencyEntry struct {
+	resp    *IdempotentResponse // 为 nil 时请求正在处理
+	expires time.Time
+}
+
+// MemoryIdempotencyStore 是保存在进程内存中的 IdempotencyStore，只适合单实例部署。
+type MemoryIdempotencyStore struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	entries   map[string]*memoryIdempotencyEntry
+	lastSweep time.Time
+}
+
+var _ IdempotencyStore = (*MemoryIdempotencyStore)(nil)
+
+// NewMemoryIdempotencyStore 创建内存存储，响应和正在处理的键在 ttl 后过期。
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{ttl: ttl, entries: make(map[string]*memoryIdempotencyEntry)}
+}
+
+// Reserve 实现 IdempotencyStore。
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key string) (*IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		if entry.resp == nil {
+			return nil, ErrIdempotencyKeyInUse
+		}
+		return entry.resp, nil
+	}
+	s.entries[key] = &memoryIdempotencyEntry{expires: now.Add(s.ttl)}
+	return nil, nil
+}
+
+// Save 实现 IdempotencyStore。
+func (s *MemoryIdempotencyStore) Save(ctx context.Context, key string, resp *IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = &memoryIdempotencyEntry{resp: resp, expires: time.Now().Add(s.ttl)}
+	return nil
+}
+
+// Release 实现 IdempotencyStore。
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep 每隔 ttl 删除一次过期的键，调用方需要持有锁。
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// idempotencyRecorder 在写入响应的同时记录响应，用于保存到 IdempotencyStore。
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *idempotencyRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *idempotencyRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+// writeErrorResponse 以生成的服务端的错误格式 {"code": -1, "message": ...} 返回错误。
+func writeErrorResponse(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"code": -1, "message": message})
+}
+
+// isErrorEnvelope 判断响应体是否是 {"code": 非 0, "message": ...} 格式的错误，
+// 生成的 std 服务端出错时可能以 200 状态码返回这种响应。
+func isErrorEnvelope(body []byte) bool {
+	var envelope map[string]json.RawMessage
+	if json.Unmarshal(body, &envelope) != nil || len(envelope) != 2 {
+		return false
+	}
+	var code int
+	var message string
+	if json.Unmarshal(envelope["code"], &code) != nil || json.Unmarshal(envelope["message"], &message) != nil {
+		return false
+	}
+	return code != 0
+}
+
+// idempotencyScope 返回调用方的摘要，幂等键只在同一个调用方的同一个方法和路径下有效。
+// 认证通过的请求使用 AuthenticatedCredentials 返回的凭据，否则使用请求携带的 Authorization 和 X-API-Key 请求头。
+func idempotencyScope(r *http.Request) string {
+	hash := sha256.New()
+	if cred, ok := AuthenticatedCredentials(r.Context()); ok {
+		fmt.Fprintf(hash, "%s\n%s\n%s\n%s\n", cred.Scheme, cred.Token, cred.Username, cred.Password)
+	} else {
+		fmt.Fprintf(hash, "%s\n%s\n", r.Header.Get("Authorization"), r.Header.Get(APIKeyHeader))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Idempotent 用 store 为 next 实现幂等键。
+//
+// 请求没有 Idempotency-Key 请求头时，required 为 true 返回 400，否则直接调用 next。
+// 同一个调用方、方法和路径下，幂等键已经保存响应时重放响应并设置 Idempotent-Replayed 响应头，
+// 请求内容、Content-Type 或 Accept 和首次请求不同时返回 422，首次请求还在处理时返回 409，
+// 请求体超过 IdempotencyMaxBodyBytes 时返回 413。
+// 5xx 响应和 code 不为 0 的错误响应不会保存，客户端可以用同一个幂等键重试。
+func Idempotent(store IdempotencyStore, required bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			if required {
+				writeErrorResponse(w, http.StatusBadRequest, "missing "+IdempotencyKeyHeader+" header")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reader := r.Body
+		if IdempotencyMaxBodyBytes > 0 {
+			reader = http.MaxBytesReader(w, r.Body, IdempotencyMaxBodyBytes)
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			if IdempotencyMaxBodyBytes > 0 && int64(len(body)) >= IdempotencyMaxBodyBytes {
+				writeErrorResponse(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("unable to read request body, error %v", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// 同一个请求以不同格式编码或要求不同格式的响应时不能重放
+		hash := sha256.New()
+		fmt.Fprintf(hash, "%s %s?%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+		fmt.Fprintf(hash, "Content-Type: %s\nAccept: %s\n", r.Header.Get("Content-Type"), r.Header.Get("Accept"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		ctx := r.Context()
+		storeKey := r.Method + " " + r.URL.Path + " " + idempotencyScope(r) + " " + key
+		saved, err := store.Reserve(ctx, storeKey)
+		switch {
+		case errors.Is(err, ErrIdempotencyKeyInUse):
+			writeErrorResponse(w, http.StatusConflict, "request with the same "+IdempotencyKeyHeader+" is in progress")
+			return
+		case err != nil:
+			writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("unable to reserve idempotency key, error %v", err))
+			return
+		case saved != nil && saved.RequestHash != requestHash:
+			writeErrorResponse(w, http.StatusUnprocessableEntity, IdempotencyKeyHeader+" is reused by a different request")
+			return
+		case saved != nil:
+			for name, values := range saved.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(saved.StatusCode)
+			_, _ = w.Write(saved.Body)
+			return
+		}
+
+		recorder := &idempotencyRecorder{ResponseWriter: w}
+		saveOK := false
+		defer func() {
+			// 处理失败或 panic 时释放幂等键，允许重试
+			if !saveOK {
+				_ = store.Release(ctx, storeKey)
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		if recorder.status >= http.StatusInternalServerError || isErrorEnvelope(recorder.body.Bytes()) {
+			return
+		}
+		saveOK = store.Save(ctx, storeKey, &IdempotentResponse{
+			RequestHash: requestHash,
+			StatusCode:  recorder.status,
+			Header:      w.Header().Clone(),
+			Body:        recorder.body.Bytes(),
+		}) == nil
+	})
+}
+
+type idempotencyKeyContextKey struct{}
+
+// NewIdempotencyKey 返回随机生成的 UUID v4 格式的幂等键。
+func NewIdempotencyKey() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// WithIdempotencyKey 指定用 ctx 发起的请求携带的幂等键，同一次调用的重试应当使用同一个 ctx。
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// IdempotencyKey 返回 WithIdempotencyKey 指定的幂等键。
+func IdempotencyKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyContextKey{}).(string)
+	return key, ok && key != ""
+}
+
+// SetIdempotencyKey 把 ctx 中的幂等键设置为请求头，可以用作 go-kit 的 ClientBefore。
+func SetIdempotencyKey(ctx context.Context, r *http.Request) context.Context {
+	if key, ok := IdempotencyKey(ctx); ok {
+		r.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return ctx
+}
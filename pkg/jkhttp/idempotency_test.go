@@ -0,0 +1,220 @@
+package jkhttp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func idempotentRequest(key, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return r
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	calls := 0
+	handler := Idempotent(NewMemoryIdempotencyStore(time.Minute), false, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"order_id":%d}`, calls)
+	}))
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, idempotentRequest("k1", `{"sku":"a"}`))
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, idempotentRequest("k1", `{"sku":"a"}`))
+
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() ||
+		second.Header().Get("Content-Type") != "application/json" || second.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("replayed %d %q %v, want %d %q", second.Code, second.Body, second.Header(), first.Code, first.Body)
+	}
+
+	reused := httptest.NewRecorder()
+	handler.ServeHTTP(reused, idempotentRequest("k1", `{"sku":"b"}`))
+	if reused.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with different body got status %d", reused.Code)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("", `{"sku":"a"}`))
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("", `{"sku":"a"}`))
+	if calls != 3 {
+		t.Errorf("requests without key should not be deduplicated, handler called %d times", calls)
+	}
+}
+
+func TestIdempotentRequired(t *testing.T) {
+	handler := Idempotent(NewMemoryIdempotencyStore(time.Minute), true, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called without idempotency key")
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("", "{}"))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("missing key got status %d", w.Code)
+	}
+}
+
+func TestIdempotentServerErrorNotSaved(t *testing.T) {
+	calls := 0
+	handler := Idempotent(NewMemoryIdempotencyStore(time.Minute), false, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("k1", "{}"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("k1", "{}"))
+	if calls != 2 || w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("retry after 5xx got %d %q, handler called %d times", w.Code, w.Body, calls)
+	}
+}
+
+func TestIdempotentErrorEnvelopeNotSaved(t *testing.T) {
+	calls := 0
+	handler := Idempotent(NewMemoryIdempotencyStore(time.Minute), false, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			// 和生成的 std 服务端的 beautifyErrorEncoder 一样以 200 返回错误
+			_, _ = w.Write([]byte(`{"code":-1,"message":"error occurred: db timeout"}` + "\n"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":1,"order_id":2}`))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("k1", "{}"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("k1", "{}"))
+	if calls != 2 || w.Body.String() != `{"code":1,"order_id":2}` {
+		t.Errorf("retry after error envelope got %d %q, handler called %d times", w.Code, w.Body, calls)
+	}
+
+	// 包含 code 字段的正常响应仍然保存
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("k1", "{}"))
+	if calls != 2 || w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("response with code field was not replayed, handler called %d times", calls)
+	}
+}
+
+func TestIdempotentInProgress(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	handler := Idempotent(NewMemoryIdempotencyStore(time.Minute), false, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("k1", "{}"))
+	}()
+	<-entered
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("k1", "{}"))
+	close(release)
+	<-done
+	if w.Code != http.StatusConflict {
+		t.Errorf("concurrent request got status %d", w.Code)
+	}
+}
+
+func TestSetIdempotencyKey(t *testing.T) {
+	key := NewIdempotencyKey()
+	if key == NewIdempotencyKey() || len(key) != 36 {
+		t.Errorf("unexpected key %s", key)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	SetIdempotencyKey(context.Background(), r)
+	if r.Header.Get(IdempotencyKeyHeader) != "" {
+		t.Error("expect no header without key in context")
+	}
+	SetIdempotencyKey(WithIdempotencyKey(context.Background(), key), r)
+	if r.Header.Get(IdempotencyKeyHeader) != key {
+		t.Errorf("header = %s, want %s", r.Header.Get(IdempotencyKeyHeader), key)
+	}
+}
+
+func TestIdempotentScope(t *testing.T) {
+	calls := 0
+	handler := Idempotent(NewMemoryIdempotencyStore(time.Minute), false, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, `{"calls":%d}`, calls)
+	}))
+
+	alice := idempotentRequest("k1", "{}")
+	alice.Header.Set("Authorization", "Bearer alice")
+	handler.ServeHTTP(httptest.NewRecorder(), alice)
+
+	bob := idempotentRequest("k1", "{}")
+	bob.Header.Set("Authorization", "Bearer bob")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, bob)
+	if calls != 2 || w.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("same key of different caller got %d %q, handler called %d times", w.Code, w.Body, calls)
+	}
+
+	authenticated := idempotentRequest("k1", "{}")
+	authenticated = authenticated.WithContext(WithAuthenticatedCredentials(authenticated.Context(), &Credentials{Scheme: AuthBearer, Token: "alice"}))
+	handler.ServeHTTP(httptest.NewRecorder(), authenticated)
+	authenticated = idempotentRequest("k1", "{}")
+	authenticated = authenticated.WithContext(WithAuthenticatedCredentials(authenticated.Context(), &Credentials{Scheme: AuthBearer, Token: "alice"}))
+	handler.ServeHTTP(httptest.NewRecorder(), authenticated)
+	if calls != 3 {
+		t.Errorf("retry of authenticated caller should be replayed, handler called %d times", calls)
+	}
+
+	msgpack := idempotentRequest("k1", "{}")
+	msgpack.Header.Set("Authorization", "Bearer alice")
+	msgpack.Header.Set("Accept", "application/msgpack")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, msgpack)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key with different Accept got status %d", w.Code)
+	}
+}
+
+func TestIdempotencyMaxBodyBytesDefault(t *testing.T) {
+	// multipart/form-data 请求先经过 Idempotent，默认值不能比 MaxMultipartSize 小
+	if IdempotencyMaxBodyBytes < MaxMultipartSize {
+		t.Errorf("IdempotencyMaxBodyBytes %d is less than MaxMultipartSize %d", IdempotencyMaxBodyBytes, MaxMultipartSize)
+	}
+}
+
+func TestIdempotentBodyTooLarge(t *testing.T) {
+	defer func(limit int64) { IdempotencyMaxBodyBytes = limit }(IdempotencyMaxBodyBytes)
+	IdempotencyMaxBodyBytes = 8
+
+	handler := Idempotent(NewMemoryIdempotencyStore(time.Minute), false, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(data)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("k1", `{"sku":"a"}`))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("large body got status %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("k2", `{"a":1}`))
+	if w.Code != http.StatusOK || w.Body.String() != `{"a":1}` {
+		t.Errorf("small body got %d %q", w.Code, w.Body)
+	}
+}
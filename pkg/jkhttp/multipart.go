@@ -0,0 +1,381 @@
+// Package jkhttp 是 jk 生成的 HTTP 传输层代码在运行时依赖的辅助类型和函数。
+package jkhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// MaxMultipartMemory 是解析 multipart/form-data 请求时保存在内存中的最大字节数，超出部分写入临时文件。
+	MaxMultipartMemory int64 = 32 << 20
+	// MaxMultipartSize 是 multipart/form-data 请求体的最大字节数，小于等于 0 时不限制。
+	// 使用 @idempotent 的方法还受 IdempotencyMaxBodyBytes 限制。
+	MaxMultipartSize int64 = 64 << 20
+)
+
+// File 是 multipart/form-data 请求中的文件。请求结构体中 *File 和 []*File 类型的字段，
+// 以及带有 `jk:"file"` 标签的 []byte 字段会作为文件上传，其他字段作为普通表单值。
+type File struct {
+	Name        string    `json:"name"`         // 文件名
+	ContentType string    `json:"content_type"` // MIME 类型，为空时客户端使用 application/octet-stream
+	Size        int64     `json:"size"`         // 文件大小，只在服务端解析请求时设置
+	Reader      io.Reader `json:"-"`            // 文件内容，只能读取一次
+}
+
+// Close 关闭服务端解析得到的文件，Reader 没有实现 io.Closer 时什么也不做。
+func (f *File) Close() error {
+	if closer, ok := f.Reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+var (
+	filePtrType  = reflect.TypeOf(&File{})
+	fileListType = reflect.TypeOf([]*File{})
+	bytesType    = reflect.TypeOf([]byte{})
+)
+
+// formField 是结构体中对应一个表单字段的字段。
+type formField struct {
+	name  string
+	index []int
+	file  bool // 带有 `jk:"file"` 标签
+}
+
+// formFields 按 encoding/json 的规则返回结构体的字段，表单字段名就是 JSON 字段名。
+func formFields(t reflect.Type) []formField {
+	var ret []formField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			// 和 encoding/json 一样把嵌入结构体的字段提升到外层，不支持嵌入指针
+			if field.Type.Kind() == reflect.Struct {
+				for _, embedded := range formFields(field.Type) {
+					embedded.index = append([]int{i}, embedded.index...)
+					ret = append(ret, embedded)
+				}
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		ret = append(ret, formField{name: name, index: field.Index, file: field.Tag.Get("jk") == "file"})
+	}
+	return ret
+}
+
+// structValue 返回 v 指向的结构体。
+func structValue(v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("jkhttp: expect pointer to struct, got %T", v)
+	}
+	return rv, nil
+}
+
+// isScalar 判断类型是否编码为单个表单值。
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// DecodeMultipartRequest 解析 multipart/form-data 请求，把表单写入 dst 指向的结构体。
+// 标量和标量切片从表单值解析，其他类型的表单值按 JSON 解析。
+func DecodeMultipartRequest(r *http.Request, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("jkhttp: expect non-nil pointer to struct, got %T", dst)
+	}
+	rv = rv.Elem()
+
+	if MaxMultipartSize > 0 {
+		r.Body = http.MaxBytesReader(nil, r.Body, MaxMultipartSize)
+	}
+	err := r.ParseMultipartForm(MaxMultipartMemory)
+	if err != nil {
+		return err
+	}
+
+	form := r.MultipartForm
+	for _, field := range formFields(rv.Type()) {
+		fv := rv.FieldByIndex(field.index)
+		headers := form.File[field.name]
+		switch {
+		case fv.Type() == filePtrType:
+			if len(headers) > 0 {
+				file, err := openFile(headers[0])
+				if err != nil {
+					return err
+				}
+				fv.Set(reflect.ValueOf(file))
+			}
+		case fv.Type() == fileListType:
+			files := make([]*File, 0, len(headers))
+			for _, header := range headers {
+				file, err := openFile(header)
+				if err != nil {
+					return err
+				}
+				files = append(files, file)
+			}
+			if len(files) > 0 {
+				fv.Set(reflect.ValueOf(files))
+			}
+		case field.file && fv.Type().ConvertibleTo(bytesType):
+			if len(headers) > 0 {
+				data, err := readFile(headers[0])
+				if err != nil {
+					return err
+				}
+				fv.Set(reflect.ValueOf(data).Convert(fv.Type()))
+			}
+		default:
+			values := form.Value[field.name]
+			if len(values) == 0 {
+				continue
+			}
+			err := setValue(fv, values)
+			if err != nil {
+				return fmt.Errorf("jkhttp: decode form field %s failed: %w", field.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func openFile(header *multipart.FileHeader) (*File, error) {
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		Name:        header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Size:        header.Size,
+		Reader:      f,
+	}, nil
+}
+
+func readFile(header *multipart.FileHeader) ([]byte, error) {
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// setValue 把表单值写入 v。
+func setValue(v reflect.Value, values []string) error {
+	switch {
+	case v.Kind() == reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		err := setValue(elem.Elem(), values)
+		if err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case isScalar(v.Type()):
+		return setScalar(v, values[0])
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 && isScalar(v.Type().Elem()):
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			err := setScalar(slice.Index(i), value)
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	default:
+		return json.Unmarshal([]byte(values[0]), v.Addr().Interface())
+	}
+}
+
+func setScalar(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	}
+	return nil
+}
+
+// formatScalar 把标量格式化为表单值，是 setScalar 的逆操作。
+func formatScalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	default:
+		return v.String()
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// EncodeMultipartRequest 把 src 指向的结构体编码为 multipart/form-data 请求体，是 DecodeMultipartRequest 的逆操作。
+// nil 指针、切片和映射字段不会出现在表单中。请求体会完整缓存在内存中，以便设置 Content-Length。
+func EncodeMultipartRequest(r *http.Request, src any) error {
+	rv, err := structValue(src)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, field := range formFields(rv.Type()) {
+		fv := rv.FieldByIndex(field.index)
+		switch {
+		case fv.Type() == filePtrType:
+			if !fv.IsNil() {
+				err = writeFile(w, field.name, fv.Interface().(*File))
+			}
+		case fv.Type() == fileListType:
+			for _, file := range fv.Interface().([]*File) {
+				if file == nil {
+					continue
+				}
+				err = writeFile(w, field.name, file)
+				if err != nil {
+					break
+				}
+			}
+		case field.file && fv.Type().ConvertibleTo(bytesType):
+			if !fv.IsNil() {
+				data := fv.Convert(bytesType).Interface().([]byte)
+				err = writeFile(w, field.name, &File{Name: field.name, Reader: bytes.NewReader(data)})
+			}
+		default:
+			err = writeValue(w, field.name, fv)
+		}
+		if err != nil {
+			return fmt.Errorf("jkhttp: encode form field %s failed: %w", field.name, err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	data := body.Bytes()
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	r.ContentLength = int64(len(data))
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return nil
+}
+
+func writeFile(w *multipart.Writer, name string, file *File) error {
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(name), quoteEscaper.Replace(file.Name)))
+	header.Set("Content-Type", contentType)
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	if file.Reader == nil {
+		return nil
+	}
+	_, err = io.Copy(part, file.Reader)
+	return err
+}
+
+func writeValue(w *multipart.Writer, name string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	if v.Kind() == reflect.Ptr && v.Elem().Kind() != reflect.Ptr {
+		v = v.Elem()
+	}
+
+	switch {
+	case isScalar(v.Type()):
+		return w.WriteField(name, formatScalar(v))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 && isScalar(v.Type().Elem()):
+		for i := 0; i < v.Len(); i++ {
+			err := w.WriteField(name, formatScalar(v.Index(i)))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return err
+		}
+		return w.WriteField(name, string(data))
+	}
+}
@@ -0,0 +1,106 @@
+package jkhttp
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type uploadMeta struct {
+	Tags map[string]string `json:"tags"`
+}
+
+type uploadRequest struct {
+	uploadMeta
+	Title     string   `json:"title"`
+	Page      *int     `json:"page,omitempty"`
+	Labels    []string `json:"labels"`
+	Ratio     float64  `json:"ratio"`
+	Avatar    *File    `json:"avatar"`
+	Photos    []*File  `json:"photos"`
+	Thumbnail []byte   `json:"thumbnail" jk:"file"`
+	Ignored   string   `json:"-"`
+}
+
+func TestMultipartRoundTrip(t *testing.T) {
+	page := 3
+	src := &uploadRequest{
+		uploadMeta: uploadMeta{Tags: map[string]string{"k": "v"}},
+		Title:      "hello",
+		Page:       &page,
+		Labels:     []string{"a", "b"},
+		Ratio:      0.5,
+		Avatar:     &File{Name: "a.png", ContentType: "image/png", Reader: strings.NewReader("avatar")},
+		Photos: []*File{
+			{Name: `"1".jpg`, Reader: strings.NewReader("one")},
+			{Name: "2.jpg", Reader: strings.NewReader("two")},
+		},
+		Thumbnail: []byte("thumb"),
+		Ignored:   "ignored",
+	}
+
+	r, err := http.NewRequest(http.MethodPost, "http://example.com/upload", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = EncodeMultipartRequest(r, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dst uploadRequest
+	err = DecodeMultipartRequest(r, &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dst.Title != "hello" || dst.Page == nil || *dst.Page != 3 || dst.Ratio != 0.5 || dst.Ignored != "" {
+		t.Errorf("unexpected form values %+v", dst)
+	}
+	if !reflect.DeepEqual(dst.Labels, src.Labels) || !reflect.DeepEqual(dst.Tags, src.Tags) {
+		t.Errorf("labels = %v, tags = %v", dst.Labels, dst.Tags)
+	}
+	if string(dst.Thumbnail) != "thumb" {
+		t.Errorf("thumbnail = %q", dst.Thumbnail)
+	}
+
+	files := append([]*File{dst.Avatar}, dst.Photos...)
+	want := []struct{ name, contentType, content string }{
+		{"a.png", "image/png", "avatar"},
+		{`"1".jpg`, "application/octet-stream", "one"},
+		{"2.jpg", "application/octet-stream", "two"},
+	}
+	if len(files) != len(want) || files[0] == nil {
+		t.Fatalf("got %d files, want %d", len(files), len(want))
+	}
+	for i, file := range files {
+		content, err := io.ReadAll(file.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+		if file.Name != want[i].name || file.ContentType != want[i].contentType || string(content) != want[i].content || file.Size != int64(len(content)) {
+			t.Errorf("file %d = %+v %q, want %+v", i, file, content, want[i])
+		}
+	}
+}
+
+func TestDecodeMultipartRequestTooLarge(t *testing.T) {
+	r, err := http.NewRequest(http.MethodPost, "http://example.com/upload", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = EncodeMultipartRequest(r, &uploadRequest{Thumbnail: make([]byte, 1024)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(size int64) { MaxMultipartSize = size }(MaxMultipartSize)
+	MaxMultipartSize = 512
+	var dst uploadRequest
+	if err := DecodeMultipartRequest(r, &dst); err == nil {
+		t.Error("expect error for request body larger than MaxMultipartSize")
+	}
+}
@@ -0,0 +1,72 @@
+package jkhttp
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// queryAliasTags 是以前的服务端解析查询参数使用的标签：gin 的 BindQuery 使用 form，gorilla/schema 默认使用 schema。
+var queryAliasTags = []string{"form", "schema"}
+
+// queryAliases 收集结构体的 JSON 字段名，以及 form 或 schema 标签的参数名到 JSON 字段名的映射，
+// 两者相同的字段不包含在映射中。嵌入结构体的字段和 encoding/json 一样提升到外层。
+func queryAliases(t reflect.Type, names map[string]bool, aliases map[string]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			// 和 formFields 一样不支持嵌入指针
+			if field.Type.Kind() == reflect.Struct {
+				queryAliases(field.Type, names, aliases)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+		for _, key := range queryAliasTags {
+			alias := strings.Split(field.Tag.Get(key), ",")[0]
+			if alias != "" && alias != "-" && alias != name {
+				aliases[alias] = name
+			}
+		}
+	}
+}
+
+// AliasFormTags 把 values 中按 form 或 schema 标签命名的参数改为对应的 JSON 字段名，dst 是请求结构体的指针。
+//
+// 生成的服务端按 JSON 字段名解析 GET 和 DELETE 请求的查询参数，以前的服务端按 form 或 schema 标签解析，
+// 调用 AliasFormTags 后按标签名发送参数的旧客户端仍然可用。同时出现两个名字时使用 JSON 字段名的参数。
+func AliasFormTags(dst any, values url.Values) {
+	names, aliases := make(map[string]bool), make(map[string]string)
+	queryAliases(reflect.TypeOf(dst), names, aliases)
+	for alias, name := range aliases {
+		value, ok := values[alias]
+		// 和其他字段的 JSON 字段名相同的标签名属于那个字段
+		if !ok || names[alias] {
+			continue
+		}
+		if _, ok := values[name]; !ok {
+			values[name] = value
+		}
+		delete(values, alias)
+	}
+}
@@ -0,0 +1,52 @@
+package jkhttp
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type queryBase struct {
+	Tenant string `json:"tenant" form:"tenant_id"`
+}
+
+type queryRequest struct {
+	queryBase
+	OrderID string `json:"order_id" form:"orderId"`
+	Page    int    `json:"page" schema:"p"`
+	Size    int    `json:"size" form:"size"`
+	Keyword string `json:"keyword" form:"page"`
+}
+
+func TestAliasFormTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		values url.Values
+		want   url.Values
+	}{
+		{
+			name:   "Alias",
+			values: url.Values{"orderId": {"1"}, "p": {"2"}, "tenant_id": {"t"}, "size": {"3"}},
+			want:   url.Values{"order_id": {"1"}, "page": {"2"}, "tenant": {"t"}, "size": {"3"}},
+		},
+		{
+			name:   "JSONNameWins",
+			values: url.Values{"orderId": {"1"}, "order_id": {"2"}},
+			want:   url.Values{"order_id": {"2"}},
+		},
+		{
+			// page 是 Page 的 JSON 字段名，不作为 Keyword 的别名
+			name:   "AliasClashesWithJSONName",
+			values: url.Values{"page": {"2"}},
+			want:   url.Values{"page": {"2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AliasFormTags(&queryRequest{}, tt.values)
+			if !reflect.DeepEqual(tt.values, tt.want) {
+				t.Errorf("got %v, want %v", tt.values, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package jkhttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	// EventStreamHeartbeat 是 SSE 响应发送心跳注释的间隔，避免代理关闭空闲连接，小于等于 0 时不发送。
+	EventStreamHeartbeat = 15 * time.Second
+	// MaxEventSize 是客户端读取的单个事件的最大字节数。
+	MaxEventSize = 1 << 20
+)
+
+// WriteEvents 把通道 events 中的事件以 text/event-stream 格式写入 w，每个事件的 data 是一行 JSON。
+// 通道关闭或 ctx 结束（客户端断开连接）时返回，方法向通道发送事件时应当同时检查 ctx.Done()。
+func WriteEvents(ctx context.Context, w http.ResponseWriter, events any) error {
+	ch := reflect.ValueOf(events)
+	if ch.Kind() != reflect.Chan || ch.Type().ChanDir()&reflect.RecvDir == 0 {
+		return fmt.Errorf("jkhttp: events must be a receivable channel, got %T", events)
+	}
+	if ch.IsNil() {
+		// 从 nil 通道接收会一直阻塞
+		return errors.New("jkhttp: nil events channel")
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errors.New("jkhttp: response writer does not support flushing")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var heartbeat <-chan time.Time
+	if EventStreamHeartbeat > 0 {
+		ticker := time.NewTicker(EventStreamHeartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: ch},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(heartbeat)},
+	}
+	for {
+		chosen, event, ok := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			return nil
+		case 1:
+			if !ok {
+				return nil
+			}
+			data, err := json.Marshal(event.Interface())
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+			if err != nil {
+				return err
+			}
+		case 2:
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			if err != nil {
+				return err
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// EncodeEventStreamResponse 是写入 SSE 响应的 go-kit EncodeResponseFunc。
+func EncodeEventStreamResponse(ctx context.Context, w http.ResponseWriter, response any) error {
+	return WriteEvents(ctx, w, response)
+}
+
+// ReadEvents 从 text/event-stream 响应体读取事件，把每个事件的 data 按 JSON 解码为 *T 后发送到返回的通道。
+// 响应体结束、读取或解码失败、ctx 结束时关闭通道和响应体，忽略心跳等注释行。
+func ReadEvents[T any](ctx context.Context, body io.ReadCloser) <-chan *T {
+	events := make(chan *T)
+	go func() {
+		defer close(events)
+		defer body.Close()
+
+		// ctx 结束时关闭响应体，让阻塞的读取返回
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				body.Close()
+			case <-done:
+			}
+		}()
+
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 4096), MaxEventSize)
+		var data []string
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if len(data) == 0 {
+					continue
+				}
+				event := new(T)
+				err := json.Unmarshal([]byte(strings.Join(data, "\n")), event)
+				if err != nil {
+					return
+				}
+				data = data[:0]
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			case strings.HasPrefix(line, "data:"):
+				data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			}
+		}
+	}()
+	return events
+}
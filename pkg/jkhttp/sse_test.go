@@ -0,0 +1,97 @@
+package jkhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type orderEvent struct {
+	OrderID int64  `json:"order_id"`
+	Status  string `json:"status"`
+}
+
+func TestEventsRoundTrip(t *testing.T) {
+	want := []*orderEvent{{OrderID: 1, Status: "paid"}, {OrderID: 1, Status: "shipped\nto 上海"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events := make(chan *orderEvent, len(want))
+		for _, event := range want {
+			events <- event
+		}
+		close(events)
+		if err := WriteEvents(r.Context(), w, (<-chan *orderEvent)(events)); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %s", ct)
+	}
+
+	var got []*orderEvent
+	for event := range ReadEvents[orderEvent](context.Background(), resp.Body) {
+		got = append(got, event)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteEventsClientDisconnect(t *testing.T) {
+	returned := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 通道一直不关闭，只有客户端断开连接时 WriteEvents 才会返回
+		events := make(chan *orderEvent)
+		returned <- WriteEvents(r.Context(), w, events)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := ReadEvents[orderEvent](ctx, resp.Body)
+	cancel()
+
+	if _, ok := <-events; ok {
+		t.Error("expect events channel closed after cancel")
+	}
+	select {
+	case err := <-returned:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WriteEvents did not return after client disconnected")
+	}
+}
+
+func TestWriteEventsHeartbeat(t *testing.T) {
+	defer func(d time.Duration) { EventStreamHeartbeat = d }(EventStreamHeartbeat)
+	EventStreamHeartbeat = 10 * time.Millisecond
+
+	events := make(chan *orderEvent)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(events)
+	}()
+
+	w := httptest.NewRecorder()
+	if err := WriteEvents(context.Background(), w, events); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.Body.String(), ": heartbeat\n\n") {
+		t.Errorf("expect heartbeat comment, got %q", w.Body.String())
+	}
+}
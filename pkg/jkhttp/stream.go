@@ -0,0 +1,87 @@
+package jkhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+)
+
+// Stream 是流式响应，适合 CSV、ZIP 等不适合编码为 JSON 的大文件导出。
+//
+// 方法的响应类型嵌入 Stream 或实现 Streamer 时，生成的服务端把 Body 直接写入响应体，
+// 生成的 Go 客户端把响应体作为 Body 返回，调用方读完后必须关闭 Body。
+type Stream struct {
+	ContentType string        `json:"-"` // 为空时是 application/octet-stream
+	Filename    string        `json:"-"` // 不为空时以 Content-Disposition 提示下载的文件名
+	Body        io.ReadCloser `json:"-"`
+}
+
+// ContentStream 实现 Streamer。
+func (s *Stream) ContentStream() *Stream {
+	return s
+}
+
+// Streamer 是流式响应实现的接口，嵌入 Stream 的结构体自动实现。
+type Streamer interface {
+	ContentStream() *Stream
+}
+
+// streamOf 返回 v 的 Stream，v 不是 Streamer 或是 nil 指针时返回错误。
+func streamOf(v any) (*Stream, error) {
+	streamer, ok := v.(Streamer)
+	if !ok {
+		return nil, errors.New("jkhttp: response does not implement Streamer")
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, errors.New("jkhttp: nil stream response")
+	}
+	return streamer.ContentStream(), nil
+}
+
+// WriteStream 把流式响应写入 w，写完后关闭 Body。
+func WriteStream(w http.ResponseWriter, response any) error {
+	stream, err := streamOf(response)
+	if err != nil {
+		return err
+	}
+
+	contentType := stream.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if stream.Filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": stream.Filename}))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	if stream.Body == nil {
+		return nil
+	}
+	defer stream.Body.Close()
+	_, err = io.Copy(w, stream.Body)
+	return err
+}
+
+// EncodeStreamResponse 是写入流式响应的 go-kit EncodeResponseFunc。
+func EncodeStreamResponse(ctx context.Context, w http.ResponseWriter, response any) error {
+	return WriteStream(w, response)
+}
+
+// DecodeStreamResponse 从响应头读取 Content-Type 和文件名，把响应体作为 dst 的 Body，不会关闭响应体。
+func DecodeStreamResponse(resp *http.Response, dst any) error {
+	stream, err := streamOf(dst)
+	if err != nil {
+		return err
+	}
+
+	stream.ContentType = resp.Header.Get("Content-Type")
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		stream.Filename = params["filename"]
+	}
+	stream.Body = resp.Body
+	return nil
+}
@@ -0,0 +1,51 @@
+package jkhttp
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type exportResponse struct {
+	Stream
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	src := &exportResponse{Stream{
+		ContentType: "text/csv",
+		Filename:    "订单 1.csv",
+		Body:        io.NopCloser(strings.NewReader("id,name\n1,jk\n")),
+	}}
+
+	w := httptest.NewRecorder()
+	err := WriteStream(w, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dst exportResponse
+	err = DecodeStreamResponse(w.Result(), &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Body.Close()
+
+	content, err := io.ReadAll(dst.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dst.ContentType != "text/csv" || dst.Filename != "订单 1.csv" || string(content) != "id,name\n1,jk\n" {
+		t.Errorf("got %s %q %q, headers %v", dst.ContentType, dst.Filename, content, w.Header())
+	}
+}
+
+func TestWriteStreamNilResponse(t *testing.T) {
+	var resp *exportResponse
+	if err := WriteStream(httptest.NewRecorder(), resp); err == nil {
+		t.Error("expect error for nil stream response")
+	}
+	if err := WriteStream(httptest.NewRecorder(), struct{}{}); err == nil {
+		t.Error("expect error for response without stream")
+	}
+}